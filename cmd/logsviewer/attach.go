@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/pflag"
+
+	"github.com/marcuzy/logsviewer/internal/config"
+	"github.com/marcuzy/logsviewer/internal/share"
+	"github.com/marcuzy/logsviewer/internal/ui"
+)
+
+// runAttach shows the session of an instance started with --share.
+func runAttach(args []string) int {
+	flags := pflag.NewFlagSet("logsviewer attach", pflag.ContinueOnError)
+	configPath := flags.StringP("config", "c", "", "path to configuration file")
+	follow := flags.Bool("follow", false, "mirror the host's search, filter and selection")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s attach [flags] session.sock\n\nFlags:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	cfg, err := config.Load(config.Flags{ConfigPath: *configPath, NoFiles: true})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	entries, views, errs, err := share.Attach(ctx, flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+
+	opts := viewerOptions(cfg)
+	opts.Entries = entries
+	opts.Errors = errs
+	opts.Cancel = cancel
+	program := tea.NewProgram(ui.NewModel(opts), tea.WithAltScreen())
+	go func() {
+		for v := range views {
+			if *follow {
+				program.Send(ui.ViewMsg{View: ui.ViewState(v)})
+			}
+		}
+	}()
+	_, err = program.Run()
+	cancel()
+	drainSources(entries, errs, shutdownTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "program error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
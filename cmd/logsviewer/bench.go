@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// benchTimestampField carries the write time of a synthetic line so the
+// ingest latency can be measured on the consumer side.
+const benchTimestampField = "bench_ts"
+
+type benchOptions struct {
+	rate        int
+	duration    time.Duration
+	size        int
+	files       int
+	rotateEvery int
+	dir         string
+	maxEntries  int
+}
+
+// runBench generates synthetic JSON logs into temporary files, tails them
+// with the regular tailer and reports ingest latency and memory usage.
+func runBench(args []string) int {
+	flags := pflag.NewFlagSet("logsviewer bench", pflag.ContinueOnError)
+	var opts benchOptions
+	flags.IntVar(&opts.rate, "rate", 5000, "lines per second written across all files")
+	flags.DurationVar(&opts.duration, "duration", 10*time.Second, "how long to generate traffic")
+	flags.IntVar(&opts.size, "size", 200, "approximate size of each line in bytes")
+	flags.IntVar(&opts.files, "files", 1, "number of files written in parallel")
+	flags.IntVar(&opts.rotateEvery, "rotate-every", 0, "rotate a file after this many lines (0 disables rotation)")
+	flags.StringVar(&opts.dir, "dir", "", "directory for generated files (default: a temporary directory)")
+	flags.IntVar(&opts.maxEntries, "max-entries", 1000, "entries retained by the consumer, as in the viewer")
+	if err := flags.Parse(args); err != nil {
+		if err == pflag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if opts.rate <= 0 || opts.files <= 0 || opts.duration <= 0 {
+		fmt.Fprintln(os.Stderr, "bench: --rate, --files and --duration must be positive")
+		return 2
+	}
+
+	dir := opts.dir
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "logsviewer-bench-*")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+			return 1
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+
+	paths := make([]string, opts.files)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("bench-%d.log", i))
+		if err := os.WriteFile(paths[i], nil, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+			return 1
+		}
+	}
+
+	report, err := bench(paths, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+		return 1
+	}
+	fmt.Print(report)
+	return 0
+}
+
+func bench(paths []string, opts benchOptions) (string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tailer := logs.NewTailer(paths, logs.Options{
+		Parser: logs.ParserConfig{
+			Format:         logs.DefaultFormat,
+			TimestampField: "timestamp",
+			MessageField:   "message",
+		},
+	})
+	entries, errs := tailer.Start(ctx)
+	go func() {
+		for range errs {
+		}
+	}()
+
+	var written int64
+	var writeErr error
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	perFile := opts.rate / len(paths)
+	if perFile == 0 {
+		perFile = 1
+	}
+	for _, path := range paths {
+		path := path
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := generate(ctx, path, perFile, opts)
+			mu.Lock()
+			written += n
+			if err != nil && writeErr == nil {
+				writeErr = err
+			}
+			mu.Unlock()
+		}()
+	}
+
+	latencies := make([]time.Duration, 0, opts.rate*int(opts.duration/time.Second+1))
+	retained := make([]logs.LogEntry, 0, opts.maxEntries)
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var peakHeap uint64
+	memTicker := time.NewTicker(250 * time.Millisecond)
+	defer memTicker.Stop()
+	var ms runtime.MemStats
+	drainUntil := time.Time{}
+
+loop:
+	for {
+		var timeout <-chan time.Time
+		if !drainUntil.IsZero() {
+			timeout = time.After(time.Until(drainUntil))
+		}
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				break loop
+			}
+			if sent, err := strconv.ParseInt(entry.FieldString(benchTimestampField), 10, 64); err == nil {
+				latencies = append(latencies, time.Since(time.Unix(0, sent)))
+			}
+			if len(retained) == opts.maxEntries {
+				retained = retained[1:]
+			}
+			retained = append(retained, entry)
+			mu.Lock()
+			finished := !drainUntil.IsZero() && int64(len(latencies)) >= written
+			mu.Unlock()
+			if finished {
+				break loop
+			}
+		case <-memTicker.C:
+			runtime.ReadMemStats(&ms)
+			if ms.HeapAlloc > peakHeap {
+				peakHeap = ms.HeapAlloc
+			}
+		case <-done:
+			done = nil
+			// Give the tailer a moment to pick up the last lines.
+			drainUntil = time.Now().Add(2 * time.Second)
+		case <-timeout:
+			break loop
+		}
+	}
+	elapsed := time.Since(start)
+	cancel()
+
+	runtime.ReadMemStats(&ms)
+	if ms.HeapAlloc > peakHeap {
+		peakHeap = ms.HeapAlloc
+	}
+	if writeErr != nil {
+		return "", writeErr
+	}
+
+	var dropped int64
+	for _, s := range tailer.Stats() {
+		dropped += s.Dropped
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "files:        %d\n", len(paths))
+	fmt.Fprintf(&b, "written:      %d lines\n", written)
+	fmt.Fprintf(&b, "ingested:     %d lines (%.0f/s)\n", len(latencies), float64(len(latencies))/elapsed.Seconds())
+	fmt.Fprintf(&b, "dropped:      %d\n", dropped)
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		fmt.Fprintf(&b, "latency p50:  %s\n", percentile(latencies, 0.50))
+		fmt.Fprintf(&b, "latency p95:  %s\n", percentile(latencies, 0.95))
+		fmt.Fprintf(&b, "latency p99:  %s\n", percentile(latencies, 0.99))
+		fmt.Fprintf(&b, "latency max:  %s\n", latencies[len(latencies)-1])
+	}
+	fmt.Fprintf(&b, "heap peak:    %.1f MiB\n", float64(peakHeap)/(1<<20))
+	fmt.Fprintf(&b, "heap final:   %.1f MiB (%d entries retained)\n", float64(ms.HeapAlloc)/(1<<20), len(retained))
+	fmt.Fprintf(&b, "gc cycles:    %d\n", ms.NumGC)
+	return b.String(), nil
+}
+
+// generate appends synthetic lines to path at the given rate until the
+// configured duration elapses, rotating the file if requested.
+func generate(ctx context.Context, path string, rate int, opts benchOptions) (int64, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { file.Close() }()
+
+	const tick = 10 * time.Millisecond
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	deadline := time.Now().Add(opts.duration)
+	padding := strings.Repeat("x", max(opts.size-120, 0))
+
+	var written, sinceRotate int64
+	var carry float64
+	perTick := float64(rate) * tick.Seconds()
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return written, nil
+		case <-ticker.C:
+		}
+		carry += perTick
+		n := int(carry)
+		carry -= float64(n)
+
+		var buf strings.Builder
+		for i := 0; i < n; i++ {
+			now := time.Now()
+			fmt.Fprintf(&buf, `{"timestamp":%q,"level":"info","message":"synthetic line %d","%s":"%d","pad":%q}`+"\n",
+				now.Format(time.RFC3339Nano), written, benchTimestampField, now.UnixNano(), padding)
+			written++
+			sinceRotate++
+		}
+		if _, err := file.WriteString(buf.String()); err != nil {
+			return written, err
+		}
+
+		if opts.rotateEvery > 0 && sinceRotate >= int64(opts.rotateEvery) {
+			file.Close()
+			if err := os.Rename(path, path+".1"); err != nil {
+				return written, err
+			}
+			if file, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644); err != nil {
+				return written, err
+			}
+			sinceRotate = 0
+		}
+	}
+	return written, nil
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+	"github.com/marcuzy/logsviewer/internal/ui"
+)
+
+// controlTimeout bounds how long a control request waits for the UI.
+const controlTimeout = 5 * time.Second
+
+// controlRequest is the request message of the control methods, a
+// google.protobuf.Struct with these keys. Each method reads the fields it
+// needs.
+type controlRequest struct {
+	Command string   `json:"command"`
+	Query   string   `json:"query"`
+	Time    string   `json:"time"`
+	Path    string   `json:"path"`
+	Format  string   `json:"format"`
+	Columns []string `json:"columns"`
+}
+
+// controlServer is the logsviewer.Control gRPC service described in
+// control.proto. Requests and responses are google.protobuf.Struct, so the
+// service needs no generated code on either side.
+type controlServer interface {
+	Sources(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+	Filter(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+	Jump(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+	Export(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+	Command(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+}
+
+var controlServiceDesc = grpc.ServiceDesc{
+	ServiceName: "logsviewer.Control",
+	HandlerType: (*controlServer)(nil),
+	Methods: []grpc.MethodDesc{
+		controlMethod("Sources", controlServer.Sources),
+		controlMethod("Filter", controlServer.Filter),
+		controlMethod("Jump", controlServer.Jump),
+		controlMethod("Export", controlServer.Export),
+		controlMethod("Command", controlServer.Command),
+	},
+	Metadata: "control.proto",
+}
+
+// controlMethod describes the unary method name served by call.
+func controlMethod(name string, call func(controlServer, context.Context, *structpb.Struct) (*structpb.Struct, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(structpb.Struct)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(srv.(controlServer), ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/logsviewer.Control/" + name}
+			return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return call(srv.(controlServer), ctx, req.(*structpb.Struct))
+			})
+		},
+	}
+}
+
+// control serves the control methods by turning them into ":" command
+// lines for the UI.
+type control struct {
+	stats func() []logs.SourceStats
+	send  func(tea.Msg)
+}
+
+func (c *control) Sources(context.Context, *structpb.Struct) (*structpb.Struct, error) {
+	return controlStruct(map[string]any{"sources": c.stats()})
+}
+
+func (c *control) Filter(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return c.handle(ctx, in, func(req controlRequest) (string, error) {
+		return "search " + req.Query, nil
+	})
+}
+
+func (c *control) Jump(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return c.handle(ctx, in, func(req controlRequest) (string, error) {
+		return "jump " + req.Time, nil
+	})
+}
+
+func (c *control) Export(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return c.handle(ctx, in, func(req controlRequest) (string, error) {
+		// :export splits its arguments at spaces.
+		if req.Path == "" || strings.ContainsAny(req.Path, " \t") {
+			return "", fmt.Errorf("path must be set and must not contain spaces")
+		}
+		args := []string{"export", req.Path}
+		if req.Format != "" {
+			args = append(args, req.Format)
+		}
+		if len(req.Columns) > 0 {
+			args = append(args, strings.Join(req.Columns, ","))
+		}
+		return strings.Join(args, " "), nil
+	})
+}
+
+func (c *control) Command(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	return c.handle(ctx, in, func(req controlRequest) (string, error) {
+		return req.Command, nil
+	})
+}
+
+// handle decodes in, builds the command line for it and runs it in the UI.
+func (c *control) handle(ctx context.Context, in *structpb.Struct, build func(req controlRequest) (string, error)) (*structpb.Struct, error) {
+	var req controlRequest
+	data, err := json.Marshal(in.AsMap())
+	if err == nil {
+		err = json.Unmarshal(data, &req)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+	}
+	command, err := build(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	reply := make(chan ui.ControlReply, 1)
+	c.send(ui.ControlMsg{Command: command, Reply: reply})
+	ctx, cancel := context.WithTimeout(ctx, controlTimeout)
+	defer cancel()
+	select {
+	case r := <-reply:
+		if r.Err != nil {
+			return nil, status.Error(codes.InvalidArgument, r.Err.Error())
+		}
+		return controlStruct(map[string]any{"status": r.Status})
+	case <-ctx.Done():
+		return nil, status.Error(codes.DeadlineExceeded, "viewer did not respond")
+	}
+}
+
+// controlStruct converts v, a value that encodes to a JSON object, into a
+// response message.
+func controlStruct(v any) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	out := new(structpb.Struct)
+	if err := out.UnmarshalJSON(data); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return out, nil
+}
+
+// startControlServer lets other programs drive the running viewer over
+// gRPC on a unix socket at path, with the logsviewer.Control service from
+// control.proto:
+//
+//	Sources  {}                  per-source counters, as {"sources": [...]}
+//	Filter   {"query": "..."}    search as "/" does
+//	Jump     {"time": "..."}     select the first entry at or after time
+//	Export   {"path": "...", "format": "...", "columns": [...]}
+//	Command  {"command": "..."}  any ":" command line
+//
+// The socket is only accessible to the current user. The returned function
+// shuts the server down and removes the socket.
+func startControlServer(path string, stats func() []logs.SourceStats, send func(tea.Msg)) (func(), error) {
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("control socket: %w", err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("control socket: %w", err)
+	}
+
+	srv := grpc.NewServer()
+	srv.RegisterService(&controlServiceDesc, &control{stats: stats, send: send})
+	go func() { _ = srv.Serve(ln) }()
+	return srv.Stop, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/marcuzy/logsviewer/internal/config"
+	"github.com/marcuzy/logsviewer/internal/logs"
+	"github.com/marcuzy/logsviewer/internal/ui"
+)
+
+// sourceFlags are the flags shared by the subcommands that read log files
+// without the UI: which files to read and how to parse them.
+type sourceFlags struct {
+	set            *pflag.FlagSet
+	configPath     *string
+	files          *[]string
+	filesFrom      *string
+	dirs           *[]string
+	docker         *[]string
+	timestampField *string
+	messageField   *string
+	extraFields    *[]string
+	format         *string
+	tailLines      *int
+}
+
+func addSourceFlags(flags *pflag.FlagSet) *sourceFlags {
+	return &sourceFlags{
+		set:            flags,
+		configPath:     flags.StringP("config", "c", "", "path to configuration file"),
+		files:          flags.StringSliceP("file", "f", nil, "log file(s) to read; @list.txt reads paths from a file"),
+		filesFrom:      flags.String("files-from", "", "read log file paths from a file, one per line (- for stdin)"),
+		dirs:           flags.StringSlice("dir", nil, "read every file in a directory, including files created later (repeatable)"),
+		docker:         flags.StringSlice("docker", nil, "read the json-file log of a container, by name or ID (repeatable)"),
+		timestampField: flags.String("timestamp-field", "", "JSON field containing the timestamp"),
+		messageField:   flags.String("message-field", "", "JSON field containing the message"),
+		extraFields:    flags.StringSlice("extra-field", nil, "additional field(s) to show (repeatable)"),
+		format:         flags.String("format", "", "default line format of the log files (json, logfmt, nginx, regex, docker, plain)"),
+		tailLines:      flags.Int("tail", -1, "number of lines to read from the end of each file"),
+	}
+}
+
+// load resolves the configuration with the parsed flags applied.
+func (f *sourceFlags) load() (config.Config, error) {
+	var tailPtr *int
+	if f.set.Changed("tail") {
+		tailPtr = f.tailLines
+	}
+	var extrasOverride []string
+	if f.set.Changed("extra-field") {
+		extrasOverride = *f.extraFields
+	}
+	return config.Load(config.Flags{
+		ConfigPath:     *f.configPath,
+		Files:          *f.files,
+		FilesFrom:      *f.filesFrom,
+		Dirs:           *f.dirs,
+		Docker:         *f.docker,
+		TailLines:      tailPtr,
+		TimestampField: *f.timestampField,
+		MessageField:   *f.messageField,
+		ExtraFields:    extrasOverride,
+		Format:         *f.format,
+	})
+}
+
+// viewerOptions translates the configuration into UI options. The caller
+// adds the entry source.
+func viewerOptions(cfg config.Config) ui.Options {
+	targets := make([]ui.SendTarget, 0, len(cfg.SendTargets))
+	for _, t := range cfg.SendTargets {
+		targets = append(targets, ui.SendTarget{Name: t.Name, URL: t.URL, Template: t.Template, Headers: t.Headers})
+	}
+	alerts := make([]ui.AlertRule, 0, len(cfg.Alerts))
+	for _, a := range cfg.Alerts {
+		alerts = append(alerts, ui.AlertRule{
+			Name:      a.Name,
+			Filter:    a.Filter,
+			Quiet:     a.Quiet,
+			Notify:    a.Notify,
+			Command:   a.Command,
+			Pause:     a.Pause,
+			Threshold: a.Threshold,
+			Window:    a.Window,
+		})
+	}
+	columns := make([]ui.Column, 0, len(cfg.Columns))
+	for _, c := range cfg.Columns {
+		columns = append(columns, ui.Column{Field: c.Field, Title: c.Title, Width: c.Width, Right: c.Align == "right"})
+	}
+	actions := make([]ui.KeyAction, 0, len(cfg.KeyActions))
+	for _, a := range cfg.KeyActions {
+		actions = append(actions, ui.KeyAction{Key: a.Key, Command: a.Command, Interactive: a.Interactive})
+	}
+	return ui.Options{
+		Extra:          cfg.ExtraFields,
+		MaxItems:       cfg.MaxEntries,
+		PerSourceItems: cfg.PerSourceEntries,
+
+		RefreshRate: cfg.RefreshRate,
+		CompressRaw: cfg.CompressRaw,
+
+		TimestampField:    cfg.TimestampField,
+		MessageField:      cfg.MessageField,
+		PipeCommand:       cfg.PipeCommand,
+		TraceURL:          cfg.TraceURL,
+		EditorCommand:     cfg.EditorCommand,
+		CallerFields:      cfg.CallerFields,
+		Clipboard:         cfg.Clipboard,
+		CopyFormat:        cfg.CopyFormat,
+		Layout:            cfg.Layout,
+		Split:             cfg.Split,
+		RelativeTime:      cfg.RelativeTime,
+		Location:          cfg.Location(),
+		SearchIndex:       cfg.SearchIndex,
+		SendTargets:       targets,
+		Alerts:            alerts,
+		NotifyInterval:    cfg.NotifyInterval,
+		AlertCommandLimit: cfg.AlertCommands,
+		Watches:           cfg.Watches,
+		FilterPresets:     cfg.Filters,
+		SilenceTimeout:    cfg.SilenceTimeout,
+		SilenceBell:       cfg.SilenceBell,
+		SpikeSigma:        cfg.SpikeSigma,
+
+		RowTemplate:            cfg.RowTemplate,
+		RowDescriptionTemplate: cfg.RowDescriptionTemplate,
+		Columns:                columns,
+		KeyActions:             actions,
+		SourcePauseMode:        cfg.SourcePause,
+		StyleANSI:              cfg.ANSI == "style",
+		MarksDir:               cfg.MarksDir,
+		LevelField:             cfg.LevelField,
+		LevelColors:            cfg.LevelColors,
+	}
+}
+
+// tailerOptions translates the configuration into tailer options.
+func tailerOptions(cfg config.Config) logs.Options {
+	return logs.Options{
+		Parser: logs.ParserConfig{
+			Format:         cfg.Format,
+			TimestampField: cfg.TimestampField,
+			MessageField:   cfg.MessageField,
+			ExtraFields:    cfg.ExtraFields,
+			StripANSI:      cfg.ANSI == "strip",
+			Pattern:        cfg.LinePattern(),
+			LevelMap:       cfg.LevelMapping(),
+		},
+		Profiles:     cfg.ParserProfiles(),
+		TailLines:    cfg.TailLines,
+		Backpressure: logs.BackpressurePolicy(cfg.Backpressure),
+		Sources:      cfg.SourceOptions(),
+
+		EntryBuffer:   cfg.EntryBuffer,
+		ErrorBuffer:   cfg.ErrorBuffer,
+		ReadChunkSize: cfg.ReadChunkSize,
+		MaxEntrySize:  cfg.MaxEntrySize,
+		Transform:     logs.ComputeTransform(cfg.ComputedFields()),
+	}
+}
@@ -2,41 +2,98 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/pflag"
 
 	"github.com/marcuzy/logsviewer/internal/config"
+	"github.com/marcuzy/logsviewer/internal/export"
 	"github.com/marcuzy/logsviewer/internal/logs"
+	"github.com/marcuzy/logsviewer/internal/share"
+	"github.com/marcuzy/logsviewer/internal/store"
 	"github.com/marcuzy/logsviewer/internal/ui"
 )
 
+// Exit codes, distinct so that scripts can tell failures apart.
+const (
+	exitOK            = 0
+	exitError         = 1
+	exitUsage         = 2
+	exitSourceFailure = 3
+)
+
+// shutdownTimeout bounds how long sources get to stop after quitting.
+const shutdownTimeout = 2 * time.Second
+
+// subcommands maps a first argument to an alternative entry point. Each
+// returns the process exit code.
+var subcommands = map[string]func(args []string) int{
+	"attach": runAttach,
+	"bench":  runBench,
+	"print":  runPrint,
+	"pipe":   runPipe,
+	"serve":  runServe,
+	"wait":   runWait,
+	"replay": runReplay,
+	"open":   runOpen,
+}
+
 func main() {
-	flags := pflag.NewFlagSet("logsviewer", pflag.ExitOnError)
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
+	}
+	os.Exit(runViewer(os.Args[1:]))
+}
+
+func runViewer(args []string) int {
+	flags := pflag.NewFlagSet("logsviewer", pflag.ContinueOnError)
 	configPath := flags.StringP("config", "c", "", "path to configuration file")
-	files := flags.StringSliceP("file", "f", nil, "log file(s) to follow")
+	files := flags.StringSliceP("file", "f", nil, "log file(s) to follow; @list.txt reads paths from a file")
+	filesFrom := flags.String("files-from", "", "read log file paths from a file, one per line (- for stdin)")
+	dirs := flags.StringSlice("dir", nil, "follow every file in a directory, including files created later (repeatable)")
+	docker := flags.StringSlice("docker", nil, "follow the json-file log of a container, by name or ID (repeatable)")
 	timestampField := flags.String("timestamp-field", "", "JSON field containing the timestamp")
 	messageField := flags.String("message-field", "", "JSON field containing the message")
 	extraFields := flags.StringSlice("extra-field", nil, "additional field(s) to show in the log list (repeatable)")
+	format := flags.String("format", "", "default line format of the log files (json, logfmt, nginx, regex, docker, plain)")
 	tailLines := flags.Int("tail", -1, "number of lines to read from the end on startup")
 	maxEntries := flags.Int("max-entries", 0, "maximum number of log entries to keep in memory")
+	mergeWindow := flags.Duration("merge-window", 0, "merge files in timestamp order, waiting up to this long for slower files (e.g. 500ms)")
+	backpressure := flags.String("backpressure", "", "what to do when the UI falls behind: block, drop-oldest or drop-newest")
+	spill := flags.Bool("spill", false, "keep entries evicted from memory in a temporary file so they remain searchable")
+	spillDir := flags.String("spill-dir", "", "directory for the spill file (implies --spill)")
+	refreshRate := flags.Int("refresh-rate", 0, "maximum list updates per second while entries stream in (0 = update on every entry)")
+	debugAddr := flags.String("debug-addr", "", "serve pprof and runtime metrics on this address (e.g. :6060)")
+	controlPath := flags.String("control", "", "accept remote-control calls over gRPC on a unix socket at this path")
+	record := flags.String("record", "", "record ingested entries with their timing to this file for logsviewer replay")
+	tee := flags.String("tee", "", "append every ingested entry to this file as normalized NDJSON")
+	teeFilter := flags.String("tee-filter", "", "only tee entries containing this text, as the / search does")
+	exportOnExit := flags.String("export-on-exit", "", "write the buffered entries as NDJSON to this file when quitting")
+	sharePath := flags.String("share", "", "let other instances attach to this session over a unix socket at this path (logsviewer attach)")
 	showHelp := flags.BoolP("help", "h", false, "show usage")
 
 	flags.Usage = func() {
-		fmt.Fprintf(flags.Output(), "Usage: %s [flags]\n\nFlags:\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "Usage: %[1]s [flags]\n       %[1]s print [flags]\n       ... | %[1]s pipe [flags]\n       %[1]s serve [flags]\n       %[1]s replay [flags] session.lv\n       %[1]s open [flags] dump.ndjson\n       %[1]s attach [flags] session.sock\n       %[1]s wait --filter EXPR [flags]\n       %[1]s bench [flags]\n\nFlags:\n", os.Args[0])
 		flags.PrintDefaults()
 	}
 
-	if err := flags.Parse(os.Args[1:]); err != nil {
-		fmt.Fprintf(os.Stderr, "error parsing flags: %v\n", err)
-		os.Exit(1)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return exitOK
+		}
+		// pflag has already reported the error along with the usage.
+		return exitUsage
 	}
 
 	if *showHelp {
 		flags.Usage()
-		return
+		return exitOK
 	}
 
 	var tailPtr *int
@@ -49,49 +106,212 @@ func main() {
 		maxPtr = maxEntries
 	}
 
+	var refreshPtr *int
+	if flags.Changed("refresh-rate") {
+		refreshPtr = refreshRate
+	}
+
+	var mergePtr *time.Duration
+	if flags.Changed("merge-window") {
+		mergePtr = mergeWindow
+	}
+
+	var spillPtr *bool
+	if flags.Changed("spill") {
+		spillPtr = spill
+	}
+
 	overrideExtras := []string(nil)
 	if flags.Changed("extra-field") {
 		overrideExtras = *extraFields
 	}
 
-	cfg, err := config.Load(config.Flags{
+	cfgFlags := config.Flags{
 		ConfigPath:     *configPath,
 		Files:          *files,
+		FilesFrom:      *filesFrom,
+		Dirs:           *dirs,
+		Docker:         *docker,
 		TailLines:      tailPtr,
 		MaxEntries:     maxPtr,
+		RefreshRate:    refreshPtr,
+		MergeWindow:    mergePtr,
 		TimestampField: *timestampField,
 		MessageField:   *messageField,
 		ExtraFields:    overrideExtras,
-	})
+		Format:         *format,
+		Backpressure:   *backpressure,
+		Spill:          spillPtr,
+		SpillDir:       *spillDir,
+	}
+	cfg, err := config.Load(cfgFlags)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
-		os.Exit(1)
+		return exitUsage
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	tailer := logs.NewTailer(cfg.Files, logs.Options{
-		Parser: logs.ParserConfig{
-			TimestampField: cfg.TimestampField,
-			MessageField:   cfg.MessageField,
-			ExtraFields:    cfg.ExtraFields,
-		},
-		TailLines: cfg.TailLines,
-	})
+	tailerOpts := tailerOptions(cfg)
+	scriptErrs, err := addScript(cfg, &tailerOpts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+	processors := newProcessorHost(ctx, cfg)
+	if processors != nil {
+		// Computed fields may use what the processors add.
+		tailerOpts.Transform = logs.ChainTransforms(processors.Transform(), tailerOpts.Transform)
+	}
+	pluginErrs, err := addWasmPlugins(ctx, cfg, &tailerOpts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+	tailer := logs.NewTailer(cfg.Files, tailerOpts)
+
+	if *debugAddr != "" {
+		stop, err := startDebugServer(*debugAddr, tailer)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitError
+		}
+		defer stop()
+	}
+
+	var spillStore *store.Spill
+	if cfg.Spill {
+		spillStore, err = store.OpenSpill(cfg.SpillDir, cfg.SpillMaxEntries)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "open spill: %v\n", err)
+			return exitError
+		}
+		defer spillStore.Close()
+	}
 
 	entriesCh, errsCh := tailer.Start(ctx)
+	errsCh = mergeErrors(mergeErrors(errsCh, scriptErrs), pluginErrs)
+	if processors != nil {
+		entriesCh = mergeEntries(entriesCh, processors.Sources(tailerOpts.Parser, tailerOpts.Profiles))
+		errsCh = mergeErrors(errsCh, processors.Errors())
+	}
+	if cfg.MergeWindow > 0 {
+		entriesCh = logs.MergeByTime(ctx, entriesCh, cfg.MergeWindow)
+	}
+
+	var tees []teeResult
+	if *tee != "" {
+		teeFile, err := export.OpenTee(*tee)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tee: %v\n", err)
+			return exitError
+		}
+		var done <-chan error
+		entriesCh, done = teeEntries(ctx, entriesCh, teeFile, *teeFilter)
+		tees = append(tees, teeResult{name: "tee", done: done})
+	}
+	if *record != "" {
+		recorder, err := store.CreateRecorder(*record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "record: %v\n", err)
+			return exitError
+		}
+		var done <-chan error
+		entriesCh, done = teeEntries(ctx, entriesCh, recorder, "")
+		tees = append(tees, teeResult{name: "record", done: done})
+	}
+
+	var shareHost *share.Host
+	if *sharePath != "" {
+		shareHost, err = share.Listen(*sharePath, cfg.MaxEntries)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitError
+		}
+		var done <-chan error
+		entriesCh, done = teeEntries(ctx, entriesCh, shareHost, "")
+		tees = append(tees, teeResult{name: "share", done: done})
+	}
+
+	opts := viewerOptions(cfg)
+	opts.Entries = entriesCh
+	opts.Errors = errsCh
+	opts.Sources = tailer.Events()
+	opts.Spill = spillStore
+	opts.Stats = tailer.Stats
+	opts.SetSourcePaused = tailer.SetPaused
+	if shareHost != nil {
+		opts.OnViewChange = func(v ui.ViewState) { shareHost.SetView(share.View(v)) }
+	}
+	opts.Cancel = cancel
+	opts.SaveMapping = func(timestampField, messageField string) error {
+		return config.SaveFieldMapping(cfg.Path, timestampField, messageField)
+	}
+	m := ui.NewModel(opts)
+
+	programOpts := []tea.ProgramOption{tea.WithAltScreen()}
+	if cfgFlags.ReadsStdin() || cfg.ReadsStdin() {
+		programOpts = append(programOpts, tea.WithInputTTY())
+	}
 
-	m := ui.NewModel(ui.Options{
-		Entries:  entriesCh,
-		Errors:   errsCh,
-		Cancel:   cancel,
-		Extra:    cfg.ExtraFields,
-		MaxItems: cfg.MaxEntries,
-	})
+	program := tea.NewProgram(m, programOpts...)
+	if *controlPath != "" {
+		stop, err := startControlServer(*controlPath, tailer.Stats, program.Send)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitError
+		}
+		defer stop()
+	}
 
-	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+	final, err := program.Run()
+	cancel()
+	drainSources(entriesCh, errsCh, shutdownTimeout)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "program error: %v\n", err)
-		os.Exit(1)
+		return exitError
+	}
+
+	for _, t := range tees {
+		if err := <-t.done; err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", t.name, err)
+			return exitError
+		}
+	}
+
+	if *exportOnExit != "" {
+		if fm, ok := final.(ui.Model); ok {
+			if err := export.WriteFile(*exportOnExit, fm.Entries(), export.Options{Format: export.NDJSON}); err != nil {
+				fmt.Fprintf(os.Stderr, "export: %v\n", err)
+				return exitError
+			}
+		}
+	}
+
+	if n := tailer.Failures(); n > 0 {
+		fmt.Fprintf(os.Stderr, "%d source error(s) occurred while tailing\n", n)
+		return exitSourceFailure
+	}
+	return exitOK
+}
+
+// drainSources consumes what the tailer still sends after cancellation so
+// that its goroutines can exit and close their files.
+func drainSources(entries <-chan logs.LogEntry, errs <-chan error, timeout time.Duration) {
+	deadline := time.After(timeout)
+	for entries != nil || errs != nil {
+		select {
+		case _, ok := <-entries:
+			if !ok {
+				entries = nil
+			}
+		case _, ok := <-errs:
+			if !ok {
+				errs = nil
+			}
+		case <-deadline:
+			return
+		}
 	}
 }
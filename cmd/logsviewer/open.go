@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/pflag"
+
+	"github.com/marcuzy/logsviewer/internal/config"
+	"github.com/marcuzy/logsviewer/internal/export"
+	"github.com/marcuzy/logsviewer/internal/logs"
+	"github.com/marcuzy/logsviewer/internal/store"
+	"github.com/marcuzy/logsviewer/internal/ui"
+)
+
+// runOpen loads a saved file into the UI without following it: a session
+// recorded with --record, records written by --tee or the pipe subcommand,
+// or original lines as written by :export.
+func runOpen(args []string) int {
+	flags := pflag.NewFlagSet("logsviewer open", pflag.ContinueOnError)
+	configPath := flags.StringP("config", "c", "", "path to configuration file")
+	timestampField := flags.String("timestamp-field", "", "JSON field containing the timestamp of original lines")
+	messageField := flags.String("message-field", "", "JSON field containing the message of original lines")
+	format := flags.String("format", "", "line format of original lines (json, logfmt, nginx, regex, docker, plain)")
+	maxEntries := flags.Int("max-entries", 0, "maximum number of entries to load (default: the whole file)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s open [flags] dump.ndjson\n\nFlags:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+	path := flags.Arg(0)
+
+	cfg, err := config.Load(config.Flags{
+		ConfigPath:     *configPath,
+		TimestampField: *timestampField,
+		MessageField:   *messageField,
+		Format:         *format,
+		NoFiles:        true,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+	session, lines, err := inspectDump(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open: %v\n", err)
+		return exitError
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		entries <-chan logs.LogEntry
+		errs    <-chan error
+	)
+	if session {
+		entries, errs = store.Replay(ctx, path, 0)
+	} else {
+		entries, errs = importEntries(ctx, path, tailerOptions(cfg).Parser, cfg.ParserProfiles())
+	}
+
+	opts := viewerOptions(cfg)
+	opts.Entries = entries
+	opts.Errors = errs
+	opts.Cancel = cancel
+	// The file is loaded once, so keep all of it rather than the last
+	// max_entries as when following.
+	opts.MaxItems = max(opts.MaxItems, lines)
+	opts.SilenceTimeout = 0
+	if flags.Changed("max-entries") {
+		opts.MaxItems = *maxEntries
+	}
+	_, err = tea.NewProgram(ui.NewModel(opts), tea.WithAltScreen()).Run()
+	cancel()
+	drainSources(entries, errs, shutdownTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "program error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// inspectDump reports whether path starts with a session header and how
+// many lines it has.
+func inspectDump(path string) (session bool, lines int, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, 0, err
+	}
+	defer file.Close()
+	r := bufio.NewReader(file)
+	first, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, 0, err
+	}
+	session = strings.HasPrefix(first, `{"logsviewer_session":`)
+	lines = 1
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := r.Read(buf)
+		lines += bytes.Count(buf[:n], []byte{'\n'})
+		if err == io.EOF {
+			return session, lines, nil
+		}
+		if err != nil {
+			return false, 0, err
+		}
+	}
+}
+
+// importEntries reads the lines of an exported file. Normalized records
+// are restored as they were written; other lines are parsed as log lines
+// of path. A leading export description line is skipped.
+func importEntries(ctx context.Context, path string, parser logs.ParserConfig, profiles []logs.ParserProfile) (<-chan logs.LogEntry, <-chan error) {
+	entries := make(chan logs.LogEntry, 256)
+	errs := make(chan error, 16)
+	go func() {
+		defer close(entries)
+		defer close(errs)
+		file, err := os.Open(path)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer file.Close()
+
+		r := bufio.NewReader(file)
+		for first := true; ; first = false {
+			line, readErr := r.ReadString('\n')
+			line = strings.TrimRight(line, "\r\n")
+			if line != "" && !(first && isExportMeta(line)) {
+				var err error
+				entry, ok := export.ParseRecord(line)
+				if !ok {
+					entry, err = logs.ParseLine(path, line, parser, profiles)
+				}
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+						// The UI shows one error at a time; do not stall
+						// the import on a file of unparsable lines.
+					}
+				} else {
+					select {
+					case entries <- entry:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			if readErr != nil {
+				if readErr != io.EOF {
+					errs <- fmt.Errorf("read %s: %w", path, readErr)
+				}
+				return
+			}
+		}
+	}()
+	return entries, errs
+}
+
+// isExportMeta reports whether line is the {"export": ...} line that
+// :export writes ahead of a selection.
+func isExportMeta(line string) bool {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &doc); err != nil {
+		return false
+	}
+	_, ok := doc["export"]
+	return ok && len(doc) == 1
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	"github.com/marcuzy/logsviewer/internal/config"
+	"github.com/marcuzy/logsviewer/internal/export"
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// stdinPath is the source path of entries read from stdin; parser profiles
+// can match it.
+const stdinPath = logs.StdinPath
+
+// runPipe parses log lines from stdin with the configured parsers and
+// transforms and writes the entries matching --filter to stdout as NDJSON,
+// for use in shell pipelines.
+func runPipe(args []string) int {
+	flags := pflag.NewFlagSet("logsviewer pipe", pflag.ContinueOnError)
+	configPath := flags.StringP("config", "c", "", "path to configuration file")
+	timestampField := flags.String("timestamp-field", "", "JSON field containing the timestamp")
+	messageField := flags.String("message-field", "", "JSON field containing the message")
+	format := flags.String("format", "", "line format of the input (json, logfmt, nginx, regex, docker, plain)")
+	filterExpr := flags.String("filter", "", `only write matching entries, e.g. 'level=error service=api "timed out"'`)
+	raw := flags.Bool("raw", false, "write the original lines instead of normalized records")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: ... | %s pipe [flags]\n\nFlags:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	cfg, err := config.Load(config.Flags{
+		ConfigPath:     *configPath,
+		TimestampField: *timestampField,
+		MessageField:   *messageField,
+		Format:         *format,
+		NoFiles:        true,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+	filter, err := logs.ParseFilter(*filterExpr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pipe: %v\n", err)
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Stdin is read by a tailer as the viewer reads it, so that source
+	// options, computed fields, the script and processors apply here too.
+	opts := tailerOptions(cfg)
+	scriptErrs, err := addScript(cfg, &opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+	if processors := newProcessorHost(ctx, cfg); processors != nil {
+		opts.Transform = logs.ChainTransforms(processors.Transform(), opts.Transform)
+	}
+	pluginErrs, err := addWasmPlugins(ctx, cfg, &opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+	opts.Once = true
+	tailer := logs.NewTailer([]string{stdinPath}, opts)
+	entries, errs := tailer.Start(ctx)
+	errs = mergeErrors(mergeErrors(errs, scriptErrs), pluginErrs)
+
+	out := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(out)
+	for entries != nil || errs != nil {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				entries = nil
+				continue
+			}
+			if filter.Match(entry) {
+				var err error
+				if *raw {
+					out.WriteString(entry.RawLine())
+					err = out.WriteByte('\n')
+				} else {
+					err = enc.Encode(export.NewRecord(entry))
+				}
+				if err != nil {
+					// Typically a closed pipe, e.g. "| head".
+					return exitOK
+				}
+			}
+			if len(entries) == 0 {
+				// Flush when caught up so that output keeps pace with a
+				// slow producer such as "tail -f".
+				if err := out.Flush(); err != nil {
+					return exitOK
+				}
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			out.Flush()
+			fmt.Fprintf(os.Stderr, "pipe: %v\n", err)
+		}
+	}
+	out.Flush()
+
+	if tailer.Failures() > 0 {
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+
+	"github.com/marcuzy/logsviewer/internal/config"
+	"github.com/marcuzy/logsviewer/internal/logs"
+	"github.com/marcuzy/logsviewer/internal/plugin"
+	"github.com/marcuzy/logsviewer/internal/script"
+	"github.com/marcuzy/logsviewer/internal/wasm"
+)
+
+// newProcessorHost prepares the configured processors, or returns nil if
+// there are none.
+func newProcessorHost(ctx context.Context, cfg config.Config) *plugin.Host {
+	if len(cfg.Processors) == 0 {
+		return nil
+	}
+	specs := make([]plugin.Spec, len(cfg.Processors))
+	for i, p := range cfg.Processors {
+		specs[i] = plugin.Spec{Name: p.Name, Command: p.Command, Kind: plugin.Kind(p.Kind)}
+	}
+	return plugin.NewHost(ctx, specs)
+}
+
+// addScript puts the configured transform script in front of the
+// transforms of opts, so that computed fields may use what it sets. It
+// returns the script's errors, or nil without a script.
+func addScript(cfg config.Config, opts *logs.Options) (<-chan error, error) {
+	if cfg.Script == "" {
+		return nil, nil
+	}
+	s, err := script.Load(cfg.Script, cfg.ExtraFields)
+	if err != nil {
+		return nil, err
+	}
+	opts.Transform = logs.ChainTransforms(s.Transform(), opts.Transform)
+	return s.Errors(), nil
+}
+
+// addWasmPlugins puts the configured WASM plugins in front of the
+// transforms of opts, in the configured order, so that processors, the
+// script and computed fields see what they parse. It returns their errors,
+// or nil without plugins.
+func addWasmPlugins(ctx context.Context, cfg config.Config, opts *logs.Options) (<-chan error, error) {
+	var (
+		fns  []logs.TransformFunc
+		errs <-chan error
+	)
+	for _, path := range cfg.WasmPlugins {
+		p, err := wasm.Load(ctx, path, cfg.ExtraFields)
+		if err != nil {
+			return nil, err
+		}
+		fns = append(fns, p.Transform())
+		if errs == nil {
+			errs = p.Errors()
+		} else {
+			errs = mergeErrors(errs, p.Errors())
+		}
+	}
+	if len(fns) > 0 {
+		opts.Transform = logs.ChainTransforms(append(fns, opts.Transform)...)
+	}
+	return errs, nil
+}
+
+// mergeEntries forwards the entries of a and b until both are closed. A
+// nil b returns a.
+func mergeEntries(a, b <-chan logs.LogEntry) <-chan logs.LogEntry {
+	if b == nil {
+		return a
+	}
+	out := make(chan logs.LogEntry, cap(a))
+	go func() {
+		defer close(out)
+		for a != nil || b != nil {
+			select {
+			case entry, ok := <-a:
+				if !ok {
+					a = nil
+					continue
+				}
+				out <- entry
+			case entry, ok := <-b:
+				if !ok {
+					b = nil
+					continue
+				}
+				out <- entry
+			}
+		}
+	}()
+	return out
+}
+
+// mergeErrors forwards the errors of a and b until a is closed; b is
+// expected to stay open. A nil b returns a.
+func mergeErrors(a, b <-chan error) <-chan error {
+	if b == nil {
+		return a
+	}
+	out := make(chan error, cap(a))
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case err, ok := <-a:
+				if !ok {
+					return
+				}
+				out <- err
+			case err := <-b:
+				out <- err
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/spf13/pflag"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// runPrint writes formatted entries to stdout instead of starting the UI,
+// using the same configuration, parsers and search as the viewer.
+func runPrint(args []string) int {
+	flags := pflag.NewFlagSet("logsviewer print", pflag.ContinueOnError)
+	source := addSourceFlags(flags)
+	filter := flags.String("filter", "", "only print entries containing this text, as the / search does")
+	follow := flags.BoolP("follow", "F", false, "keep printing entries as they are appended")
+	color := flags.String("color", "auto", "colorize output: auto, always or never")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	cfg, err := source.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+
+	renderer := lipgloss.NewRenderer(os.Stdout)
+	switch *color {
+	case "auto":
+	case "always":
+		renderer.SetColorProfile(termenv.ANSI256)
+	case "never":
+		renderer.SetColorProfile(termenv.Ascii)
+	default:
+		fmt.Fprintf(os.Stderr, "print: unknown --color value %q\n", *color)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts := tailerOptions(cfg)
+	opts.Once = !*follow
+	scriptErrs, err := addScript(cfg, &opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+	pluginErrs, err := addWasmPlugins(ctx, cfg, &opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+	tailer := logs.NewTailer(cfg.Files, opts)
+	entries, errs := tailer.Start(ctx)
+	errs = mergeErrors(mergeErrors(errs, scriptErrs), pluginErrs)
+	if cfg.MergeWindow > 0 {
+		entries = logs.MergeByTime(ctx, entries, cfg.MergeWindow)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	p := newEntryPrinter(out, renderer, cfg.ExtraFields, len(cfg.Files) > 1)
+	p.location = cfg.Location()
+	search := logs.ParseSearch(*filter).In(p.location)
+	for entries != nil || errs != nil {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				entries = nil
+				continue
+			}
+			if search.Match(entry) {
+				p.print(entry)
+			}
+			if len(entries) == 0 {
+				// Flush whenever caught up so that followed output appears
+				// promptly without flushing every line of a backlog.
+				if err := out.Flush(); err != nil {
+					// Typically a closed pipe, e.g. "| head".
+					return exitOK
+				}
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			out.Flush()
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+	out.Flush()
+
+	if n := tailer.Failures(); n > 0 {
+		return exitSourceFailure
+	}
+	return exitOK
+}
+
+// entryPrinter formats entries as single lines: timestamp, source,
+// message and the configured extra fields.
+type entryPrinter struct {
+	w          io.Writer
+	extras     []string
+	showSource bool
+	// location is the zone timestamps are printed in.
+	location *time.Location
+
+	timestamp lipgloss.Style
+	source    lipgloss.Style
+	extra     lipgloss.Style
+}
+
+func newEntryPrinter(w io.Writer, r *lipgloss.Renderer, extras []string, showSource bool) *entryPrinter {
+	return &entryPrinter{
+		w:          w,
+		extras:     extras,
+		showSource: showSource,
+		timestamp:  r.NewStyle().Foreground(lipgloss.Color("244")),
+		source:     r.NewStyle().Foreground(lipgloss.Color("39")),
+		extra:      r.NewStyle().Foreground(lipgloss.Color("245")),
+		location:   time.Local,
+	}
+}
+
+func (p *entryPrinter) print(entry logs.LogEntry) {
+	line := ""
+	if ts := entry.DisplayTimestampIn(p.location); ts != "" {
+		line = p.timestamp.Render(ts) + "  "
+	}
+	if p.showSource {
+		line += p.source.Render(filepath.Base(entry.Path)) + "  "
+	}
+	message := entry.Message
+	if message == "" {
+		message = entry.RawLine()
+	}
+	line += message
+
+	for _, name := range p.extras {
+		if val := entry.ExtraValue(name); val != "" {
+			line += "  " + p.extra.Render(name+"="+val)
+		}
+	}
+	fmt.Fprintln(p.w, line)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/pflag"
+
+	"github.com/marcuzy/logsviewer/internal/config"
+	"github.com/marcuzy/logsviewer/internal/store"
+	"github.com/marcuzy/logsviewer/internal/ui"
+)
+
+// runReplay plays a session recorded with --record through the UI.
+func runReplay(args []string) int {
+	flags := pflag.NewFlagSet("logsviewer replay", pflag.ContinueOnError)
+	configPath := flags.StringP("config", "c", "", "path to configuration file")
+	speedText := flags.String("speed", "1x", "playback speed relative to the recording, e.g. 4x (0 = as fast as possible)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s replay [flags] session.lv\n\nFlags:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+	speed, err := parseSpeed(*speedText)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		return exitUsage
+	}
+
+	cfg, err := config.Load(config.Flags{ConfigPath: *configPath, NoFiles: true})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	entries, errs := store.Replay(ctx, flags.Arg(0), speed)
+
+	opts := viewerOptions(cfg)
+	opts.Entries = entries
+	opts.Errors = errs
+	opts.Cancel = cancel
+	m := ui.NewModel(opts)
+	_, err = tea.NewProgram(m, tea.WithAltScreen()).Run()
+	cancel()
+	drainSources(entries, errs, shutdownTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "program error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// parseSpeed accepts a playback factor such as "4", "4x" or "0.5x".
+func parseSpeed(text string) (float64, error) {
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(text), "x"), 64)
+	if err != nil || speed < 0 {
+		return 0, fmt.Errorf("invalid speed %q", text)
+	}
+	return speed, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/pflag"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+	"github.com/marcuzy/logsviewer/internal/server"
+)
+
+// runServe tails the configured files without the UI and exposes the
+// buffered entries over HTTP.
+func runServe(args []string) int {
+	flags := pflag.NewFlagSet("logsviewer serve", pflag.ContinueOnError)
+	source := addSourceFlags(flags)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	web := flags.Bool("web", false, "also serve a web UI with list, detail and search at /")
+	maxEntries := flags.Int("max-entries", 0, "maximum number of log entries to keep (default: max_entries from the config)")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	cfg, err := source.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+	if flags.Changed("max-entries") {
+		cfg.MaxEntries = *maxEntries
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "serve: %v\n", err)
+		return exitError
+	}
+
+	opts := tailerOptions(cfg)
+	scriptErrs, err := addScript(cfg, &opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+	pluginErrs, err := addWasmPlugins(ctx, cfg, &opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+	tailer := logs.NewTailer(cfg.Files, opts)
+	entries, errs := tailer.Start(ctx)
+	errs = mergeErrors(mergeErrors(errs, scriptErrs), pluginErrs)
+	if cfg.MergeWindow > 0 {
+		entries = logs.MergeByTime(ctx, entries, cfg.MergeWindow)
+	}
+
+	buffer := server.NewBuffer(cfg.MaxEntries)
+	srv := &http.Server{
+		Handler:     server.New(buffer, server.Options{Stats: tailer.Stats, Web: *web}),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() { _ = srv.Serve(ln) }()
+	fmt.Fprintf(os.Stderr, "serving %d file(s) on http://%s\n", len(cfg.Files), ln.Addr())
+
+	for entries != nil || errs != nil {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				entries = nil
+				continue
+			}
+			buffer.Add(entry)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	_ = srv.Shutdown(shutdownCtx)
+
+	if n := tailer.Failures(); n > 0 {
+		return exitSourceFailure
+	}
+	return exitOK
+}
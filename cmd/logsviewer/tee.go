@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// entryWriter is a file that entries are copied to while they stream to the
+// UI, such as --tee or --record.
+type entryWriter interface {
+	Write(entry logs.LogEntry) error
+	Flush() error
+	Close() error
+}
+
+type teeResult struct {
+	name string
+	done <-chan error
+}
+
+// teeEntries passes entries through while writing those matching filter to
+// w. The returned error channel yields the first write error, or nil, once
+// in is closed and w is closed. After ctx is canceled entries are still
+// written but no longer passed on.
+func teeEntries(ctx context.Context, in <-chan logs.LogEntry, w entryWriter, filter string) (<-chan logs.LogEntry, <-chan error) {
+	out := make(chan logs.LogEntry, cap(in))
+	done := make(chan error, 1)
+	search := logs.ParseSearch(filter)
+	go func() {
+		defer close(out)
+		var werr error
+		for entry := range in {
+			if werr == nil && search.Match(entry) {
+				werr = w.Write(entry)
+			}
+			if werr == nil && len(in) == 0 {
+				// Flush when caught up so the file trails the sources
+				// closely without a write per entry.
+				werr = w.Flush()
+			}
+			select {
+			case out <- entry:
+			case <-ctx.Done():
+			}
+		}
+		if err := w.Close(); werr == nil {
+			werr = err
+		}
+		done <- werr
+	}()
+	return out, done
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// exitNoMatch is the exit code of wait when nothing matched in time, as
+// grep exits with 1 when it finds nothing.
+const exitNoMatch = 1
+
+// runWait follows the files until an entry matches a filter and exits 0,
+// or exits with exitNoMatch once the timeout passes, for gating deploy
+// scripts and smoke tests on what a service logs.
+func runWait(args []string) int {
+	flags := pflag.NewFlagSet("logsviewer wait", pflag.ContinueOnError)
+	source := addSourceFlags(flags)
+	filterExpr := flags.String("filter", "", "filter the entries must match, e.g. 'level=fatal' (required)")
+	timeout := flags.Duration("timeout", 0, "give up after this long (0 = wait indefinitely)")
+	count := flags.Int("count", 1, "number of matching entries to wait for")
+	quiet := flags.BoolP("quiet", "q", false, "do not print the matching lines")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s wait --filter EXPR [flags]\n\nExits 0 once an entry matches, %d on timeout.\nWithout --tail only lines appended after startup are checked.\n\nFlags:\n", os.Args[0], exitNoMatch)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if *filterExpr == "" || *count < 1 {
+		flags.Usage()
+		return exitUsage
+	}
+	filter, err := logs.ParseFilter(*filterExpr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "wait: %v\n", err)
+		return exitUsage
+	}
+	cfg, err := source.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+
+	// Text is matched against timestamps as print shows them.
+	filter = filter.In(cfg.Location())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var expired <-chan time.Time
+	if *timeout > 0 {
+		timer := time.NewTimer(*timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	opts := tailerOptions(cfg)
+	opts.SkipExisting = !flags.Changed("tail")
+	scriptErrs, err := addScript(cfg, &opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+	pluginErrs, err := addWasmPlugins(ctx, cfg, &opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return exitUsage
+	}
+	tailer := logs.NewTailer(cfg.Files, opts)
+	entries, errs := tailer.Start(ctx)
+	errs = mergeErrors(mergeErrors(errs, scriptErrs), pluginErrs)
+
+	matched := 0
+	for {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				return exitNoMatch
+			}
+			if !filter.Match(entry) {
+				continue
+			}
+			if !*quiet {
+				fmt.Println(entry.RawLine())
+			}
+			if matched++; matched == *count {
+				return exitOK
+			}
+		case err, ok := <-errs:
+			if ok {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+			} else {
+				errs = nil
+			}
+		case <-expired:
+			fmt.Fprintf(os.Stderr, "wait: no match within %s\n", *timeout)
+			return exitNoMatch
+		case <-ctx.Done():
+			return exitNoMatch
+		}
+	}
+}
@@ -1,39 +1,220 @@
 package config
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
 )
 
 const (
 	defaultTimestampField = "timestamp"
 	defaultMessageField   = "message"
+	defaultRefreshRate    = 20
 )
 
 // Config represents the merged application configuration.
 type Config struct {
-	Files          []string `mapstructure:"files"`
-	TailLines      int      `mapstructure:"tail_lines"`
-	MaxEntries     int      `mapstructure:"max_entries"`
-	TimestampField string   `mapstructure:"timestamp_field"`
-	MessageField   string   `mapstructure:"message_field"`
-	ExtraFields    []string `mapstructure:"extra_fields"`
+	Files          []string          `mapstructure:"files"`
+	Dirs           []string          `mapstructure:"dirs"`
+	Docker         []string          `mapstructure:"docker"`
+	DockerRoot     string            `mapstructure:"docker_root"`
+	TailLines      int               `mapstructure:"tail_lines"`
+	MaxEntries     int               `mapstructure:"max_entries"`
+	RefreshRate    int               `mapstructure:"refresh_rate"`
+	CompressRaw    bool              `mapstructure:"compress_raw"`
+	MaxEntrySize   int               `mapstructure:"max_entry_size"`
+	MergeWindow    time.Duration     `mapstructure:"merge_window"`
+	TimestampField string            `mapstructure:"timestamp_field"`
+	MessageField   string            `mapstructure:"message_field"`
+	ExtraFields    []string          `mapstructure:"extra_fields"`
+	Format         string            `mapstructure:"format"`
+	Pattern        string            `mapstructure:"pattern"`
+	Parsers        []Parser          `mapstructure:"parsers"`
+	Backpressure   string            `mapstructure:"backpressure"`
+	EntryBuffer    int               `mapstructure:"entry_buffer"`
+	ErrorBuffer    int               `mapstructure:"error_buffer"`
+	ReadChunkSize  int               `mapstructure:"read_chunk_size"`
+	Sources        []Source          `mapstructure:"sources"`
+	Spill          bool              `mapstructure:"spill"`
+	SpillDir       string            `mapstructure:"spill_dir"`
+	PipeCommand    string            `mapstructure:"pipe_command"`
+	TraceURL       string            `mapstructure:"trace_url"`
+	EditorCommand  string            `mapstructure:"editor_command"`
+	CallerFields   []string          `mapstructure:"caller_fields"`
+	Clipboard      string            `mapstructure:"clipboard"`
+	CopyFormat     string            `mapstructure:"copy_format"`
+	Layout         string            `mapstructure:"layout"`
+	Split          float64           `mapstructure:"split"`
+	RelativeTime   bool              `mapstructure:"relative_time"`
+	Timezone       string            `mapstructure:"timezone"`
+	SearchIndex    bool              `mapstructure:"search_index"`
+	SendTargets    []SendTarget      `mapstructure:"send_targets"`
+	Alerts         []Alert           `mapstructure:"alerts"`
+	NotifyInterval time.Duration     `mapstructure:"notify_interval"`
+	AlertCommands  int               `mapstructure:"alert_commands"`
+	Watches        []string          `mapstructure:"watches"`
+	Filters        map[string]string `mapstructure:"filters"`
+	SilenceTimeout time.Duration     `mapstructure:"silence_timeout"`
+	SilenceBell    bool              `mapstructure:"silence_bell"`
+	SpikeSigma     float64           `mapstructure:"spike_sigma"`
+	Processors     []Processor       `mapstructure:"processors"`
+	Script         string            `mapstructure:"script"`
+	// WasmPlugins are WebAssembly modules that parse entries first.
+	WasmPlugins []string `mapstructure:"wasm_plugins"`
+	// Computed are "name = expression" definitions of computed fields.
+	Computed []string `mapstructure:"computed_fields"`
+	// RowTemplate and RowDescriptionTemplate replace the lines of a list
+	// row.
+	RowTemplate            string `mapstructure:"row_template"`
+	RowDescriptionTemplate string `mapstructure:"row_description_template"`
+	// Columns draw the list as a table of one line per entry.
+	Columns []Column `mapstructure:"columns"`
+
+	KeyActions []KeyAction `mapstructure:"key_actions"`
+	// SourcePause is the default mode of :pause-source: display or read.
+	SourcePause string `mapstructure:"source_pause"`
+	// ANSI decides what happens to escape sequences producers write:
+	// hide (dropped when drawing), strip (removed before parsing) or style
+	// (colors shown in the list).
+	ANSI string `mapstructure:"ansi"`
+	// LevelField and LevelColors choose the color of list rows by the
+	// value of a level field.
+	LevelField  string            `mapstructure:"level_field"`
+	LevelColors map[string]string `mapstructure:"level_colors"`
+	// LevelMap renames raw level values, e.g. pino's "30" to "info".
+	LevelMap map[string]string `mapstructure:"level_map"`
+	// MarksDir keeps the sidecar files of bookmarks and notes in one
+	// directory instead of next to the log files.
+	MarksDir string `mapstructure:"marks_dir"`
+	// PerSourceEntries caps the buffer each file keeps for its tab.
+	PerSourceEntries int `mapstructure:"per_source_entries"`
+	// SpillMaxEntries caps the entries kept in the spill file; zero keeps
+	// them all.
+	SpillMaxEntries int `mapstructure:"spill_max_entries"`
+
+	// Path is the most specific config file that was loaded, if any.
+	Path string `mapstructure:"-"`
+}
+
+// Parser assigns a line format to files whose path matches a glob pattern.
+// Entries are evaluated in order and the first match wins.
+type Parser struct {
+	Match          string `mapstructure:"match"`
+	Format         string `mapstructure:"format"`
+	TimestampField string `mapstructure:"timestamp_field"`
+	MessageField   string `mapstructure:"message_field"`
+	Pattern        string `mapstructure:"pattern"`
+}
+
+// Source holds per-source settings for files whose path matches a glob
+// pattern. Entries are evaluated in order and the first match wins.
+type Source struct {
+	Match         string `mapstructure:"match"`
+	Backpressure  string `mapstructure:"backpressure"`
+	ReadChunkSize int    `mapstructure:"read_chunk_size"`
+	// Rewrite rules and Join, a pattern of continuation lines, fix raw
+	// lines before they are parsed.
+	Rewrite []RewriteRule `mapstructure:"rewrite"`
+	Join    string        `mapstructure:"join"`
+	// JoinJSON makes every line that does not start a JSON object a
+	// continuation, for stack traces written after JSON lines.
+	JoinJSON bool `mapstructure:"join_json"`
+	// TimeOffset corrects the clock of the machine that wrote the files,
+	// e.g. "-1.5s" for one running ahead.
+	TimeOffset time.Duration `mapstructure:"time_offset"`
+}
+
+// KeyAction binds a key to a command template run for the selected
+// entry.
+type KeyAction struct {
+	Key         string `mapstructure:"key"`
+	Command     string `mapstructure:"command"`
+	Interactive bool   `mapstructure:"interactive"`
+}
+
+// RewriteRule replaces the matches of the regular expression Pattern.
+type RewriteRule struct {
+	Pattern string `mapstructure:"pattern"`
+	Replace string `mapstructure:"replace"`
+}
+
+// SendTarget is a webhook that selected entries can be posted to with
+// :send. Template renders the request body; Headers are added to the
+// request.
+type SendTarget struct {
+	Name     string            `mapstructure:"name"`
+	URL      string            `mapstructure:"url"`
+	Template string            `mapstructure:"template"`
+	Headers  map[string]string `mapstructure:"headers"`
+}
+
+// Column is a column of the list drawn as a table. A zero Width shares the
+// width left by the other columns; Align is left or right.
+type Column struct {
+	Field string `mapstructure:"field"`
+	Title string `mapstructure:"title"`
+	Width int    `mapstructure:"width"`
+	Align string `mapstructure:"align"`
+}
+
+// Processor is an external program exchanging entries as NDJSON: a
+// transform rewrites or drops every entry, a source adds its own.
+type Processor struct {
+	Name    string `mapstructure:"name"`
+	Command string `mapstructure:"command"`
+	Kind    string `mapstructure:"kind"`
+}
+
+// Alert raises a status line badge and rings the terminal bell when an
+// entry matching Filter arrives, and with Notify shows a desktop
+// notification.
+type Alert struct {
+	Name    string `mapstructure:"name"`
+	Filter  string `mapstructure:"filter"`
+	Quiet   bool   `mapstructure:"quiet"`
+	Notify  bool   `mapstructure:"notify"`
+	Command string `mapstructure:"command"`
+	Pause   bool   `mapstructure:"pause"`
+	// Threshold, when set, raises the alert only when more than Threshold
+	// entries match within Window.
+	Threshold int           `mapstructure:"threshold"`
+	Window    time.Duration `mapstructure:"window"`
 }
 
 // Flags captures CLI overrides supplied by the user.
 type Flags struct {
 	ConfigPath     string
 	Files          []string
+	FilesFrom      string
+	Dirs           []string
+	Docker         []string
 	TailLines      *int
 	MaxEntries     *int
+	RefreshRate    *int
+	MergeWindow    *time.Duration
 	TimestampField string
 	MessageField   string
 	ExtraFields    []string
+	Format         string
+	Backpressure   string
+	Spill          *bool
+	SpillDir       string
+	// NoFiles allows an empty file list, for callers that get their
+	// entries elsewhere, e.g. from a recorded session.
+	NoFiles bool
 }
 
 // Load resolves configuration from defaults, config files, and CLI overrides.
@@ -41,70 +222,553 @@ func Load(flags Flags) (Config, error) {
 	v := viper.New()
 	setDefaults(v)
 
+	var source string
 	if flags.ConfigPath != "" {
 		v.SetConfigFile(flags.ConfigPath)
+		if err := v.ReadInConfig(); err != nil {
+			return Config{}, fmt.Errorf("read config: %w", err)
+		}
+		source = flags.ConfigPath
 	} else {
-		addDefaultConfigPaths(v)
-		v.SetConfigName("logsviewer")
-	}
-
-	if err := readConfig(v); err != nil {
-		return Config{}, err
+		var err error
+		if source, err = mergeConfigLayers(v, defaultConfigLayers()); err != nil {
+			return Config{}, err
+		}
 	}
 
 	var cfg Config
 	if err := v.Unmarshal(&cfg); err != nil {
 		return Config{}, fmt.Errorf("decode config: %w", err)
 	}
+	cfg.Path = source
 
 	cfg = applyOverrides(cfg, flags)
 	cfg = ensureDefaults(cfg)
 
-	if len(cfg.Files) == 0 {
-		return Config{}, fmt.Errorf("no log files configured; set via config file or --file flag")
+	if flags.FilesFrom != "" && len(flags.Files) == 0 {
+		// Like --file, a CLI file list replaces the configured files.
+		cfg.Files = nil
+	}
+	// Sources given on the command line replace the configured ones of
+	// every kind.
+	cliFiles := len(flags.Files) > 0 || flags.FilesFrom != ""
+	if (len(flags.Dirs) > 0 || len(flags.Docker) > 0) && !cliFiles {
+		cfg.Files = nil
+	}
+	if len(flags.Dirs) == 0 && (cliFiles || len(flags.Docker) > 0) {
+		cfg.Dirs = nil
+	}
+	if len(flags.Docker) == 0 && (cliFiles || len(flags.Dirs) > 0) {
+		cfg.Docker = nil
+	}
+	files, err := expandFileLists(cfg.Files, flags.FilesFrom)
+	if err != nil {
+		return Config{}, err
+	}
+	patterns, err := dirPatterns(cfg.Dirs)
+	if err != nil {
+		return Config{}, err
+	}
+	containers, err := dockerFiles(&cfg)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Files = uniquePaths(append(append(files, patterns...), containers...))
+
+	if len(cfg.Files) == 0 && !flags.NoFiles {
+		return Config{}, fmt.Errorf("no log files configured; set via config file, --file, --dir or --docker flag")
+	}
+	if flags.ReadsStdin() && cfg.ReadsStdin() {
+		return Config{}, fmt.Errorf("stdin cannot be both a file list and a log source (-)")
+	}
+
+	if err := validateFormats(cfg); err != nil {
+		return Config{}, err
+	}
+	if err := validateSources(cfg); err != nil {
+		return Config{}, err
+	}
+	switch cfg.Clipboard {
+	case "auto", "os", "osc52":
+	default:
+		return Config{}, fmt.Errorf("unknown clipboard mode %q (supported: auto, os, osc52)", cfg.Clipboard)
+	}
+	switch cfg.CopyFormat {
+	case "raw", "pretty":
+	default:
+		return Config{}, fmt.Errorf("unknown copy format %q (supported: raw, pretty)", cfg.CopyFormat)
+	}
+	switch cfg.Layout {
+	case "vertical", "horizontal", "auto":
+	default:
+		return Config{}, fmt.Errorf("unknown layout %q (supported: vertical, horizontal, auto)", cfg.Layout)
+	}
+	if cfg.Split < 0.2 || cfg.Split > 0.8 {
+		return Config{}, fmt.Errorf("split must be between 0.2 and 0.8, got %v", cfg.Split)
+	}
+	for raw, level := range cfg.LevelMap {
+		if strings.TrimSpace(level) == "" {
+			return Config{}, fmt.Errorf("level_map.%s: level is empty", raw)
+		}
+	}
+	if _, err := loadLocation(cfg.Timezone); err != nil {
+		return Config{}, fmt.Errorf("timezone: %w", err)
+	}
+	for i, c := range cfg.Columns {
+		if c.Field == "" {
+			return Config{}, fmt.Errorf("columns[%d]: field is required", i)
+		}
+		if c.Width < 0 {
+			return Config{}, fmt.Errorf("columns[%d]: width must not be negative", i)
+		}
+		switch c.Align {
+		case "", "left", "right":
+		default:
+			return Config{}, fmt.Errorf("columns[%d]: align must be left or right, got %q", i, c.Align)
+		}
+	}
+	if err := validateSendTargets(cfg); err != nil {
+		return Config{}, err
+	}
+	if err := validateProcessors(cfg); err != nil {
+		return Config{}, err
+	}
+	if err := validateKeyActions(cfg); err != nil {
+		return Config{}, err
+	}
+	switch cfg.SourcePause {
+	case "", "display", "read":
+	default:
+		return Config{}, fmt.Errorf("unknown source_pause mode %q (supported: display, read)", cfg.SourcePause)
+	}
+	switch cfg.ANSI {
+	case "", "hide", "strip", "style":
+	default:
+		return Config{}, fmt.Errorf("unknown ansi mode %q (supported: hide, strip, style)", cfg.ANSI)
+	}
+	for _, def := range cfg.Computed {
+		f, err := logs.ParseComputedField(def)
+		if err != nil {
+			return Config{}, err
+		}
+		// Computed fields can be shown like configured extra fields.
+		if !slices.Contains(cfg.ExtraFields, f.Name) {
+			cfg.ExtraFields = append(cfg.ExtraFields, f.Name)
+		}
+	}
+	if err := validateAlerts(cfg); err != nil {
+		return Config{}, err
 	}
 
 	return cfg, nil
 }
 
+// ParserProfiles converts the configured parser entries for the tailer.
+func (c Config) ParserProfiles() []logs.ParserProfile {
+	out := make([]logs.ParserProfile, 0, len(c.Parsers))
+	for _, p := range c.Parsers {
+		out = append(out, logs.ParserProfile{
+			Match:          p.Match,
+			Format:         p.Format,
+			TimestampField: p.TimestampField,
+			MessageField:   p.MessageField,
+			Pattern:        compilePattern(p.Pattern),
+		})
+	}
+	return out
+}
+
+// Location returns the zone timestamps are shown in: the local one, UTC or
+// a named zone from the tz database.
+func (c Config) Location() *time.Location {
+	loc, err := loadLocation(c.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	switch strings.ToLower(name) {
+	case "", "local":
+		return time.Local, nil
+	case "utc":
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// LinePattern returns the compiled pattern of the regex format, which Load
+// has validated, or nil if none is set.
+func (c Config) LinePattern() *regexp.Regexp {
+	return compilePattern(c.Pattern)
+}
+
+// LevelMapping returns level_map with lower-cased keys and values, as the
+// parser looks raw levels up.
+func (c Config) LevelMapping() map[string]string {
+	if len(c.LevelMap) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(c.LevelMap))
+	for raw, level := range c.LevelMap {
+		out[strings.ToLower(raw)] = strings.ToLower(strings.TrimSpace(level))
+	}
+	return out
+}
+
+func compilePattern(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// ComputedFields parses the computed field definitions, which Load has
+// validated.
+func (c Config) ComputedFields() []logs.ComputedField {
+	out := make([]logs.ComputedField, 0, len(c.Computed))
+	for _, def := range c.Computed {
+		if f, err := logs.ParseComputedField(def); err == nil {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// SourceOptions converts the configured source entries for the tailer.
+func (c Config) SourceOptions() []logs.SourceOptions {
+	out := make([]logs.SourceOptions, 0, len(c.Sources))
+	for _, s := range c.Sources {
+		opts := logs.SourceOptions{
+			Match:         s.Match,
+			Backpressure:  logs.BackpressurePolicy(s.Backpressure),
+			ReadChunkSize: s.ReadChunkSize,
+			TimeOffset:    s.TimeOffset,
+		}
+		// The patterns were checked by Load.
+		for _, r := range s.Rewrite {
+			opts.Rewrite = append(opts.Rewrite, logs.RewriteRule{Pattern: regexp.MustCompile(r.Pattern), Replace: r.Replace})
+		}
+		if s.Join != "" {
+			opts.Join = regexp.MustCompile(s.Join)
+		}
+		opts.JoinJSON = s.JoinJSON
+		out = append(out, opts)
+	}
+	return out
+}
+
+func validateSources(cfg Config) error {
+	if _, err := logs.ParseBackpressure(cfg.Backpressure); err != nil {
+		return err
+	}
+	if cfg.EntryBuffer < 0 || cfg.ErrorBuffer < 0 || cfg.ReadChunkSize < 0 || cfg.MaxEntrySize < 0 {
+		return fmt.Errorf("entry_buffer, error_buffer, read_chunk_size and max_entry_size must not be negative")
+	}
+	for i, s := range cfg.Sources {
+		if s.Match == "" {
+			return fmt.Errorf("sources[%d]: match pattern is required", i)
+		}
+		if _, err := filepath.Match(s.Match, ""); err != nil {
+			return fmt.Errorf("sources[%d]: bad pattern %q: %w", i, s.Match, err)
+		}
+		if _, err := logs.ParseBackpressure(s.Backpressure); err != nil {
+			return fmt.Errorf("sources[%d]: %w", i, err)
+		}
+		if s.ReadChunkSize < 0 {
+			return fmt.Errorf("sources[%d]: read_chunk_size must not be negative", i)
+		}
+		for j, r := range s.Rewrite {
+			if r.Pattern == "" {
+				return fmt.Errorf("sources[%d].rewrite[%d]: pattern is required", i, j)
+			}
+			if _, err := regexp.Compile(r.Pattern); err != nil {
+				return fmt.Errorf("sources[%d].rewrite[%d]: bad pattern %q: %w", i, j, r.Pattern, err)
+			}
+		}
+		if _, err := regexp.Compile(s.Join); err != nil {
+			return fmt.Errorf("sources[%d]: bad join pattern %q: %w", i, s.Join, err)
+		}
+		if s.Join != "" && s.JoinJSON {
+			return fmt.Errorf("sources[%d]: join and join_json are exclusive", i)
+		}
+	}
+	return nil
+}
+
+func validateSendTargets(cfg Config) error {
+	seen := make(map[string]bool)
+	for i, t := range cfg.SendTargets {
+		if t.Name == "" {
+			return fmt.Errorf("send_targets[%d]: name is required", i)
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("send_targets[%d]: duplicate name %q", i, t.Name)
+		}
+		seen[t.Name] = true
+		u, err := url.Parse(t.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("send_targets[%d]: url must be an http or https URL, got %q", i, t.URL)
+		}
+	}
+	return nil
+}
+
+func validateKeyActions(cfg Config) error {
+	keys := make(map[string]bool)
+	for i, a := range cfg.KeyActions {
+		if a.Key == "" || strings.TrimSpace(a.Command) == "" {
+			return fmt.Errorf("key_actions[%d]: key and command are required", i)
+		}
+		if keys[a.Key] {
+			return fmt.Errorf("key_actions[%d]: key %q is bound twice", i, a.Key)
+		}
+		keys[a.Key] = true
+	}
+	return nil
+}
+
+func validateProcessors(cfg Config) error {
+	names := make(map[string]bool)
+	for i, p := range cfg.Processors {
+		if p.Name == "" || strings.TrimSpace(p.Command) == "" {
+			return fmt.Errorf("processors[%d]: name and command are required", i)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("processors[%d]: duplicate name %q", i, p.Name)
+		}
+		names[p.Name] = true
+		switch p.Kind {
+		case "", "transform", "source":
+		default:
+			return fmt.Errorf("processors[%d]: unknown kind %q (supported: transform, source)", i, p.Kind)
+		}
+	}
+	return nil
+}
+
+func validateAlerts(cfg Config) error {
+	if cfg.NotifyInterval < 0 || cfg.SilenceTimeout < 0 || cfg.AlertCommands < 0 {
+		return fmt.Errorf("notify_interval, silence_timeout and alert_commands must not be negative")
+	}
+	if cfg.SpikeSigma < 0 {
+		return fmt.Errorf("spike_sigma must not be negative")
+	}
+	for i, expr := range cfg.Watches {
+		if _, err := logs.ParseFilter(expr); err != nil {
+			return fmt.Errorf("watches[%d]: %w", i, err)
+		}
+	}
+	for name, expr := range cfg.Filters {
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("filters: bad preset name %q", name)
+		}
+		if _, err := logs.ParseFilter(expr); err != nil {
+			return fmt.Errorf("filters.%s: %w", name, err)
+		}
+	}
+	for i, a := range cfg.Alerts {
+		if strings.TrimSpace(a.Filter) == "" {
+			return fmt.Errorf("alerts[%d]: filter is required", i)
+		}
+		if _, err := logs.ParseFilter(a.Filter); err != nil {
+			return fmt.Errorf("alerts[%d]: %w", i, err)
+		}
+		if a.Threshold < 0 {
+			return fmt.Errorf("alerts[%d]: threshold must not be negative", i)
+		}
+		if a.Threshold > 0 && a.Window <= 0 {
+			return fmt.Errorf("alerts[%d]: threshold needs a positive window", i)
+		}
+	}
+	return nil
+}
+
+func validateFormats(cfg Config) error {
+	if !logs.KnownFormat(cfg.Format) {
+		return fmt.Errorf("unknown format %q (supported: %s)", cfg.Format, strings.Join(logs.FormatNames(), ", "))
+	}
+	if err := validatePattern("pattern", cfg.Pattern); err != nil {
+		return err
+	}
+	if strings.EqualFold(cfg.Format, "regex") && cfg.Pattern == "" {
+		return fmt.Errorf("format regex needs a pattern with named groups, e.g. pattern: '^(?P<time>\\S+) (?P<level>\\w+) (?P<message>.*)$'")
+	}
+	for i, p := range cfg.Parsers {
+		if p.Match == "" {
+			return fmt.Errorf("parsers[%d]: match pattern is required", i)
+		}
+		if _, err := filepath.Match(p.Match, ""); err != nil {
+			return fmt.Errorf("parsers[%d]: bad pattern %q: %w", i, p.Match, err)
+		}
+		if p.Format != "" && !logs.KnownFormat(p.Format) {
+			return fmt.Errorf("parsers[%d]: unknown format %q (supported: %s)", i, p.Format, strings.Join(logs.FormatNames(), ", "))
+		}
+		if err := validatePattern(fmt.Sprintf("parsers[%d].pattern", i), p.Pattern); err != nil {
+			return err
+		}
+		if strings.EqualFold(p.Format, "regex") && p.Pattern == "" && cfg.Pattern == "" {
+			return fmt.Errorf("parsers[%d]: format regex needs a pattern", i)
+		}
+	}
+	return nil
+}
+
+// validatePattern checks a regex format pattern, which must name at least
+// one group to produce fields.
+func validatePattern(key, pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: no named groups, e.g. (?P<level>\\w+)", key)
+}
+
 func setDefaults(v *viper.Viper) {
 	v.SetDefault("tail_lines", 200)
 	v.SetDefault("max_entries", 1000)
-	v.SetDefault("timestamp_field", defaultTimestampField)
-	v.SetDefault("message_field", defaultMessageField)
+	v.SetDefault("per_source_entries", 200)
+	v.SetDefault("spill_max_entries", 1000000)
+	v.SetDefault("refresh_rate", defaultRefreshRate)
 	v.SetDefault("extra_fields", []string{"level"})
+	v.SetDefault("format", logs.DefaultFormat)
+	v.SetDefault("backpressure", string(logs.BackpressureBlock))
+	v.SetDefault("clipboard", "auto")
+	v.SetDefault("copy_format", "raw")
+	v.SetDefault("layout", "vertical")
+	v.SetDefault("split", 0.5)
+	v.SetDefault("docker_root", logs.DockerRoot)
+	v.SetDefault("search_index", true)
+	v.SetDefault("spike_sigma", 4.0)
 }
 
-func addDefaultConfigPaths(v *viper.Viper) {
-	v.AddConfigPath(".")
+var configExtensions = []string{"yaml", "yml", "json", "toml"}
 
+// configLayer is a set of candidate files of which the first existing one is
+// merged into the configuration.
+type configLayer []string
+
+// defaultConfigLayers returns the config layers in merge order: the user
+// config first, then the project-local files from the current directory so
+// that repositories can commit their own field mappings.
+func defaultConfigLayers() []configLayer {
+	var user configLayer
 	if home := homeDir(); home != "" {
-		v.AddConfigPath(filepath.Join(home, ".config", "logsviewer"))
-		v.AddConfigPath(filepath.Join(home, ".logsviewer"))
+		user = append(user, candidateFiles(filepath.Join(home, ".config", "logsviewer"), "logsviewer")...)
+		user = append(user, candidateFiles(filepath.Join(home, ".logsviewer"), "logsviewer")...)
+	}
+	return []configLayer{
+		user,
+		candidateFiles(".", "logsviewer"),
+		candidateFiles(".", ".logsviewer"),
 	}
 }
 
-func readConfig(v *viper.Viper) error {
-	if err := v.ReadInConfig(); err != nil {
-		var configFileNotFound viper.ConfigFileNotFoundError
-		if errors.As(err, &configFileNotFound) {
-			return nil
+func candidateFiles(dir, name string) configLayer {
+	out := make(configLayer, 0, len(configExtensions))
+	for _, ext := range configExtensions {
+		out = append(out, filepath.Join(dir, name+"."+ext))
+	}
+	return out
+}
+
+// mergeConfigLayers merges every layer into v and returns the path of the
+// last file that was read.
+func mergeConfigLayers(v *viper.Viper, layers []configLayer) (string, error) {
+	var last string
+	for _, layer := range layers {
+		path := firstExisting(layer)
+		if path == "" {
+			continue
+		}
+		v.SetConfigFile(path)
+		if err := v.MergeInConfig(); err != nil {
+			return "", fmt.Errorf("read config %s: %w", path, err)
+		}
+		last = path
+	}
+	return last, nil
+}
+
+// SaveFieldMapping stores the timestamp and message field names in the
+// config file at path. When path is empty the user config file is created.
+func SaveFieldMapping(path, timestampField, messageField string) error {
+	return updateConfigFile(path, map[string]any{
+		"timestamp_field": timestampField,
+		"message_field":   messageField,
+	})
+}
+
+func updateConfigFile(path string, values map[string]any) error {
+	if path == "" {
+		home := homeDir()
+		if home == "" {
+			return fmt.Errorf("cannot determine home directory for config file")
 		}
-		return fmt.Errorf("read config: %w", err)
+		path = filepath.Join(home, ".config", "logsviewer", "logsviewer.yaml")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("read config %s: %w", path, err)
+	}
+	for key, val := range values {
+		v.Set(key, val)
+	}
+	if err := v.WriteConfigAs(path); err != nil {
+		return fmt.Errorf("write config %s: %w", path, err)
 	}
 	return nil
 }
 
+func firstExisting(paths []string) string {
+	for _, p := range paths {
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
 func applyOverrides(cfg Config, flags Flags) Config {
 	if len(flags.Files) > 0 {
 		cfg.Files = uniquePaths(flags.Files)
 	}
+	if len(flags.Dirs) > 0 {
+		cfg.Dirs = uniquePaths(flags.Dirs)
+	}
+	if len(flags.Docker) > 0 {
+		cfg.Docker = flags.Docker
+	}
 	if flags.TailLines != nil {
 		cfg.TailLines = *flags.TailLines
 	}
 	if flags.MaxEntries != nil {
 		cfg.MaxEntries = *flags.MaxEntries
 	}
+	if flags.MergeWindow != nil {
+		cfg.MergeWindow = *flags.MergeWindow
+	}
+	if flags.RefreshRate != nil {
+		cfg.RefreshRate = *flags.RefreshRate
+	}
 	if flags.TimestampField != "" {
 		cfg.TimestampField = flags.TimestampField
 	}
@@ -114,15 +778,40 @@ func applyOverrides(cfg Config, flags Flags) Config {
 	if len(flags.ExtraFields) > 0 {
 		cfg.ExtraFields = flags.ExtraFields
 	}
+	if flags.Format != "" {
+		cfg.Format = flags.Format
+	}
+	if flags.Backpressure != "" {
+		cfg.Backpressure = flags.Backpressure
+	}
+	if flags.Spill != nil {
+		cfg.Spill = *flags.Spill
+	}
+	if flags.SpillDir != "" {
+		cfg.SpillDir = flags.SpillDir
+		cfg.Spill = true
+	}
 	return cfg
 }
 
 func ensureDefaults(cfg Config) Config {
+	if cfg.Format == "" {
+		cfg.Format = logs.DefaultFormat
+	}
+	// Fields that are not set explicitly default to those of the format,
+	// e.g. time_local and request for nginx.
+	timestampField, messageField := logs.FormatFields(cfg.Format)
+	if timestampField == "" {
+		timestampField = defaultTimestampField
+	}
+	if messageField == "" {
+		messageField = defaultMessageField
+	}
 	if cfg.TimestampField == "" {
-		cfg.TimestampField = defaultTimestampField
+		cfg.TimestampField = timestampField
 	}
 	if cfg.MessageField == "" {
-		cfg.MessageField = defaultMessageField
+		cfg.MessageField = messageField
 	}
 	if len(cfg.ExtraFields) == 0 {
 		cfg.ExtraFields = []string{"level"}
@@ -130,12 +819,129 @@ func ensureDefaults(cfg Config) Config {
 	if cfg.MaxEntries <= 0 {
 		cfg.MaxEntries = 1000
 	}
+	if cfg.PerSourceEntries <= 0 {
+		cfg.PerSourceEntries = 200
+	}
+	if cfg.SpillMaxEntries < 0 {
+		cfg.SpillMaxEntries = 0
+	}
+	if cfg.MergeWindow < 0 {
+		cfg.MergeWindow = 0
+	}
+	if cfg.RefreshRate < 0 {
+		cfg.RefreshRate = 0
+	}
 	if cfg.TailLines < 0 {
 		cfg.TailLines = 0
 	}
 	return cfg
 }
 
+// expandFileLists replaces "@list.txt" entries with the paths listed in that
+// file and appends the paths from filesFrom. "-" reads the list from stdin.
+func expandFileLists(files []string, filesFrom string) ([]string, error) {
+	var out []string
+	for _, f := range files {
+		if !strings.HasPrefix(f, "@") || len(f) == 1 {
+			out = append(out, f)
+			continue
+		}
+		listed, err := readPathList(f[1:])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, listed...)
+	}
+	if filesFrom != "" {
+		listed, err := readPathList(filesFrom)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, listed...)
+	}
+	return uniquePaths(out), nil
+}
+
+// dirPatterns turns the directories to tail into patterns matching every
+// file in them, which the tailer keeps watching for new files.
+func dirPatterns(dirs []string) ([]string, error) {
+	patterns := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("dirs: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("dirs: %s is not a directory", dir)
+		}
+		patterns = append(patterns, filepath.Join(dir, "*"))
+	}
+	return patterns, nil
+}
+
+// dockerFiles resolves the containers to follow to their json-file logs and
+// puts a parser for the Docker format in front of the configured ones.
+func dockerFiles(cfg *Config) ([]string, error) {
+	paths := make([]string, 0, len(cfg.Docker))
+	parsers := make([]Parser, 0, len(cfg.Docker)+len(cfg.Parsers))
+	for _, container := range cfg.Docker {
+		path, err := logs.DockerLogPath(cfg.DockerRoot, container)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+		parsers = append(parsers, Parser{Match: path, Format: "docker"})
+	}
+	cfg.Parsers = append(parsers, cfg.Parsers...)
+	return paths, nil
+}
+
+// readPathList reads one path per line, skipping blank lines and # comments.
+func readPathList(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("read file list: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read file list %s: %w", path, err)
+	}
+	return paths, nil
+}
+
+// ReadsStdin reports whether the flags consume stdin as a file list.
+func (f Flags) ReadsStdin() bool {
+	if f.FilesFrom == "-" {
+		return true
+	}
+	for _, p := range f.Files {
+		if p == "@-" {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadsStdin reports whether "-" is among the files, so that stdin is read
+// as a log source.
+func (c Config) ReadsStdin() bool {
+	return slices.Contains(c.Files, logs.StdinPath)
+}
+
 func uniquePaths(in []string) []string {
 	seen := make(map[string]struct{})
 	var out []string
@@ -0,0 +1,78 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// ContextAround picks the entries surrounding anchor: from every source, up
+// to n entries up to and including the anchor's time and n entries after
+// it. entries must be in arrival order; so is the result. Entries without
+// a timestamp are placed by arrival order instead.
+func ContextAround(entries []logs.LogEntry, anchor logs.LogEntry, n int) []logs.LogEntry {
+	anchorIndex := -1
+	key := anchor.Key()
+	for i, entry := range entries {
+		if entry.Key() == key {
+			anchorIndex = i
+			break
+		}
+	}
+	before := func(i int) bool {
+		e := entries[i]
+		if !anchor.Timestamp.IsZero() && !e.Timestamp.IsZero() {
+			return !e.Timestamp.After(anchor.Timestamp)
+		}
+		return i <= anchorIndex
+	}
+
+	bySource := make(map[string][]int)
+	for i, entry := range entries {
+		bySource[entry.Path] = append(bySource[entry.Path], i)
+	}
+	keep := make([]bool, len(entries))
+	for _, indexes := range bySource {
+		split := 0
+		for split < len(indexes) && before(indexes[split]) {
+			split++
+		}
+		for _, i := range indexes[max(0, split-n):min(len(indexes), split+n)] {
+			keep[i] = true
+		}
+	}
+
+	var out []logs.LogEntry
+	for i, entry := range entries {
+		if keep[i] {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+// bundle is the file layout written by WriteBundle.
+type bundle struct {
+	Export   Meta     `json:"export"`
+	Selected Record   `json:"selected"`
+	Entries  []Record `json:"entries"`
+}
+
+// WriteBundle writes selected and its context as one JSON document with
+// the sources and time range covered, for sharing an incident moment.
+func WriteBundle(path string, selected logs.LogEntry, context []logs.LogEntry) error {
+	b := bundle{
+		Export:   Describe(context),
+		Selected: NewRecord(selected),
+		Entries:  make([]Record, 0, len(context)),
+	}
+	for _, entry := range context {
+		b.Entries = append(b.Entries, NewRecord(entry))
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
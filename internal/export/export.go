@@ -0,0 +1,324 @@
+// Package export writes log entries to files in formats suited for sharing
+// outside the viewer.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// Format selects how entries are written.
+type Format string
+
+const (
+	// NDJSON writes the original lines, one per line.
+	NDJSON Format = "ndjson"
+	// JSON writes an indented JSON array of the entries.
+	JSON Format = "json"
+	// Text writes the timestamp and message as shown in the list.
+	Text Format = "text"
+	// CSV writes chosen fields as columns, with a header row.
+	CSV Format = "csv"
+	// Markdown writes chosen fields as a table followed by the original
+	// lines in a code fence, for pasting into documents.
+	Markdown Format = "markdown"
+)
+
+// Options controls how entries are written.
+type Options struct {
+	Format Format
+	// Meta, when set, describes the entries ahead of them: as a leading
+	// {"export": meta} line for NDJSON, as an object holding both for JSON,
+	// as "#" comment lines for text and as a list for Markdown. CSV ignores
+	// it.
+	Meta *Meta
+	// Columns are the fields written by CSV and Markdown. The pseudo fields
+	// @timestamp, @level, @message and @file refer to the parsed timestamp,
+	// the severity, the message and the source path. Defaults to
+	// DefaultColumns.
+	Columns []string
+}
+
+// DefaultColumns are the table columns used when none are chosen.
+var DefaultColumns = []string{"@timestamp", "@level", "@file", "@message"}
+
+// Tabular reports whether the format writes Columns.
+func (f Format) Tabular() bool {
+	return f == CSV || f == Markdown
+}
+
+// ParseFormat validates a format name.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(name))); f {
+	case NDJSON, JSON, Text, CSV, Markdown:
+		return f, nil
+	case "md":
+		return Markdown, nil
+	case "jsonl":
+		return NDJSON, nil
+	case "txt":
+		return Text, nil
+	}
+	return "", fmt.Errorf("unknown export format %q (want ndjson, json, text, csv or markdown)", name)
+}
+
+// FormatFor guesses the format from the extension of path, defaulting to
+// NDJSON.
+func FormatFor(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return JSON
+	case ".txt", ".log", ".text":
+		return Text
+	case ".csv":
+		return CSV
+	case ".md", ".markdown":
+		return Markdown
+	}
+	return NDJSON
+}
+
+// WriteFile writes entries to path, in the order given.
+func WriteFile(path string, entries []logs.LogEntry, opts Options) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(file, entries, opts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Write writes entries to w, in the order given.
+func Write(w io.Writer, entries []logs.LogEntry, opts Options) error {
+	bw := bufio.NewWriter(w)
+	var err error
+	switch opts.Format {
+	case JSON:
+		err = writeJSON(bw, entries, opts.Meta)
+	case Text:
+		err = writeText(bw, entries, opts.Meta)
+	case CSV:
+		err = writeCSV(bw, entries, opts.Columns)
+	case Markdown:
+		err = writeMarkdown(bw, entries, opts.Columns, opts.Meta)
+	default:
+		err = writeNDJSON(bw, entries, opts.Meta)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func writeNDJSON(w *bufio.Writer, entries []logs.LogEntry, meta *Meta) error {
+	if meta != nil {
+		data, err := json.Marshal(map[string]*Meta{"export": meta})
+		if err != nil {
+			return err
+		}
+		w.Write(data)
+		w.WriteByte('\n')
+	}
+	for _, entry := range entries {
+		w.WriteString(fullLine(entry))
+		w.WriteByte('\n')
+	}
+	return nil
+}
+
+func writeJSON(w *bufio.Writer, entries []logs.LogEntry, meta *Meta) error {
+	items := make([]any, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, jsonValue(entry))
+	}
+	var doc any = items
+	if meta != nil {
+		doc = map[string]any{"export": meta, "entries": items}
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	w.Write(data)
+	w.WriteByte('\n')
+	return nil
+}
+
+func writeText(w *bufio.Writer, entries []logs.LogEntry, meta *Meta) error {
+	if meta != nil {
+		fmt.Fprintf(w, "# sources: %s\n", strings.Join(meta.Sources, ", "))
+		if !meta.From.IsZero() {
+			fmt.Fprintf(w, "# range: %s .. %s\n", meta.From.Format(time.RFC3339Nano), meta.To.Format(time.RFC3339Nano))
+		}
+		fmt.Fprintf(w, "# entries: %d\n\n", meta.Count)
+	}
+	for _, entry := range entries {
+		message := entry.Message
+		if message == "" {
+			message = fullLine(entry)
+		}
+		if ts := entry.DisplayTimestamp(); ts != "" {
+			fmt.Fprintf(w, "%s  %s\n", ts, message)
+		} else {
+			fmt.Fprintf(w, "%s\n", message)
+		}
+	}
+	return nil
+}
+
+func writeCSV(w io.Writer, entries []logs.LogEntry, columns []string) error {
+	if len(columns) == 0 {
+		columns = DefaultColumns
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	row := make([]string, len(columns))
+	for _, entry := range entries {
+		for i, name := range columns {
+			row[i] = columnValue(entry, name)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeMarkdown(w *bufio.Writer, entries []logs.LogEntry, columns []string, meta *Meta) error {
+	if len(columns) == 0 {
+		columns = DefaultColumns
+	}
+	if meta != nil {
+		fmt.Fprintf(w, "- Sources: %s\n", strings.Join(meta.Sources, ", "))
+		if !meta.From.IsZero() {
+			fmt.Fprintf(w, "- Time range: %s – %s\n", meta.From.Format(time.RFC3339Nano), meta.To.Format(time.RFC3339Nano))
+		}
+		fmt.Fprintf(w, "- Entries: %d\n\n", meta.Count)
+	}
+
+	header := make([]string, len(columns))
+	for i, name := range columns {
+		header[i] = strings.TrimPrefix(name, "@")
+	}
+	fmt.Fprintf(w, "| %s |\n|%s\n", strings.Join(header, " | "), strings.Repeat("---|", len(columns)))
+	row := make([]string, len(columns))
+	for _, entry := range entries {
+		for i, name := range columns {
+			row[i] = markdownCell(columnValue(entry, name))
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | "))
+	}
+
+	// Fences must be longer than any backtick run in the content.
+	fence := "```"
+	for _, entry := range entries {
+		for strings.Contains(fullLine(entry), fence) {
+			fence += "`"
+		}
+	}
+	fmt.Fprintf(w, "\n%sjson\n", fence)
+	for _, entry := range entries {
+		w.WriteString(fullLine(entry))
+		w.WriteByte('\n')
+	}
+	fmt.Fprintf(w, "%s\n", fence)
+	return nil
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\r", "")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// columnValue returns the value of a CSV column for entry. Nested values
+// are written as JSON.
+func columnValue(entry logs.LogEntry, name string) string {
+	switch name {
+	case "@timestamp":
+		if !entry.Timestamp.IsZero() {
+			return entry.Timestamp.Format(time.RFC3339Nano)
+		}
+		return entry.TimestampText
+	case "@level":
+		return entry.Level()
+	case "@message":
+		return entry.Message
+	case "@file":
+		return entry.Path
+	}
+	if v, ok := entry.Field(name); ok {
+		return logs.FormatValue(v)
+	}
+	// Truncated entries keep only their extra fields.
+	return entry.ExtraValue(name)
+}
+
+// Meta describes an exported slice of entries.
+type Meta struct {
+	Sources []string  `json:"sources"`
+	From    time.Time `json:"from,omitzero"`
+	To      time.Time `json:"to,omitzero"`
+	Count   int       `json:"count"`
+}
+
+// Describe collects the sources and the time range of entries.
+func Describe(entries []logs.LogEntry) Meta {
+	meta := Meta{Count: len(entries)}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if !seen[entry.Path] {
+			seen[entry.Path] = true
+			meta.Sources = append(meta.Sources, entry.Path)
+		}
+		if entry.Timestamp.IsZero() {
+			continue
+		}
+		if meta.From.IsZero() || entry.Timestamp.Before(meta.From) {
+			meta.From = entry.Timestamp
+		}
+		if entry.Timestamp.After(meta.To) {
+			meta.To = entry.Timestamp
+		}
+	}
+	sort.Strings(meta.Sources)
+	return meta
+}
+
+// fullLine returns the complete original line, falling back to what is
+// held in memory when the file can no longer be read.
+func fullLine(entry logs.LogEntry) string {
+	if raw, err := entry.FullRaw(); err == nil {
+		return raw
+	}
+	return entry.RawLine()
+}
+
+// jsonValue returns entry as a JSON value: the original object for JSON
+// lines, the parsed fields for other formats, or the bare line.
+func jsonValue(entry logs.LogEntry) any {
+	line := fullLine(entry)
+	if json.Valid([]byte(line)) {
+		return json.RawMessage(line)
+	}
+	if fields := entry.FieldMap(); len(fields) > 0 {
+		return fields
+	}
+	return line
+}
@@ -0,0 +1,138 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// Record is the normalized JSON form of an entry. It has the same shape
+// whatever the format of the source line.
+type Record struct {
+	Path          string            `json:"path"`
+	Timestamp     time.Time         `json:"timestamp,omitzero"`
+	TimestampText string            `json:"timestamp_text,omitempty"`
+	Message       string            `json:"message"`
+	Extras        map[string]string `json:"extras,omitempty"`
+	Fields        map[string]any    `json:"fields,omitempty"`
+}
+
+// NewRecord normalizes entry.
+func NewRecord(entry logs.LogEntry) Record {
+	return Record{
+		Path:          entry.Path,
+		Timestamp:     entry.Timestamp,
+		TimestampText: entry.TimestampText,
+		Message:       entry.Message,
+		Extras:        entry.Extras,
+		Fields:        entry.FieldMap(),
+	}
+}
+
+// recordKeys are the keys a Record line may have.
+var recordKeys = map[string]bool{
+	"path": true, "timestamp": true, "timestamp_text": true,
+	"message": true, "extras": true, "fields": true,
+}
+
+// ParseRecord decodes a line written as a Record back into an entry. ok is
+// false for lines of any other shape, such as original log lines. The raw
+// line of the entry is its fields as JSON, or the message if there are
+// none.
+func ParseRecord(line string) (entry logs.LogEntry, ok bool) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &keys); err != nil {
+		return logs.LogEntry{}, false
+	}
+	if _, ok := keys["path"]; !ok {
+		return logs.LogEntry{}, false
+	}
+	for k := range keys {
+		if !recordKeys[k] {
+			return logs.LogEntry{}, false
+		}
+	}
+	var rec Record
+	if err := json.Unmarshal([]byte(line), &rec); err != nil {
+		return logs.LogEntry{}, false
+	}
+	entry = logs.LogEntry{
+		Path:          rec.Path,
+		Timestamp:     rec.Timestamp,
+		TimestampText: rec.TimestampText,
+		Message:       rec.Message,
+		Extras:        rec.Extras,
+		Fields:        rec.Fields,
+		Raw:           rec.Message,
+	}
+	if len(rec.Fields) > 0 {
+		if data, err := json.Marshal(rec.Fields); err == nil {
+			entry.Raw = string(data)
+		}
+	}
+	return entry, true
+}
+
+// Apply replaces the timestamp, message, extras and fields of entry with
+// those of r, as transforms answering with a record do. The source path,
+// raw line and offset stay those of the entry so that it can still be
+// opened and re-read. extraFields the record leaves as they were are
+// refreshed from its fields.
+func (r Record) Apply(entry logs.LogEntry, extraFields []string) logs.LogEntry {
+	old := entry.Extras
+	entry.Timestamp = r.Timestamp
+	entry.TimestampText = r.TimestampText
+	entry.Message = r.Message
+	entry.Extras = r.Extras
+	entry.Fields = r.Fields
+	for _, name := range extraFields {
+		if name == "@file" || entry.Extras[name] != old[name] {
+			continue
+		}
+		if entry.Extras == nil {
+			entry.Extras = make(map[string]string)
+		}
+		entry.Extras[name] = entry.FieldString(name)
+	}
+	return entry
+}
+
+// Tee appends entries to a file as normalized NDJSON records while they
+// are ingested. It is not safe for concurrent use.
+type Tee struct {
+	file *os.File
+	w    *bufio.Writer
+	enc  *json.Encoder
+}
+
+// OpenTee opens path for appending.
+func OpenTee(path string) (*Tee, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(file)
+	return &Tee{file: file, w: w, enc: json.NewEncoder(w)}, nil
+}
+
+// Write buffers the record of entry.
+func (t *Tee) Write(entry logs.LogEntry) error {
+	return t.enc.Encode(NewRecord(entry))
+}
+
+// Flush writes buffered records to the file.
+func (t *Tee) Flush() error {
+	return t.w.Flush()
+}
+
+// Close flushes and closes the file.
+func (t *Tee) Close() error {
+	err := t.w.Flush()
+	if cerr := t.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
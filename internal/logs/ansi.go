@@ -0,0 +1,82 @@
+package logs
+
+import "strings"
+
+// StripANSI removes terminal escape sequences, such as the colors of
+// plaintext loggers, from s.
+func StripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); {
+		if n := ANSISequenceLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// ANSISequenceLen returns the length of the terminal escape sequence at the
+// start of s, or 0 if there is none: CSI (ESC [ ... final byte), OSC and
+// the other string sequences (ESC ] ... BEL or ESC \), and short ESC
+// sequences such as ESC ( B. An unterminated CSI or string sequence
+// extends to the end of s.
+func ANSISequenceLen(s string) int {
+	if len(s) < 2 || s[0] != 0x1b {
+		return 0
+	}
+	switch s[1] {
+	case '[':
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+			if s[i] < 0x20 || s[i] > 0x7e {
+				// Malformed; drop what was read of it.
+				return i
+			}
+		}
+		return len(s)
+	case ']', 'P', 'X', '^', '_':
+		for i := 2; i < len(s); i++ {
+			if s[i] == 0x07 {
+				return i + 1
+			}
+			if s[i] == 0x1b && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	}
+	i := 1
+	for i < len(s) && s[i] >= 0x20 && s[i] <= 0x2f {
+		i++
+	}
+	if i < len(s) && s[i] >= 0x30 && s[i] <= 0x7e {
+		return i + 1
+	}
+	return 0
+}
+
+// stripANSIValues removes escape sequences from the strings of a decoded
+// line, which JSON carries escaped as \u001b.
+func stripANSIValues(v any) any {
+	switch v := v.(type) {
+	case string:
+		return StripANSI(v)
+	case map[string]any:
+		for k, x := range v {
+			v[k] = stripANSIValues(x)
+		}
+	case []any:
+		for i, x := range v {
+			v[i] = stripANSIValues(x)
+		}
+	}
+	return v
+}
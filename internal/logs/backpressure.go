@@ -0,0 +1,175 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// BackpressurePolicy decides what happens to a new entry when the entries
+// channel is full.
+type BackpressurePolicy string
+
+const (
+	// BackpressureBlock waits until the consumer catches up.
+	BackpressureBlock BackpressurePolicy = "block"
+	// BackpressureDropOldest discards the oldest queued entry.
+	BackpressureDropOldest BackpressurePolicy = "drop-oldest"
+	// BackpressureDropNewest discards the entry being delivered.
+	BackpressureDropNewest BackpressurePolicy = "drop-newest"
+)
+
+// ParseBackpressure validates a policy name. An empty name means block.
+func ParseBackpressure(name string) (BackpressurePolicy, error) {
+	switch p := BackpressurePolicy(name); p {
+	case "":
+		return BackpressureBlock, nil
+	case BackpressureBlock, BackpressureDropOldest, BackpressureDropNewest:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown backpressure policy %q (supported: block, drop-oldest, drop-newest)", name)
+}
+
+// SourceOptions holds per-source settings for files whose path matches Match.
+type SourceOptions struct {
+	Match        string
+	Backpressure BackpressurePolicy
+	// ReadChunkSize is the read buffer size used for the file.
+	ReadChunkSize int
+	// Rewrite rules apply to every raw line before it is parsed, after
+	// continuation lines matching Join are appended to the line before.
+	Rewrite []RewriteRule
+	Join    *regexp.Regexp
+	// JoinJSON treats every line that does not start a JSON object as a
+	// continuation, instead of Join.
+	JoinJSON bool
+	// TimeOffset is added to the timestamps of the file's entries, to
+	// correct the clock of the machine that wrote it.
+	TimeOffset time.Duration
+}
+
+// resolveSource returns the settings for path: the first matching entry of
+// sources applied on top of base.
+func resolveSource(base SourceOptions, sources []SourceOptions, path string) SourceOptions {
+	out := base
+	for _, s := range sources {
+		if !matchPath(s.Match, path) {
+			continue
+		}
+		if s.Backpressure != "" {
+			out.Backpressure = s.Backpressure
+		}
+		if s.ReadChunkSize > 0 {
+			out.ReadChunkSize = s.ReadChunkSize
+		}
+		out.Rewrite, out.Join, out.JoinJSON = s.Rewrite, s.Join, s.JoinJSON
+		out.TimeOffset = s.TimeOffset
+		break
+	}
+	if out.Backpressure == "" {
+		out.Backpressure = BackpressureBlock
+	}
+	out.ReadChunkSize = positiveOr(out.ReadChunkSize, defaultReadChunkSize)
+	return out
+}
+
+// SourceStats is a snapshot of the counters of one tailed file.
+type SourceStats struct {
+	Path      string `json:"path"`
+	Delivered int64  `json:"delivered"`
+	Dropped   int64  `json:"dropped"`
+	// ParseErrors counts lines that could not be parsed.
+	ParseErrors int64 `json:"parse_errors"`
+	// Offset is the byte position read up to in the current file.
+	Offset int64 `json:"offset"`
+}
+
+type sourceCounters struct {
+	delivered   atomic.Int64
+	dropped     atomic.Int64
+	parseErrors atomic.Int64
+	offset      atomic.Int64
+}
+
+func (t *Tailer) counters(path string) *sourceCounters {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.stats[path]
+	if !ok {
+		c = &sourceCounters{}
+		t.stats[path] = c
+	}
+	return c
+}
+
+// Stats returns per-file delivery counters and read positions sorted by
+// path.
+func (t *Tailer) Stats() []SourceStats {
+	t.mu.Lock()
+	out := make([]SourceStats, 0, len(t.stats))
+	for path, c := range t.stats {
+		out = append(out, SourceStats{
+			Path:        path,
+			Delivered:   c.delivered.Load(),
+			Dropped:     c.dropped.Load(),
+			ParseErrors: c.parseErrors.Load(),
+			Offset:      c.offset.Load(),
+		})
+	}
+	t.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// sink delivers the entries of one source according to its policy.
+type sink struct {
+	ch       chan LogEntry
+	policy   BackpressurePolicy
+	counters *sourceCounters
+}
+
+// send delivers entry and reports false once ctx is canceled.
+func (s *sink) send(ctx context.Context, entry LogEntry) bool {
+	switch s.policy {
+	case BackpressureDropNewest:
+		select {
+		case <-ctx.Done():
+			return false
+		case s.ch <- entry:
+			s.counters.delivered.Add(1)
+		default:
+			s.counters.dropped.Add(1)
+		}
+		return true
+	case BackpressureDropOldest:
+		for {
+			select {
+			case <-ctx.Done():
+				return false
+			case s.ch <- entry:
+				s.counters.delivered.Add(1)
+				return true
+			default:
+			}
+			// Make room by discarding the oldest queued entry. It may
+			// belong to another source; it is still counted here since
+			// this source's policy caused the drop.
+			select {
+			case <-s.ch:
+				s.counters.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case <-ctx.Done():
+			return false
+		case s.ch <- entry:
+			s.counters.delivered.Add(1)
+			return true
+		}
+	}
+}
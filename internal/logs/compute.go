@@ -0,0 +1,256 @@
+package logs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ComputedField is a field derived from others by an expression:
+//
+//	latency_s = duration_ms / 1000
+//	endpoint = method + " " + path
+//
+// Operands are field names, as in filters, numbers and double-quoted
+// strings; operators are + - * / and parentheses. + adds numbers and
+// joins anything else as text. A computed field is left unset for an entry
+// when a field it uses is missing or arithmetic is applied to text.
+type ComputedField struct {
+	Name string
+	expr computeExpr
+}
+
+// ParseComputedField parses a "name = expression" definition.
+func ParseComputedField(def string) (ComputedField, error) {
+	name, expr, ok := strings.Cut(def, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return ComputedField{}, fmt.Errorf("computed field %q: want name = expression", def)
+	}
+	p := &computeParser{src: expr}
+	e, err := p.parseSum()
+	if err == nil {
+		if p.skipSpace(); p.pos < len(p.src) {
+			err = fmt.Errorf("unexpected %q", p.src[p.pos:])
+		}
+	}
+	if err != nil {
+		return ComputedField{}, fmt.Errorf("computed field %s: %w", name, err)
+	}
+	return ComputedField{Name: name, expr: e}, nil
+}
+
+// ComputeTransform returns a transform setting the computed fields as
+// extras, in order, so that a field may use the ones before it.
+func ComputeTransform(fields []ComputedField) TransformFunc {
+	if len(fields) == 0 {
+		return nil
+	}
+	return func(entry LogEntry) (LogEntry, bool) {
+		for _, f := range fields {
+			v, ok := f.expr.eval(entry)
+			if !ok {
+				continue
+			}
+			if entry.Extras == nil {
+				entry.Extras = make(map[string]string)
+			}
+			entry.Extras[f.Name] = v.String()
+		}
+		return entry, true
+	}
+}
+
+// Value evaluates the field for entry. ok is false where the field is
+// left unset.
+func (f ComputedField) Value(entry LogEntry) (string, bool) {
+	v, ok := f.expr.eval(entry)
+	return v.String(), ok
+}
+
+type computeValue struct {
+	text string
+	num  float64
+	// isNum is set for numbers and for text that parses as one.
+	isNum bool
+}
+
+func textValue(s string) computeValue {
+	n, err := strconv.ParseFloat(s, 64)
+	return computeValue{text: s, num: n, isNum: err == nil}
+}
+
+func numValue(n float64) computeValue {
+	return computeValue{text: strconv.FormatFloat(n, 'f', -1, 64), num: n, isNum: true}
+}
+
+func (v computeValue) String() string {
+	return v.text
+}
+
+type computeExpr interface {
+	eval(LogEntry) (computeValue, bool)
+}
+
+type computeField string
+
+func (f computeField) eval(entry LogEntry) (computeValue, bool) {
+	s, ok := filterField(entry, string(f))
+	return textValue(s), ok
+}
+
+type computeLiteral computeValue
+
+func (l computeLiteral) eval(LogEntry) (computeValue, bool) {
+	return computeValue(l), true
+}
+
+type computeBinary struct {
+	op          byte
+	left, right computeExpr
+}
+
+func (b computeBinary) eval(entry LogEntry) (computeValue, bool) {
+	x, ok := b.left.eval(entry)
+	if !ok {
+		return computeValue{}, false
+	}
+	y, ok := b.right.eval(entry)
+	if !ok {
+		return computeValue{}, false
+	}
+	if b.op == '+' && !(x.isNum && y.isNum) {
+		return computeValue{text: x.text + y.text}, true
+	}
+	if !x.isNum || !y.isNum {
+		return computeValue{}, false
+	}
+	switch b.op {
+	case '+':
+		return numValue(x.num + y.num), true
+	case '-':
+		return numValue(x.num - y.num), true
+	case '*':
+		return numValue(x.num * y.num), true
+	default:
+		if y.num == 0 {
+			return computeValue{}, false
+		}
+		return numValue(x.num / y.num), true
+	}
+}
+
+type computeParser struct {
+	src string
+	pos int
+}
+
+func (p *computeParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// peek skips spaces and returns the next byte without consuming it, or 0
+// at the end.
+func (p *computeParser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *computeParser) parseSum() (computeExpr, error) {
+	left, err := p.parseProduct()
+	for err == nil {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			break
+		}
+		p.pos++
+		var right computeExpr
+		if right, err = p.parseProduct(); err == nil {
+			left = computeBinary{op: op, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *computeParser) parseProduct() (computeExpr, error) {
+	left, err := p.parseOperand()
+	for err == nil {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			break
+		}
+		p.pos++
+		var right computeExpr
+		if right, err = p.parseOperand(); err == nil {
+			left = computeBinary{op: op, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *computeParser) parseOperand() (computeExpr, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		e, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return e, nil
+	case c == '-':
+		p.pos++
+		e, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return computeBinary{op: '-', left: computeLiteral(numValue(0)), right: e}, nil
+	case c == '"':
+		end := p.pos + 1
+		for end < len(p.src) && p.src[end] != '"' {
+			if p.src[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.src) {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		s, err := strconv.Unquote(p.src[p.pos : end+1])
+		if err != nil {
+			return nil, err
+		}
+		p.pos = end + 1
+		return computeLiteral(computeValue{text: s}), nil
+	}
+	start := p.pos
+	for p.pos < len(p.src) && isComputeNameByte(p.src[p.pos]) {
+		p.pos++
+	}
+	word := p.src[start:p.pos]
+	if word == "" {
+		return nil, fmt.Errorf("unexpected %q", p.src[start:])
+	}
+	if n, err := strconv.ParseFloat(word, 64); err == nil {
+		return computeLiteral(numValue(n)), nil
+	}
+	return computeField(word), nil
+}
+
+// isComputeNameByte reports whether c may be part of a field name or
+// number. Bytes of non-ASCII characters are taken as letters.
+func isComputeNameByte(c byte) bool {
+	return c == '_' || c == '.' || c == '@' || c >= 0x80 ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
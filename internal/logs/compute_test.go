@@ -0,0 +1,98 @@
+package logs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseComputedFieldErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		def  string
+		want string
+	}{
+		{"no equals sign", "latency", "want name = expression"},
+		{"no name", "= duration_ms / 1000", "want name = expression"},
+		{"name with a space", "latency s = duration_ms", "want name = expression"},
+		{"empty expression", "latency =", "unexpected end of expression"},
+		{"dangling operator", "latency = duration_ms /", "unexpected end of expression"},
+		{"missing parenthesis", "latency = (duration_ms + 1", "missing )"},
+		{"unterminated string", `endpoint = method + " `, "unterminated string"},
+		{"two operands", "endpoint = method path", `unexpected "path"`},
+		{"unknown operator", "ratio = a % b", `unexpected "% b"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseComputedField(tt.def)
+			if err == nil {
+				t.Fatalf("ParseComputedField(%q) succeeded, want an error", tt.def)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseComputedField(%q) error = %q, want it to contain %q", tt.def, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputedFieldValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		def    string
+		fields map[string]any
+		want   string
+		ok     bool
+	}{
+		{"division", "latency_s = duration_ms / 1000", map[string]any{"duration_ms": 1500.0}, "1.5", true},
+		{"precedence", "x = a + b * 2", map[string]any{"a": 1.0, "b": 3.0}, "7", true},
+		{"parentheses", "x = (a + b) * 2", map[string]any{"a": 1.0, "b": 3.0}, "8", true},
+		{"left to right", "x = a - b - 1", map[string]any{"a": 10.0, "b": 3.0}, "6", true},
+		{"unary minus", "x = -a * 2", map[string]any{"a": 4.0}, "-8", true},
+		{"numbers in text fields", "x = a + b", map[string]any{"a": "2", "b": "3"}, "5", true},
+		{"plus joins text", `endpoint = method + " " + path`, map[string]any{"method": "GET", "path": "/api"}, "GET /api", true},
+		{"plus joins text and numbers", `x = "status " + status`, map[string]any{"status": 500.0}, "status 500", true},
+		{"escaped quote", `x = "say \"hi\""`, nil, `say "hi"`, true},
+		{"dotted name", "x = req.size * 2", map[string]any{"req.size": 21.0}, "42", true},
+		{"missing field", "latency_s = duration_ms / 1000", map[string]any{}, "", false},
+		{"arithmetic on text", "x = a * 2", map[string]any{"a": "slow"}, "", false},
+		{"division by zero", "x = a / b", map[string]any{"a": 1.0, "b": 0.0}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseComputedField(tt.def)
+			if err != nil {
+				t.Fatalf("ParseComputedField(%q): %v", tt.def, err)
+			}
+			got, ok := f.Value(LogEntry{Fields: tt.fields})
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Errorf("Value = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestComputeTransform(t *testing.T) {
+	var fields []ComputedField
+	for _, def := range []string{
+		"latency_s = duration_ms / 1000",
+		`label = path + " " + latency_s`,
+		"missing = user + 1",
+	} {
+		f, err := ParseComputedField(def)
+		if err != nil {
+			t.Fatalf("ParseComputedField(%q): %v", def, err)
+		}
+		fields = append(fields, f)
+	}
+	if ComputeTransform(nil) != nil {
+		t.Error("ComputeTransform(nil) is not nil")
+	}
+	entry, keep := ComputeTransform(fields)(LogEntry{Fields: map[string]any{"duration_ms": 250.0, "path": "/api"}})
+	if !keep {
+		t.Fatal("entry dropped")
+	}
+	want := map[string]string{"latency_s": "0.25", "label": "/api 0.25"}
+	if !reflect.DeepEqual(entry.Extras, want) {
+		t.Errorf("extras = %v, want %v", entry.Extras, want)
+	}
+}
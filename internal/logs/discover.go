@@ -0,0 +1,159 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// patternRescanInterval is how often glob patterns are re-evaluated in case
+// a filesystem event was missed.
+const patternRescanInterval = 2 * time.Second
+
+// SourceEventKind classifies a SourceEvent.
+type SourceEventKind int
+
+const (
+	// SourceAdded means a file started being tailed at runtime.
+	SourceAdded SourceEventKind = iota
+	// SourceRemoved means a file matched by a pattern was deleted and is
+	// no longer tailed.
+	SourceRemoved
+)
+
+// SourceEvent describes a change in the set of tailed files.
+type SourceEvent struct {
+	Kind SourceEventKind
+	Path string
+}
+
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// sidecarSuffixes end the names of files logsviewer writes next to log
+// files, such as saved bookmarks, which patterns must not pick up.
+var sidecarSuffixes = []string{".marks.json", ".marks.json.tmp"}
+
+// isSidecar reports whether path is a file logsviewer wrote itself.
+func isSidecar(path string) bool {
+	for _, suffix := range sidecarSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchPattern tails every file matching pattern and keeps watching the
+// pattern's directory so that files created later are picked up as well.
+// A file that has been gone for longer than rotationGrace, so it was not
+// just rotated, stops being tailed.
+func (t *Tailer) watchPattern(ctx context.Context, pattern string, entries chan LogEntry, errs chan<- error) {
+	pattern = filepath.Clean(pattern)
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		t.sourceFailed(errs, fmt.Errorf("bad pattern %s: %w", pattern, err))
+		return
+	}
+
+	// gone holds when each file started from the pattern was last seen
+	// missing, or the zero time while it exists.
+	gone := make(map[string]time.Time)
+	start := func(path string, tailLines int) bool {
+		if !t.startFile(ctx, path, tailLines, entries, errs) {
+			return false
+		}
+		gone[filepath.Clean(path)] = time.Time{}
+		return true
+	}
+	prune := func() {
+		for path, since := range gone {
+			if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+				gone[path] = time.Time{}
+				continue
+			}
+			if since.IsZero() {
+				gone[path] = time.Now()
+				continue
+			}
+			if time.Since(since) >= rotationGrace {
+				t.stopFile(path)
+				delete(gone, path)
+				t.announce(SourceEvent{Kind: SourceRemoved, Path: path})
+			}
+		}
+	}
+
+	scan := func(tailLines int, announce bool) {
+		matches, _ := filepath.Glob(pattern)
+		for _, path := range matches {
+			if !isRegularFile(path) || isSidecar(path) {
+				continue
+			}
+			// A compressed file appearing later is a rotated one whose
+			// lines were already read from the live file.
+			if announce && isCompressed(path) {
+				continue
+			}
+			if start(path, tailLines) && announce {
+				t.announce(SourceEvent{Kind: SourceAdded, Path: path})
+			}
+		}
+	}
+	scan(t.tailLines, false)
+	if t.once {
+		return
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		var events <-chan fsnotify.Event
+		if dir := filepath.Dir(pattern); !isGlobPattern(dir) {
+			var unsubscribe func()
+			events, unsubscribe = t.watchDir(dir, errs)
+			defer unsubscribe()
+		}
+
+		ticker := time.NewTicker(patternRescanInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				prune()
+				scan(0, true)
+			case event := <-events:
+				if event.Op&fsnotify.Create == 0 {
+					continue
+				}
+				if matched, _ := filepath.Match(pattern, event.Name); matched && isRegularFile(event.Name) && !isSidecar(event.Name) && !isCompressed(event.Name) {
+					if start(event.Name, 0) {
+						t.announce(SourceEvent{Kind: SourceAdded, Path: event.Name})
+					}
+				}
+			}
+		}
+	}()
+}
+
+// announce publishes ev without blocking the tailer if nobody listens.
+func (t *Tailer) announce(ev SourceEvent) {
+	select {
+	case t.events <- ev:
+	default:
+	}
+}
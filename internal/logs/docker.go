@@ -0,0 +1,94 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DockerRoot is where the Docker daemon keeps container state, including
+// the files of the json-file log driver.
+const DockerRoot = "/var/lib/docker/containers"
+
+// DockerStreamField holds the stream, stdout or stderr, a line of a
+// container was written to.
+const DockerStreamField = "stream"
+
+// DockerLogPath returns the json-file log of a container under root,
+// given its name or a prefix of its ID.
+func DockerLogPath(root, container string) (string, error) {
+	name := strings.TrimPrefix(container, "/")
+	if name == "" {
+		return "", fmt.Errorf("docker: empty container name")
+	}
+	dirs, err := os.ReadDir(root)
+	if err != nil {
+		return "", fmt.Errorf("docker: %w", err)
+	}
+	var found []string
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		id := dir.Name()
+		if id == name || dockerContainerName(filepath.Join(root, id)) == name {
+			found = []string{id}
+			break
+		}
+		if strings.HasPrefix(id, name) {
+			found = append(found, id)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("docker: no container %q in %s", container, root)
+	case 1:
+		return filepath.Join(root, found[0], found[0]+"-json.log"), nil
+	}
+	return "", fmt.Errorf("docker: %q matches %d containers", container, len(found))
+}
+
+// dockerContainerName reads the name of the container kept in dir, or ""
+// if it cannot.
+func dockerContainerName(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "config.v2.json"))
+	if err != nil {
+		return ""
+	}
+	var state struct {
+		Name string `json:"Name"`
+	}
+	if json.Unmarshal(data, &state) != nil {
+		return ""
+	}
+	return strings.TrimPrefix(state.Name, "/")
+}
+
+// decodeDocker parses a line of the json-file log driver and then the line
+// the container wrote, as JSON. A line that is not JSON becomes the
+// message, and the time Docker received a line is its timestamp unless
+// the line has one.
+func decodeDocker(line string, cfg ParserConfig) (map[string]any, error) {
+	var envelope struct {
+		Log    string `json:"log"`
+		Stream string `json:"stream"`
+		Time   string `json:"time"`
+	}
+	if err := json.Unmarshal([]byte(line), &envelope); err != nil {
+		return nil, fmt.Errorf("docker: %w", err)
+	}
+	inner := strings.TrimRight(envelope.Log, "\r\n")
+	fields, err := decodeJSON(inner, cfg)
+	if err != nil {
+		fields = map[string]any{cfg.MessageField: inner}
+	}
+	if _, ok := lookupField(fields, cfg.TimestampField); !ok && envelope.Time != "" {
+		fields[cfg.TimestampField] = envelope.Time
+	}
+	if _, ok := fields[DockerStreamField]; !ok && envelope.Stream != "" {
+		fields[DockerStreamField] = envelope.Stream
+	}
+	return fields, nil
+}
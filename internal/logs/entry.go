@@ -1,10 +1,17 @@
 package logs
 
 import (
+	"bytes"
+	"compress/flate"
 	"encoding/json"
 	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"regexp"
 	"strconv"
 	"time"
+	"unicode/utf8"
 )
 
 // LogEntry represents a decoded JSON log line coming from a watched file.
@@ -16,28 +23,244 @@ type LogEntry struct {
 	Extras        map[string]string
 	Fields        map[string]any
 	Raw           string
+	// LevelName is the canonical level the level map gave the entry's raw
+	// level, if any; Level returns it instead.
+	LevelName string
+
+	// Offset is the position of the line in its file.
+	Offset int64
+	// Size is the length of the line in its file when Raw was truncated,
+	// and zero otherwise. Checksum is the CRC-32 of those bytes, with
+	// CRLF line breaks read as LF, to tell whether the file changed.
+	Size     int
+	Checksum uint32
+
+	// packed holds the deflated raw line and fields of entries compacted
+	// with Pack; Raw and Fields are empty in that case. preview keeps the
+	// start of the line and sum its checksum, so that lists, search and
+	// Key do not need to decompress.
+	packed  []byte
+	preview string
+	sum     uint32
+}
+
+const (
+	// packMinSize is the shortest raw line worth compressing.
+	packMinSize = 128
+	// packPreviewSize is how much of a packed line stays uncompressed.
+	packPreviewSize = 96
+)
+
+// Pack returns a copy of the entry whose raw line and fields are kept
+// compressed in memory. RawLine and Field decompress them; the level is
+// resolved beforehand so that it stays cheap to read.
+func (e LogEntry) Pack() LogEntry {
+	if e.packed != nil || len(e.Raw) < packMinSize {
+		return e
+	}
+	fields, err := json.Marshal(e.Fields)
+	if err != nil {
+		return e
+	}
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return e
+	}
+	data := append(append([]byte(e.Raw), 0), fields...)
+	if _, err := w.Write(data); err != nil {
+		return e
+	}
+	if err := w.Close(); err != nil {
+		return e
+	}
+	if buf.Len() >= len(data) {
+		return e
+	}
+	e.LevelName = e.Level()
+	e.packed = buf.Bytes()
+	e.preview = cutString(e.Raw, packPreviewSize)
+	e.sum = crc32.ChecksumIEEE([]byte(e.Raw))
+	e.Raw = ""
+	e.Fields = nil
+	return e
+}
+
+// unpack returns the raw line and fields of a packed entry.
+func (e LogEntry) unpack() (string, map[string]any) {
+	data, err := io.ReadAll(flate.NewReader(bytes.NewReader(e.packed)))
+	if err != nil {
+		return "", nil
+	}
+	raw, fields, _ := bytes.Cut(data, []byte{0})
+	var decoded map[string]any
+	json.Unmarshal(fields, &decoded)
+	return string(raw), decoded
+}
+
+// Unpacked returns the entry with its raw line and fields decompressed.
+func (e LogEntry) Unpacked() LogEntry {
+	if e.packed == nil {
+		return e
+	}
+	e.Raw, e.Fields = e.unpack()
+	e.packed, e.preview, e.sum = nil, "", 0
+	return e
+}
+
+// RawLine returns the original line, decompressing it if needed.
+func (e LogEntry) RawLine() string {
+	if e.packed == nil {
+		return e.Raw
+	}
+	raw, _ := e.unpack()
+	return raw
+}
+
+// RawPreview returns the raw line, or only its start for packed entries.
+// It is meant for lists and search, which should not decompress.
+func (e LogEntry) RawPreview() string {
+	if e.packed == nil {
+		return e.Raw
+	}
+	return e.preview
+}
+
+// FieldMap returns the decoded fields, decompressing them if needed.
+func (e LogEntry) FieldMap() map[string]any {
+	if e.packed == nil {
+		return e.Fields
+	}
+	_, fields := e.unpack()
+	return fields
+}
+
+// Key identifies the entry by its source and raw content without
+// decompressing it.
+func (e LogEntry) Key() string {
+	if e.packed != nil {
+		return e.Path + "\x00z" + strconv.FormatInt(e.Offset, 10) + ":" + strconv.FormatUint(uint64(e.sum), 16)
+	}
+	return e.Path + "\x00" + e.Raw
+}
+
+// Truncated reports whether Raw holds only a prefix of the line.
+func (e LogEntry) Truncated() bool {
+	return e.Size > 0
+}
+
+// truncate caps the memory held by an oversized entry: the raw line, the
+// message and long string fields are cut to limit bytes, while the other
+// fields stay so that filters and columns still see the entry. source and
+// size are the line as it is in the file, which FullRaw reads again; Raw
+// may differ from it after rewriting, joining or stripping escapes.
+func (e LogEntry) truncate(limit int, source string, size int) LogEntry {
+	if len(e.Raw) <= limit {
+		return e
+	}
+	e.Size = size
+	e.Checksum = crc32.ChecksumIEEE([]byte(source))
+	e.Raw = cutString(e.Raw, limit)
+	e.Message = cutString(e.Message, limit)
+	capStrings(e.Fields, limit)
+	return e
+}
+
+// capStrings cuts the strings in a decoded value to limit bytes, in place.
+func capStrings(v any, limit int) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			if s, ok := item.(string); ok {
+				v[k] = cutString(s, limit)
+			} else {
+				capStrings(item, limit)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			if s, ok := item.(string); ok {
+				v[i] = cutString(s, limit)
+			} else {
+				capStrings(item, limit)
+			}
+		}
+	}
+}
+
+// cutString returns at most limit bytes of s without splitting a rune.
+func cutString(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit]
+}
+
+// FullRaw returns the complete line, re-reading it from the source file if
+// it was truncated in memory. The line is then as it is in the file, before
+// any rewriting.
+func (e LogEntry) FullRaw() (string, error) {
+	if !e.Truncated() {
+		return e.RawLine(), nil
+	}
+	file, err := os.Open(e.Path)
+	if err != nil {
+		return "", fmt.Errorf("load full entry: %w", err)
+	}
+	defer file.Close()
+
+	buf := make([]byte, e.Size)
+	if _, err := file.ReadAt(buf, e.Offset); err != nil {
+		return "", fmt.Errorf("load full entry: %w", err)
+	}
+	if !e.sameSource(buf) {
+		return "", fmt.Errorf("load full entry: %s changed since the entry was read", e.Path)
+	}
+	return string(buf), nil
+}
+
+// sameSource reports whether buf holds the line the entry was read from.
+// Entries stored without a checksum compare their raw prefix instead.
+func (e LogEntry) sameSource(buf []byte) bool {
+	if e.Checksum == 0 {
+		return bytes.HasPrefix(buf, []byte(e.RawLine()))
+	}
+	return crc32.ChecksumIEEE(bytes.ReplaceAll(buf, []byte("\r\n"), []byte("\n"))) == e.Checksum
 }
 
 // PrettyJSON returns a prettified version of the original raw JSON payload.
+// Truncated entries are loaded in full from their file first.
 func (e LogEntry) PrettyJSON() string {
-	if e.Raw == "" {
+	raw, err := e.FullRaw()
+	if err != nil {
+		return e.RawLine() + "\n\n[truncated: " + err.Error() + "]"
+	}
+	if raw == "" {
 		return ""
 	}
 	var buf map[string]any
-	if err := json.Unmarshal([]byte(e.Raw), &buf); err != nil {
-		return e.Raw
+	if err := json.Unmarshal([]byte(raw), &buf); err != nil {
+		return raw
 	}
 	data, err := json.MarshalIndent(buf, "", "  ")
 	if err != nil {
-		return e.Raw
+		return raw
 	}
 	return string(data)
 }
 
 // DisplayTimestamp returns the best-effort human timestamp associated with the entry.
 func (e LogEntry) DisplayTimestamp() string {
+	return e.DisplayTimestampIn(time.Local)
+}
+
+// DisplayTimestampIn is DisplayTimestamp with a parsed time shown in loc.
+func (e LogEntry) DisplayTimestampIn(loc *time.Location) string {
 	if !e.Timestamp.IsZero() {
-		return e.Timestamp.Local().Format("2006-01-02 15:04:05")
+		return e.Timestamp.In(loc).Format("2006-01-02 15:04:05")
 	}
 	return e.TimestampText
 }
@@ -53,11 +276,23 @@ func (e LogEntry) ExtraValue(name string) string {
 	return e.Extras[name]
 }
 
+// ParseLine decodes a line read from path outside of a Tailer, applying the
+// first profile matching path on top of base.
+func ParseLine(path, line string, base ParserConfig, profiles []ParserProfile) (LogEntry, error) {
+	return parseEntry(path, line, resolveParser(base, profiles, path))
+}
+
 func parseEntry(path string, line string, cfg ParserConfig) (LogEntry, error) {
-	fields := make(map[string]any)
-	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+	if cfg.StripANSI {
+		line = StripANSI(line)
+	}
+	fields, err := lookupFormat(cfg.Format).decode(line, cfg)
+	if err != nil {
 		return LogEntry{}, fmt.Errorf("parse %s: %w", path, err)
 	}
+	if cfg.StripANSI {
+		stripANSIValues(fields)
+	}
 
 	entry := LogEntry{
 		Path:   path,
@@ -66,26 +301,99 @@ func parseEntry(path string, line string, cfg ParserConfig) (LogEntry, error) {
 		Extras: make(map[string]string),
 	}
 
-	entry.Timestamp, entry.TimestampText = extractTimestamp(fields[cfg.TimestampField])
-	entry.Message = extractString(fields[cfg.MessageField])
+	entry.applyMapping(cfg.TimestampField, cfg.MessageField)
+	if len(cfg.LevelMap) > 0 {
+		entry.LevelName = cfg.LevelMap[entry.rawLevel()]
+	}
 
 	for _, name := range cfg.ExtraFields {
 		switch name {
 		case "@file":
 			entry.Extras[name] = path
 		default:
-			entry.Extras[name] = extractString(fields[name])
+			entry.Extras[name] = entry.FieldString(name)
 		}
 	}
 
 	return entry, nil
 }
 
-// ParserConfig controls how JSON entries are interpreted.
+// WithMapping returns a copy of the entry with timestamp and message
+// re-derived from the given field names.
+func (e LogEntry) WithMapping(timestampField, messageField string) LogEntry {
+	e.applyMapping(timestampField, messageField)
+	return e
+}
+
+func (e *LogEntry) applyMapping(timestampField, messageField string) {
+	ts, _ := e.Field(timestampField)
+	e.Timestamp, e.TimestampText = extractTimestamp(ts)
+	msg, _ := e.Field(messageField)
+	e.Message = extractString(msg)
+}
+
+// Field returns the value of the named field. A name with dots that is not
+// a field itself walks nested objects, so "http.request.method" finds
+// {"http":{"request":{"method":"GET"}}}.
+func (e LogEntry) Field(name string) (any, bool) {
+	return lookupField(e.FieldMap(), name)
+}
+
+func lookupField(fields map[string]any, name string) (any, bool) {
+	if v, ok := fields[name]; ok {
+		return v, true
+	}
+	// Try each dot as a split point, so that keys containing dots, as in
+	// {"http":{"request.method":"GET"}}, are found too.
+	for i := 0; i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+		if nested, ok := fields[name[:i]].(map[string]any); ok {
+			if v, ok := lookupField(nested, name[i+1:]); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// FieldString returns the named field rendered as a string.
+func (e LogEntry) FieldString(name string) string {
+	v, _ := e.Field(name)
+	return extractString(v)
+}
+
+// FormatValue renders a decoded field value as text: strings as is,
+// numbers without exponent and objects as compact JSON.
+func FormatValue(value any) string {
+	return extractString(value)
+}
+
+// FieldPreview returns a short single-line rendering of a field value.
+func (e LogEntry) FieldPreview(name string, limit int) string {
+	val := e.FieldString(name)
+	if limit > 0 && len(val) > limit {
+		val = cutString(val, limit) + "…"
+	}
+	return val
+}
+
+// ParserConfig controls how log lines are decoded and interpreted.
 type ParserConfig struct {
+	Format         string
 	TimestampField string
 	MessageField   string
 	ExtraFields    []string
+	// StripANSI removes terminal escape sequences from lines and their
+	// values before they are parsed.
+	StripANSI bool
+	// Pattern is the expression of the regex format, whose named groups
+	// become fields.
+	Pattern *regexp.Regexp
+	// LevelMap maps lower-cased raw level values, "30" or "warning", to
+	// the level entries report instead.
+	LevelMap map[string]string
 }
 
 func extractTimestamp(value any) (time.Time, string) {
@@ -123,6 +431,7 @@ func parseTimeString(input string) (time.Time, bool) {
 		"2006-01-02 15:04:05.999999",
 		"2006-01-02 15:04:05",
 		"02/01/2006 15:04:05",
+		"02/Jan/2006:15:04:05 -0700",
 	}
 	for _, layout := range layouts {
 		if ts, err := time.Parse(layout, input); err == nil {
@@ -0,0 +1,20 @@
+package logs
+
+// ErrorSink passes errors of work that runs beside the tailer, such as
+// transforms, to whoever shows them. Reporting never blocks the entry
+// being processed: once the buffer is full, further errors are dropped
+// until the reader catches up.
+type ErrorSink chan error
+
+// NewErrorSink returns a sink buffering a few errors.
+func NewErrorSink() ErrorSink {
+	return make(ErrorSink, 16)
+}
+
+// Report sends err unless the buffer is full.
+func (s ErrorSink) Report(err error) {
+	select {
+	case s <- err:
+	default:
+	}
+}
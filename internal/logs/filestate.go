@@ -0,0 +1,399 @@
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// fileState tracks a tailed file through its open descriptor. Keeping the
+// descriptor open lets the tailer notice rotation by comparing the identity
+// (device and inode) of the open file with whatever the path points to now,
+// and notice copytruncate by the open file shrinking below the read offset
+// or, when it has been written past that offset again before the next read,
+// by the bytes just before the offset no longer being the ones read there.
+//
+// After rotation the previous descriptor is kept as retired and read
+// alongside the new file until it has been quiet for rotationGrace, so lines
+// a writer appends to the renamed file (app.log.1) before reopening are not
+// lost.
+type fileState struct {
+	file         *os.File
+	offset       int64
+	pending      string
+	pendingStart int64
+	chunkSize    int
+	// mark holds the last bytes read, ending at offset.
+	mark []byte
+
+	retired       *fileState
+	retiredActive time.Time
+}
+
+// markSize is how many bytes before the read offset are compared to detect
+// copytruncate.
+const markSize = 64
+
+// rotationGrace is how long a renamed file keeps being read after its last
+// write before it is closed.
+const rotationGrace = 5 * time.Second
+
+// rawLine is a line read from a file together with the offset of its first
+// byte, so that it can be read again later.
+type rawLine struct {
+	text   string
+	offset int64
+	// source and size are the line as read and the number of bytes it
+	// spans in the file, set once joining or rewriting changes text.
+	source string
+	size   int
+}
+
+// original returns the line as it is in the file, with line breaks as \n.
+func (l rawLine) original() string {
+	if l.size == 0 {
+		return l.text
+	}
+	return l.source
+}
+
+// span returns the number of bytes the line takes in the file, without
+// its final line break.
+func (l rawLine) span() int {
+	if l.size == 0 {
+		return len(l.text)
+	}
+	return l.size
+}
+
+// scanLines reads all lines from r, which starts at offset base.
+func scanLines(r io.Reader, base int64, bufSize int) ([]rawLine, error) {
+	var lines []rawLine
+	reader := bufio.NewReaderSize(r, bufSize)
+	offset := base
+	for {
+		text, err := reader.ReadString('\n')
+		if len(text) > 0 {
+			next := offset + int64(len(text))
+			text = strings.TrimSuffix(text, "\n")
+			text = strings.TrimSuffix(text, "\r")
+			lines = append(lines, rawLine{text: text, offset: offset})
+			offset = next
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return lines, nil
+			}
+			return lines, err
+		}
+	}
+}
+
+func (s *fileState) bufferSize() int {
+	return positiveOr(s.chunkSize, defaultReadChunkSize)
+}
+
+// open (re)opens path and resets the read position.
+func (s *fileState) open(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	s.close()
+	s.file = file
+	s.reset()
+	return nil
+}
+
+func (s *fileState) close() {
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+	s.closeRetired()
+}
+
+func (s *fileState) closeRetired() {
+	if s.retired != nil {
+		s.retired.close()
+		s.retired = nil
+	}
+}
+
+func (s *fileState) readAll(path string) ([]rawLine, error) {
+	if err := s.open(path); err != nil {
+		return nil, err
+	}
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	lines, err := scanLines(io.NewSectionReader(s.file, 0, info.Size()), 0, s.bufferSize())
+	if err != nil {
+		return nil, err
+	}
+	s.offset = info.Size()
+	s.pending = ""
+	s.pendingStart = s.offset
+	return lines, s.remember()
+}
+
+// skipToEnd opens path and positions the read offset at its end.
+func (s *fileState) skipToEnd(path string) error {
+	if err := s.open(path); err != nil {
+		return err
+	}
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	s.offset = info.Size()
+	s.pendingStart = s.offset
+	return s.remember()
+}
+
+// tailChunkSize is the block size used when scanning a file backwards.
+const tailChunkSize = 64 * 1024
+
+// readTail returns the last n lines of the file without reading it from the
+// start: it scans backwards from the end in blocks until enough newlines
+// have been seen, then reads forward from there.
+func (s *fileState) readTail(path string, n int) ([]rawLine, error) {
+	if err := s.open(path); err != nil {
+		return nil, err
+	}
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+
+	start, err := tailOffset(s.file, size, n)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := scanLines(io.NewSectionReader(s.file, start, size-start), start, s.bufferSize())
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	s.offset = size
+	s.pending = ""
+	s.pendingStart = size
+	return lines, s.remember()
+}
+
+// tailOffset finds the offset at which the last n lines of the file begin.
+func tailOffset(file *os.File, size int64, n int) (int64, error) {
+	buf := make([]byte, tailChunkSize)
+	newlines := 0
+	pos := size
+	for pos > 0 {
+		chunk := int64(len(buf))
+		if chunk > pos {
+			chunk = pos
+		}
+		pos -= chunk
+		if _, err := file.ReadAt(buf[:chunk], pos); err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		for i := chunk - 1; i >= 0; i-- {
+			if buf[i] != '\n' {
+				continue
+			}
+			// A newline terminating the very last line doesn't start a new one.
+			if pos+i == size-1 {
+				continue
+			}
+			newlines++
+			if newlines == n {
+				return pos + i + 1, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// readNewLines returns the complete lines appended since the last read. It
+// first drains the open descriptor and then, if path now refers to a
+// different file, retires the old descriptor, switches to the new file and
+// reads it from the beginning.
+func (s *fileState) readNewLines(path string) ([]rawLine, error) {
+	lines := s.drainRetired()
+
+	if s.file == nil {
+		if err := s.open(path); err != nil {
+			return lines, err
+		}
+	}
+
+	more, err := s.drain()
+	lines = append(lines, more...)
+	if err != nil {
+		return lines, err
+	}
+
+	rotated, err := s.rotated(path)
+	if err != nil || !rotated {
+		return lines, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// The path vanished between the check and the open; keep
+			// following the old descriptor until it reappears.
+			return lines, nil
+		}
+		return lines, err
+	}
+	s.closeRetired()
+	s.retired = &fileState{
+		file:         s.file,
+		offset:       s.offset,
+		pending:      s.pending,
+		pendingStart: s.pendingStart,
+		chunkSize:    s.chunkSize,
+		mark:         s.mark,
+	}
+	s.retiredActive = time.Now()
+	s.file = file
+	s.reset()
+
+	more, err = s.drain()
+	return append(lines, more...), err
+}
+
+// drainRetired reads whatever was appended to the renamed file since the
+// last read and closes it once it has been quiet for rotationGrace. A
+// trailing line without newline is delivered when the file is closed.
+func (s *fileState) drainRetired() []rawLine {
+	if s.retired == nil {
+		return nil
+	}
+	lines, err := s.retired.drain()
+	if len(lines) > 0 {
+		s.retiredActive = time.Now()
+	}
+	if err != nil || time.Since(s.retiredActive) >= rotationGrace {
+		if rest := strings.TrimSuffix(s.retired.pending, "\r"); rest != "" {
+			lines = append(lines, rawLine{text: rest, offset: s.retired.pendingStart})
+		}
+		s.closeRetired()
+	}
+	return lines
+}
+
+// rotated reports whether path no longer refers to the open file. A missing
+// path is not treated as rotation: writers may still append to the open
+// file until a replacement is created.
+func (s *fileState) rotated(path string) (bool, error) {
+	current, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	open, err := s.file.Stat()
+	if err != nil {
+		return false, err
+	}
+	return !os.SameFile(current, open), nil
+}
+
+// drain reads the open file from the current offset to EOF.
+func (s *fileState) drain() ([]rawLine, error) {
+	info, err := s.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if truncated, err := s.truncated(info.Size()); err != nil {
+		return nil, err
+	} else if truncated {
+		// Truncated in place (copytruncate): start over.
+		s.reset()
+	}
+	start := s.offset
+
+	var lines []rawLine
+	reader := bufio.NewReaderSize(io.NewSectionReader(s.file, s.offset, info.Size()-s.offset), s.bufferSize())
+	for {
+		chunk, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return lines, err
+		}
+		if len(chunk) > 0 {
+			s.offset += int64(len(chunk))
+			s.pending += chunk
+			for {
+				idx := indexOfNewline(s.pending)
+				if idx == -1 {
+					break
+				}
+				segment := s.pending[:idx]
+				if len(segment) > 0 && segment[len(segment)-1] == '\r' {
+					segment = segment[:len(segment)-1]
+				}
+				lines = append(lines, rawLine{text: segment, offset: s.pendingStart})
+				s.pendingStart += int64(idx + 1)
+				s.pending = s.pending[idx+1:]
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	if s.offset != start {
+		return lines, s.remember()
+	}
+	return lines, nil
+}
+
+// truncated reports whether the open file, now size bytes long, was
+// truncated since the last read: it is shorter than the read offset, or the
+// bytes before the offset changed because it was written past it again.
+func (s *fileState) truncated(size int64) (bool, error) {
+	if size < s.offset {
+		return true, nil
+	}
+	if len(s.mark) == 0 {
+		return false, nil
+	}
+	buf := make([]byte, len(s.mark))
+	if _, err := s.file.ReadAt(buf, s.offset-int64(len(buf))); err != nil {
+		if errors.Is(err, io.EOF) {
+			return true, nil
+		}
+		return false, err
+	}
+	return !bytes.Equal(buf, s.mark), nil
+}
+
+// remember keeps the bytes just before the read offset for truncated.
+func (s *fileState) remember() error {
+	n := min(s.offset, markSize)
+	s.mark = make([]byte, n)
+	_, err := s.file.ReadAt(s.mark, s.offset-n)
+	if errors.Is(err, io.EOF) {
+		// Truncated while reading; the next read notices it.
+		s.mark = nil
+		err = nil
+	}
+	return err
+}
+
+func (s *fileState) reset() {
+	s.offset = 0
+	s.pending = ""
+	s.pendingStart = 0
+	s.mark = nil
+}
@@ -0,0 +1,39 @@
+package logs
+
+import (
+	"strings"
+	"time"
+)
+
+// Matches reports whether query occurs, ignoring case, in the message, raw
+// line, timestamp, path or extra fields of the entry. Of packed entries only
+// the start of the raw line is searched. An empty query matches every entry.
+func (e LogEntry) Matches(query string) bool {
+	return e.MatchesIn(query, time.Local)
+}
+
+// MatchesIn is Matches with the timestamp as shown in loc.
+func (e LogEntry) MatchesIn(query string, loc *time.Location) bool {
+	if query == "" {
+		return true
+	}
+	query = strings.ToLower(query)
+	if strings.Contains(strings.ToLower(e.Message), query) {
+		return true
+	}
+	if strings.Contains(strings.ToLower(e.RawPreview()), query) {
+		return true
+	}
+	if ts := e.DisplayTimestampIn(loc); ts != "" && strings.Contains(strings.ToLower(ts), query) {
+		return true
+	}
+	if strings.Contains(strings.ToLower(e.Path), query) {
+		return true
+	}
+	for _, val := range e.Extras {
+		if strings.Contains(strings.ToLower(val), query) {
+			return true
+		}
+	}
+	return false
+}
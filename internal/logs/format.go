@@ -0,0 +1,270 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultFormat is the line format used when nothing else is configured.
+const DefaultFormat = "json"
+
+// lineFormat describes how a raw line is decoded into fields.
+type lineFormat struct {
+	decode func(line string, cfg ParserConfig) (map[string]any, error)
+	// timestampField and messageField are the natural field names of the
+	// format, used by profiles that don't override them.
+	timestampField string
+	messageField   string
+}
+
+var formats = map[string]lineFormat{
+	"json": {decode: decodeJSON},
+	"nginx": {
+		decode:         decodeNginx,
+		timestampField: "time_local",
+		messageField:   "request",
+	},
+	"logfmt": {
+		decode:         decodeLogfmt,
+		timestampField: "time",
+		messageField:   "msg",
+	},
+	"regex":  {decode: decodeRegex},
+	"docker": {decode: decodeDocker},
+	"plain":  {decode: decodePlain, messageField: "message"},
+}
+
+// KnownFormat reports whether name refers to a supported line format.
+func KnownFormat(name string) bool {
+	_, ok := formats[strings.ToLower(name)]
+	return ok
+}
+
+// FormatNames lists the supported line formats.
+func FormatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FormatFields returns the natural timestamp and message field names of a
+// line format, or empty names if it has none.
+func FormatFields(name string) (timestampField, messageField string) {
+	f := lookupFormat(name)
+	return f.timestampField, f.messageField
+}
+
+func lookupFormat(name string) lineFormat {
+	if f, ok := formats[strings.ToLower(name)]; ok {
+		return f
+	}
+	return formats[DefaultFormat]
+}
+
+// ParserProfile selects a line format for files whose path matches a glob.
+type ParserProfile struct {
+	Match          string
+	Format         string
+	TimestampField string
+	MessageField   string
+	// Pattern is the expression of the regex format.
+	Pattern *regexp.Regexp
+}
+
+// Matches reports whether the profile applies to path.
+func (p ParserProfile) Matches(path string) bool {
+	return matchPath(p.Match, path)
+}
+
+// matchPath matches path against a glob pattern. Patterns without a path
+// separator are matched against the base name only.
+func matchPath(pattern, path string) bool {
+	if pattern == "" {
+		return false
+	}
+	target := path
+	if !strings.ContainsRune(pattern, filepath.Separator) && !strings.ContainsRune(pattern, '/') {
+		target = filepath.Base(path)
+	}
+	ok, err := filepath.Match(pattern, target)
+	return err == nil && ok
+}
+
+// resolveParser returns the parser configuration for path, applying the
+// first matching profile on top of base.
+func resolveParser(base ParserConfig, profiles []ParserProfile, path string) ParserConfig {
+	cfg := base
+	for _, p := range profiles {
+		if !p.Matches(path) {
+			continue
+		}
+		if p.Format != "" {
+			cfg.Format = p.Format
+			f := lookupFormat(p.Format)
+			if f.timestampField != "" {
+				cfg.TimestampField = f.timestampField
+			}
+			if f.messageField != "" {
+				cfg.MessageField = f.messageField
+			}
+		}
+		if p.TimestampField != "" {
+			cfg.TimestampField = p.TimestampField
+		}
+		if p.MessageField != "" {
+			cfg.MessageField = p.MessageField
+		}
+		if p.Pattern != nil {
+			cfg.Pattern = p.Pattern
+		}
+		break
+	}
+	return cfg
+}
+
+// ContinuationField holds the lines joined to a JSON line, such as a stack
+// trace written after it.
+const ContinuationField = "continuation"
+
+func decodeJSON(line string, _ ParserConfig) (map[string]any, error) {
+	fields := make(map[string]any)
+	err := json.Unmarshal([]byte(line), &fields)
+	if err == nil {
+		return fields, nil
+	}
+	first, rest, joined := strings.Cut(line, "\n")
+	if !joined {
+		return nil, err
+	}
+	fields = make(map[string]any)
+	if json.Unmarshal([]byte(first), &fields) != nil {
+		return nil, err
+	}
+	fields[ContinuationField] = rest
+	return fields, nil
+}
+
+// decodePlain takes the line as it is, for lines with no structure or ones
+// a parser plugin makes sense of.
+func decodePlain(line string, _ ParserConfig) (map[string]any, error) {
+	return map[string]any{"message": line}, nil
+}
+
+var nginxPattern = regexp.MustCompile(
+	`^(\S+) \S+ (\S+) \[([^\]]+)\] "([^"]*)" (\d{3}) (\d+|-)(?: "([^"]*)" "([^"]*)")?`,
+)
+
+// decodeNginx parses the nginx/Apache "combined" (and "common") access log format.
+func decodeNginx(line string, _ ParserConfig) (map[string]any, error) {
+	m := nginxPattern.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("line does not match nginx access log format")
+	}
+	fields := map[string]any{
+		"remote_addr":     m[1],
+		"remote_user":     m[2],
+		"time_local":      m[3],
+		"request":         m[4],
+		"status":          m[5],
+		"body_bytes_sent": m[6],
+	}
+	if m[7] != "" {
+		fields["http_referer"] = m[7]
+	}
+	if m[8] != "" {
+		fields["http_user_agent"] = m[8]
+	}
+	if parts := strings.SplitN(m[4], " ", 3); len(parts) == 3 {
+		fields["method"] = parts[0]
+		fields["path"] = parts[1]
+		fields["protocol"] = parts[2]
+	}
+	return fields, nil
+}
+
+// decodeLogfmt parses key=value pairs separated by spaces. Values may be
+// double-quoted with Go escapes; a key without a value is a flag and gets
+// an empty value. Values are kept as text, as in the nginx format.
+func decodeLogfmt(line string, _ ParserConfig) (map[string]any, error) {
+	fields := make(map[string]any)
+	pairs := 0
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '\t' && line[i] != '"' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			return nil, fmt.Errorf("logfmt: expected a key at %q", line[start:])
+		}
+		if i == len(line) || line[i] != '=' {
+			if i < len(line) && line[i] == '"' {
+				return nil, fmt.Errorf("logfmt: unexpected quote after %s", key)
+			}
+			fields[key] = ""
+			continue
+		}
+		i++
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, fmt.Errorf("logfmt: unterminated quote in %s", key)
+			}
+			value, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("logfmt: value of %s: %w", key, err)
+			}
+			fields[key] = value
+			i = end + 1
+		} else {
+			start = i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				i++
+			}
+			fields[key] = line[start:i]
+		}
+		pairs++
+	}
+	if pairs == 0 {
+		return nil, fmt.Errorf("line has no logfmt key=value pairs")
+	}
+	return fields, nil
+}
+
+// decodeRegex matches the line against the configured pattern; each named
+// group that took part in the match becomes a text field.
+func decodeRegex(line string, cfg ParserConfig) (map[string]any, error) {
+	if cfg.Pattern == nil {
+		return nil, fmt.Errorf("regex format needs a pattern")
+	}
+	m := cfg.Pattern.FindStringSubmatchIndex(line)
+	if m == nil {
+		return nil, fmt.Errorf("line does not match pattern")
+	}
+	fields := make(map[string]any)
+	for i, name := range cfg.Pattern.SubexpNames() {
+		if name == "" || m[2*i] < 0 {
+			continue
+		}
+		fields[name] = line[m[2*i]:m[2*i+1]]
+	}
+	return fields, nil
+}
@@ -0,0 +1,87 @@
+package logs
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// isCompressed reports whether path is a gzip-compressed file, typically a
+// rotated log such as app.log.2.gz. Compressed files are read once and not
+// followed, since nothing appends to them.
+func isCompressed(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+// readCompressed delivers the lines of a gzip-compressed file, the last
+// tailLines of them if tailLines is positive. Offsets count bytes of the
+// decompressed content, and lines are kept whole since they cannot be read
+// again cheaply.
+func (t *Tailer) readCompressed(ctx context.Context, path string, tailLines int, entries chan LogEntry, errs chan<- error) {
+	if tailLines < 0 {
+		return
+	}
+	source := resolveSource(t.source, t.sources, path)
+	parser := resolveParser(t.parser, t.profiles, path)
+	out := &sink{
+		ch:       entries,
+		policy:   source.Backpressure,
+		counters: t.counters(path),
+	}
+	lines, size, err := scanCompressed(path, tailLines, source.ReadChunkSize)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			t.sourceFailed(errs, fmt.Errorf("initial read %s: %w", path, err))
+		}
+		return
+	}
+	out.counters.offset.Store(size)
+	t.emitLines(ctx, path, newLineRewriter(source).process(lines, true), parser, out, errs)
+}
+
+// scanCompressed decompresses path and returns its lines, only the last n
+// if n is positive, with the decompressed size.
+func scanCompressed(path string, n, bufSize int) ([]rawLine, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gzip: %w", err)
+	}
+	defer gz.Close()
+
+	var lines []rawLine
+	reader := bufio.NewReaderSize(gz, positiveOr(bufSize, defaultReadChunkSize))
+	var offset int64
+	for {
+		text, err := reader.ReadString('\n')
+		if len(text) > 0 {
+			next := offset + int64(len(text))
+			text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r")
+			lines = append(lines, rawLine{text: text, offset: offset})
+			offset = next
+			// Keep memory bounded by the tail while scanning.
+			if n > 0 && len(lines) >= 2*n {
+				lines = append(lines[:0], lines[len(lines)-n:]...)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, offset, nil
+}
@@ -0,0 +1,110 @@
+package logs
+
+import (
+	"strconv"
+	"strings"
+)
+
+// levelFields are the field names checked for a severity, in order.
+var levelFields = []string{"level", "lvl", "severity", "log.level"}
+
+// Level returns the lower-cased severity of the entry, as the level map
+// names it if it has an entry for the value, or "" if none of the usual
+// level fields is present.
+func (e LogEntry) Level() string {
+	if e.LevelName != "" {
+		return e.LevelName
+	}
+	return e.rawLevel()
+}
+
+func (e LogEntry) rawLevel() string {
+	for _, name := range levelFields {
+		if v, ok := e.Field(name); ok {
+			return strings.ToLower(extractString(v))
+		}
+		if v := e.Extras[name]; v != "" {
+			return strings.ToLower(v)
+		}
+	}
+	return ""
+}
+
+// IsErrorLevel reports whether level denotes an error or worse.
+func IsErrorLevel(level string) bool {
+	switch level {
+	case "error", "err", "fatal", "panic", "critical", "crit", "alert", "emergency", "emerg":
+		return true
+	}
+	return false
+}
+
+// Severity is a level normalized across the names and numbers producers
+// use, ordered from least to most severe.
+type Severity int
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityTrace
+	SeverityDebug
+	SeverityInfo
+	SeverityWarn
+	SeverityError
+	SeverityFatal
+)
+
+var severityNames = map[Severity]string{
+	SeverityTrace: "trace",
+	SeverityDebug: "debug",
+	SeverityInfo:  "info",
+	SeverityWarn:  "warn",
+	SeverityError: "error",
+	SeverityFatal: "fatal",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// syslogSeverities maps the syslog numbers 0 (emergency) to 7 (debug).
+var syslogSeverities = []Severity{
+	SeverityFatal, SeverityFatal, SeverityFatal, SeverityError,
+	SeverityWarn, SeverityInfo, SeverityInfo, SeverityDebug,
+}
+
+// ParseSeverity normalizes a level as returned by Level: names in their
+// usual spellings, syslog numbers 0-7 and the pino/bunyan numbers 10-60.
+func ParseSeverity(level string) Severity {
+	switch level {
+	case "trace", "trc", "finest", "finer":
+		return SeverityTrace
+	case "debug", "dbg", "fine", "verbose":
+		return SeverityDebug
+	case "info", "inf", "information", "informational", "notice", "config":
+		return SeverityInfo
+	case "warn", "warning", "wrn":
+		return SeverityWarn
+	case "error", "err", "eror", "severe":
+		return SeverityError
+	case "fatal", "panic", "critical", "crit", "alert", "emergency", "emerg", "dpanic":
+		return SeverityFatal
+	}
+	n, err := strconv.Atoi(level)
+	switch {
+	case err != nil || n < 0:
+		return SeverityUnknown
+	case n < len(syslogSeverities):
+		return syslogSeverities[n]
+	case n >= 10 && n <= 60 && n%10 == 0:
+		return Severity(n / 10)
+	}
+	return SeverityUnknown
+}
+
+// Severity returns the normalized level of the entry.
+func (e LogEntry) Severity() Severity {
+	return ParseSeverity(e.Level())
+}
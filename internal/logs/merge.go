@@ -0,0 +1,106 @@
+package logs
+
+import (
+	"context"
+	"time"
+)
+
+// mergeTick is the shortest interval at which held entries are re-checked.
+const mergeTick = 10 * time.Millisecond
+
+type heldEntry struct {
+	entry   LogEntry
+	arrived time.Time
+}
+
+// MergeByTime reorders entries arriving from several files into a single
+// timestamp-ordered stream. Each file is assumed to be ordered on its own,
+// so the merge is k-way over per-file queues: the earliest head is emitted
+// as soon as every known file has a pending entry, or once it has been held
+// for window. Files are only known once they delivered something, so the
+// first shortcut is disabled during the first window after start.
+//
+// A larger window tolerates slower sources at the cost of latency. Entries
+// without a timestamp are passed on in arrival order.
+func MergeByTime(ctx context.Context, in <-chan LogEntry, window time.Duration) <-chan LogEntry {
+	out := make(chan LogEntry, cap(in))
+	go func() {
+		defer close(out)
+
+		queues := make(map[string][]heldEntry)
+		tick := window / 4
+		if tick < mergeTick {
+			tick = mergeTick
+		}
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		warmUntil := time.Now().Add(window)
+		emit := func(flush bool) bool {
+			now := time.Now()
+			for {
+				path, ok := mergeCandidate(queues, now, window, flush, now.After(warmUntil))
+				if !ok {
+					return true
+				}
+				q := queues[path]
+				select {
+				case <-ctx.Done():
+					return false
+				case out <- q[0].entry:
+				}
+				q[0] = heldEntry{}
+				queues[path] = q[1:]
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case entry, ok := <-in:
+				if !ok {
+					emit(true)
+					return
+				}
+				queues[entry.Path] = append(queues[entry.Path], heldEntry{entry: entry, arrived: time.Now()})
+			case <-ticker.C:
+			}
+			if !emit(false) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// mergeCandidate picks the queue whose head should be emitted next, if any.
+func mergeCandidate(queues map[string][]heldEntry, now time.Time, window time.Duration, flush, warm bool) (string, bool) {
+	var (
+		best     string
+		bestHead heldEntry
+		found    bool
+		allHave  = true
+	)
+	for path, q := range queues {
+		if len(q) == 0 {
+			allHave = false
+			continue
+		}
+		head := q[0]
+		if head.entry.Timestamp.IsZero() {
+			// Nothing to order by: release immediately.
+			return path, true
+		}
+		if !found || head.entry.Timestamp.Before(bestHead.entry.Timestamp) {
+			best, bestHead, found = path, head, true
+		}
+	}
+	if !found {
+		return "", false
+	}
+	if flush || (warm && allHave) || now.Sub(bestHead.arrived) >= window {
+		return best, true
+	}
+	return "", false
+}
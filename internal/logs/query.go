@@ -0,0 +1,247 @@
+package logs
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter selects entries by a list of terms which must all match. A term is
+// either a field condition or plain text:
+//
+//	level=error       field equals value, ignoring case
+//	user!=admin       field is absent or differs
+//	msg~timeout       field contains value, ignoring case
+//	status>=500       field compares as given: >, >=, < or <=
+//	"disk full"       text anywhere in the entry, as the / search does
+//
+// Terms may be joined by AND, which reads better but changes nothing. The
+// level field matches the entry severity whatever field it is stored
+// in, and the pseudo fields @message and @file refer to the parsed message
+// and the source path; msg and message fall back to the parsed message when
+// the entry has no such field. Comparisons are numeric when both sides are
+// numbers and lexical otherwise. Values containing spaces are written in
+// double quotes.
+type Filter struct {
+	terms []filterTerm
+	// location is the zone text terms see timestamps in; nil means local
+	// time.
+	location *time.Location
+}
+
+type filterTerm struct {
+	field string
+	op    string
+	value string
+}
+
+// filterOps are tried at the first operator character of a term, longest
+// first.
+var filterOps = []string{">=", "<=", "!=", "=", "~", ">", "<"}
+
+// ParseFilter parses a filter expression. An empty expression matches every
+// entry.
+func ParseFilter(expr string) (Filter, error) {
+	words, err := splitFilterWords(expr)
+	if err != nil {
+		return Filter{}, err
+	}
+	var f Filter
+	for _, word := range words {
+		if !word.quoted && strings.EqualFold(word.text, "and") {
+			continue
+		}
+		term := filterTerm{value: word.text}
+		if i := strings.IndexAny(word.text, "=!~<>"); i >= 0 && !word.quoted {
+			for _, op := range filterOps {
+				if !strings.HasPrefix(word.text[i:], op) {
+					continue
+				}
+				if i == 0 {
+					return Filter{}, fmt.Errorf("filter term %q: missing field name", word.text)
+				}
+				term = filterTerm{field: word.text[:i], op: op, value: unquote(word.text[i+len(op):])}
+				break
+			}
+		}
+		term.value = strings.ToLower(term.value)
+		f.terms = append(f.terms, term)
+	}
+	return f, nil
+}
+
+// ParseSearch parses what is typed into the / search. A query with field
+// conditions is a filter, level=error AND service=billing msg~timeout;
+// otherwise, or if it does not parse, the whole query is plain text looked
+// for anywhere in the entry, spaces included.
+func ParseSearch(query string) Filter {
+	if query == "" {
+		return Filter{}
+	}
+	if f, err := ParseFilter(query); err == nil {
+		for _, term := range f.terms {
+			if term.field != "" {
+				return f
+			}
+		}
+	}
+	return Filter{terms: []filterTerm{{value: strings.ToLower(query)}}}
+}
+
+// In returns the filter with text terms matching timestamps as shown in
+// loc, for searching what a list in that zone displays.
+func (f Filter) In(loc *time.Location) Filter {
+	f.location = loc
+	return f
+}
+
+// Match reports whether entry satisfies every term of the filter.
+func (f Filter) Match(entry LogEntry) bool {
+	loc := f.location
+	if loc == nil {
+		loc = time.Local
+	}
+	for _, term := range f.terms {
+		if !term.match(entry, loc) {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty reports whether the filter has no terms.
+func (f Filter) Empty() bool {
+	return len(f.terms) == 0
+}
+
+// Words returns the lower-cased text the filter looks for anywhere in an
+// entry, i.e. its terms without a field.
+func (f Filter) Words() []string {
+	var words []string
+	for _, term := range f.terms {
+		if term.field == "" && term.value != "" {
+			words = append(words, term.value)
+		}
+	}
+	return words
+}
+
+func (t filterTerm) match(entry LogEntry, loc *time.Location) bool {
+	if t.field == "" {
+		return entry.MatchesIn(t.value, loc)
+	}
+	value, ok := filterField(entry, t.field)
+	value = strings.ToLower(value)
+	switch t.op {
+	case "=":
+		return ok && value == t.value
+	case "!=":
+		return !ok || value != t.value
+	case "~":
+		return ok && strings.Contains(value, t.value)
+	}
+	if !ok {
+		return false
+	}
+	c := compareValues(value, t.value)
+	switch t.op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	default:
+		return c <= 0
+	}
+}
+
+// compareValues compares a and b as numbers if both parse as one, and as
+// strings otherwise.
+func compareValues(a, b string) int {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return cmp.Compare(x, y)
+	}
+	return strings.Compare(a, b)
+}
+
+func filterField(entry LogEntry, name string) (string, bool) {
+	switch name {
+	case "level":
+		level := entry.Level()
+		return level, level != ""
+	case "@message":
+		return entry.Message, true
+	case "@file":
+		return entry.Path, true
+	}
+	if v, ok := entry.Field(name); ok {
+		return extractString(v), true
+	}
+	v, ok := entry.Extras[name]
+	if !ok && (name == "msg" || name == "message") {
+		// The message may be mapped from a field of another name.
+		return entry.Message, true
+	}
+	return v, ok
+}
+
+type filterWord struct {
+	text   string
+	quoted bool
+}
+
+// splitFilterWords splits expr at spaces outside double quotes. A word that
+// is entirely quoted is marked so that it is taken as plain text.
+func splitFilterWords(expr string) ([]filterWord, error) {
+	var (
+		words   []filterWord
+		cur     strings.Builder
+		inQuote bool
+		started bool
+		quoted  bool
+	)
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			if !started {
+				quoted = true
+			}
+			inQuote = !inQuote
+			started = true
+			cur.WriteRune(r)
+		case r == ' ' && !inQuote:
+			if started {
+				words = append(words, filterWord{text: cur.String(), quoted: quoted})
+			}
+			cur.Reset()
+			started, quoted = false, false
+		default:
+			started = true
+			cur.WriteRune(r)
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("filter %q: unterminated quote", expr)
+	}
+	if started {
+		words = append(words, filterWord{text: cur.String(), quoted: quoted})
+	}
+	for i := range words {
+		if words[i].quoted {
+			words[i].text = unquote(words[i].text)
+		}
+	}
+	return words, nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+package logs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RewriteRule replaces the matches of Pattern in a raw line before it is
+// parsed; Replace may refer to groups as $1 or ${name}.
+type RewriteRule struct {
+	Pattern *regexp.Regexp
+	Replace string
+}
+
+// lineRewriter applies the pre-parse rules of a source. Lines matching
+// join, or with joinJSON lines that do not start a JSON object, are
+// continuations and are appended, after a newline, to the line before them;
+// rewrite rules then apply to the joined line. A line that rewrites to
+// nothing is skipped.
+type lineRewriter struct {
+	rules    []RewriteRule
+	join     *regexp.Regexp
+	joinJSON bool
+	// pending is the last line read, held back while continuations of it
+	// may follow.
+	pending *rawLine
+}
+
+func newLineRewriter(source SourceOptions) *lineRewriter {
+	if len(source.Rewrite) == 0 && source.Join == nil && !source.JoinJSON {
+		return nil
+	}
+	return &lineRewriter{rules: source.Rewrite, join: source.Join, joinJSON: source.JoinJSON}
+}
+
+// continues reports whether line continues the one before it. With
+// joinJSON an entry starts at a line beginning with {, which also keeps
+// pretty-printed objects together.
+func (r *lineRewriter) continues(line string) bool {
+	if r.joinJSON {
+		return !strings.HasPrefix(line, "{")
+	}
+	return r.join.MatchString(line)
+}
+
+// process returns the lines ready for parsing. With flush the held back
+// line is returned too; the tailer flushes when a read finds no new lines,
+// so a last line waits for at most one poll.
+func (r *lineRewriter) process(lines []rawLine, flush bool) []rawLine {
+	if r == nil {
+		return lines
+	}
+	out := make([]rawLine, 0, len(lines)+1)
+	if r.join == nil && !r.joinJSON {
+		for _, line := range lines {
+			out = append(out, r.rewrite(line))
+		}
+		return out
+	}
+	for _, line := range lines {
+		if r.pending != nil && r.continues(line.text) {
+			first := r.pending
+			first.source = first.original() + "\n" + line.original()
+			first.size = int(line.offset-first.offset) + line.span()
+			first.text += "\n" + line.text
+			continue
+		}
+		if r.pending != nil {
+			out = append(out, r.rewrite(*r.pending))
+		}
+		r.pending = &line
+	}
+	if flush && r.pending != nil {
+		out = append(out, r.rewrite(*r.pending))
+		r.pending = nil
+	}
+	return out
+}
+
+func (r *lineRewriter) rewrite(line rawLine) rawLine {
+	line.source, line.size = line.original(), line.span()
+	for _, rule := range r.rules {
+		line.text = rule.Pattern.ReplaceAllString(line.text, rule.Replace)
+	}
+	line.text = strings.TrimRight(line.text, "\r")
+	return line
+}
@@ -0,0 +1,427 @@
+package logs
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SQLQuery is a SELECT-style query over entries:
+//
+//	SELECT path, count(*) AS errors WHERE level=error GROUP BY path ORDER BY errors DESC LIMIT 10
+//
+// Columns are field names or expressions as in computed fields, or the
+// aggregates count(*), count(expr), sum, avg, min and max. WHERE takes a
+// filter expression, as :filter does. GROUP BY lists expressions or column
+// names; ORDER BY column names or 1-based numbers, each optionally followed
+// by ASC or DESC. Keywords are case-insensitive and a FROM clause is
+// ignored, so that "FROM logs" may be written out of habit.
+type SQLQuery struct {
+	columns []sqlColumn
+	where   Filter
+	groupBy []sqlExpr
+	grouped bool
+	orderBy []sqlOrder
+	limit   int
+}
+
+// SQLResult is the table a query produces.
+type SQLResult struct {
+	Columns []string
+	Rows    [][]string
+	// Scanned is how many entries matched the WHERE clause.
+	Scanned int
+}
+
+type sqlExpr struct {
+	text  string
+	field ComputedField
+}
+
+type sqlColumn struct {
+	name string
+	// agg is the aggregate function, or empty for a plain expression.
+	agg string
+	// star marks count(*), which has no expression.
+	star bool
+	expr sqlExpr
+	// group is the GROUP BY term a plain column of a grouped query shows.
+	group int
+}
+
+type sqlOrder struct {
+	column int
+	desc   bool
+}
+
+var (
+	sqlAggregate = regexp.MustCompile(`(?i)^(count|sum|avg|min|max)\s*\((.*)\)$`)
+	sqlAlias     = regexp.MustCompile(`(?i)^(.*\S)\s+as\s+([A-Za-z_@][\w.@-]*)$`)
+	sqlKeywords  = []string{"select", "from", "where", "group by", "order by", "limit"}
+)
+
+// ParseSQL parses a query. The SELECT keyword may be left out.
+func ParseSQL(query string) (*SQLQuery, error) {
+	query = strings.TrimSpace(query)
+	if _, ok := keywordAt(query, 0, "select"); !ok {
+		query = "select " + query
+	}
+	clauses, err := splitSQLClauses(query)
+	if err != nil {
+		return nil, err
+	}
+	if from := clauses["from"]; from != "" && strings.ContainsAny(from, " \t") {
+		return nil, fmt.Errorf("FROM takes a single name, the buffer being the only table")
+	}
+	q := &SQLQuery{limit: -1}
+	if q.where, err = ParseFilter(clauses["where"]); err != nil {
+		return nil, err
+	}
+	for _, item := range splitSQLList(clauses["select"]) {
+		col, err := parseSQLColumn(item)
+		if err != nil {
+			return nil, err
+		}
+		if col.agg != "" {
+			q.grouped = true
+		}
+		q.columns = append(q.columns, col)
+	}
+	if len(q.columns) == 0 {
+		return nil, fmt.Errorf("usage: SELECT <columns> [WHERE <filter>] [GROUP BY <expressions>] [ORDER BY <columns>] [LIMIT <n>]")
+	}
+	if by := clauses["group by"]; by != "" {
+		q.grouped = true
+		for _, item := range splitSQLList(by) {
+			e, err := q.groupExpr(item)
+			if err != nil {
+				return nil, err
+			}
+			q.groupBy = append(q.groupBy, e)
+		}
+	}
+	if q.grouped {
+		for i, col := range q.columns {
+			if col.agg != "" {
+				continue
+			}
+			q.columns[i].group = -1
+			for j, e := range q.groupBy {
+				if e.text == col.expr.text {
+					q.columns[i].group = j
+				}
+			}
+			if q.columns[i].group < 0 {
+				return nil, fmt.Errorf("column %s must be in GROUP BY or an aggregate", col.name)
+			}
+		}
+	}
+	if by := clauses["order by"]; by != "" {
+		for _, item := range splitSQLList(by) {
+			o, err := q.parseOrder(item)
+			if err != nil {
+				return nil, err
+			}
+			q.orderBy = append(q.orderBy, o)
+		}
+	}
+	if limit := clauses["limit"]; limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("LIMIT wants a number, got %q", limit)
+		}
+		q.limit = n
+	}
+	return q, nil
+}
+
+func parseSQLColumn(item string) (sqlColumn, error) {
+	col := sqlColumn{name: item}
+	if m := sqlAlias.FindStringSubmatch(item); m != nil {
+		item, col.name = m[1], m[2]
+	}
+	if m := sqlAggregate.FindStringSubmatch(item); m != nil {
+		col.agg = strings.ToLower(m[1])
+		arg := strings.TrimSpace(m[2])
+		if arg == "*" {
+			if col.agg != "count" {
+				return sqlColumn{}, fmt.Errorf("%s(*) is not supported, only count(*)", col.agg)
+			}
+			col.star = true
+			return col, nil
+		}
+		item = arg
+	}
+	e, err := parseSQLExpr(item)
+	if err != nil {
+		return sqlColumn{}, err
+	}
+	col.expr = e
+	return col, nil
+}
+
+func parseSQLExpr(text string) (sqlExpr, error) {
+	text = strings.TrimSpace(text)
+	field, err := ParseComputedField("value = " + text)
+	if err != nil {
+		return sqlExpr{}, fmt.Errorf("%s: %s", text, strings.TrimPrefix(err.Error(), "computed field value: "))
+	}
+	return sqlExpr{text: text, field: field}, nil
+}
+
+// groupExpr parses a GROUP BY term, which may name a column by its alias.
+func (q *SQLQuery) groupExpr(item string) (sqlExpr, error) {
+	for _, col := range q.columns {
+		if col.agg == "" && col.name != col.expr.text && strings.EqualFold(col.name, item) {
+			return col.expr, nil
+		}
+	}
+	return parseSQLExpr(item)
+}
+
+func (q *SQLQuery) parseOrder(item string) (sqlOrder, error) {
+	var o sqlOrder
+	if name, ok := cutSuffixFold(item, " desc"); ok {
+		item, o.desc = name, true
+	} else if name, ok := cutSuffixFold(item, " asc"); ok {
+		item = name
+	}
+	item = strings.TrimSpace(item)
+	if n, err := strconv.Atoi(item); err == nil {
+		if n < 1 || n > len(q.columns) {
+			return sqlOrder{}, fmt.Errorf("ORDER BY %d: there are %d columns", n, len(q.columns))
+		}
+		o.column = n - 1
+		return o, nil
+	}
+	for i, col := range q.columns {
+		if strings.EqualFold(col.name, item) || (col.agg == "" && col.expr.text == item) {
+			o.column = i
+			return o, nil
+		}
+	}
+	return sqlOrder{}, fmt.Errorf("ORDER BY %s: not a selected column", item)
+}
+
+// Run evaluates the query over entries, which keep their order in the
+// result unless ORDER BY is given. Groups are listed in the order they
+// first appear.
+func (q *SQLQuery) Run(entries []LogEntry) SQLResult {
+	res := SQLResult{}
+	for _, col := range q.columns {
+		res.Columns = append(res.Columns, col.name)
+	}
+	type group struct {
+		keys []string
+		aggs []sqlAggregateState
+	}
+	var groups []*group
+	index := make(map[string]*group)
+	for _, entry := range entries {
+		if !q.where.Match(entry) {
+			continue
+		}
+		res.Scanned++
+		if !q.grouped {
+			row := make([]string, len(q.columns))
+			for i, col := range q.columns {
+				row[i], _ = col.expr.field.Value(entry)
+			}
+			res.Rows = append(res.Rows, row)
+			continue
+		}
+		keys := make([]string, len(q.groupBy))
+		for i, e := range q.groupBy {
+			keys[i], _ = e.field.Value(entry)
+		}
+		id := strings.Join(keys, "\x00")
+		g, ok := index[id]
+		if !ok {
+			g = &group{keys: keys, aggs: make([]sqlAggregateState, len(q.columns))}
+			index[id] = g
+			groups = append(groups, g)
+		}
+		for i, col := range q.columns {
+			if col.agg == "" {
+				continue
+			}
+			if col.star {
+				g.aggs[i].add("")
+				continue
+			}
+			if v, ok := col.expr.field.Value(entry); ok {
+				g.aggs[i].add(v)
+			}
+		}
+	}
+	if q.grouped && len(groups) == 0 && len(q.groupBy) == 0 {
+		// An aggregate over no entries still has one row: count is 0.
+		groups = append(groups, &group{aggs: make([]sqlAggregateState, len(q.columns))})
+	}
+	for _, g := range groups {
+		row := make([]string, len(q.columns))
+		for i, col := range q.columns {
+			if col.agg == "" {
+				row[i] = g.keys[col.group]
+			} else {
+				row[i] = g.aggs[i].result(col.agg)
+			}
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	if len(q.orderBy) > 0 {
+		sort.SliceStable(res.Rows, func(i, j int) bool {
+			for _, o := range q.orderBy {
+				c := compareValues(res.Rows[i][o.column], res.Rows[j][o.column])
+				if c == 0 {
+					continue
+				}
+				return (c < 0) != o.desc
+			}
+			return false
+		})
+	}
+	if q.limit >= 0 && len(res.Rows) > q.limit {
+		res.Rows = res.Rows[:q.limit]
+	}
+	return res
+}
+
+type sqlAggregateState struct {
+	count int
+	// sum and nums cover the values that are numbers.
+	sum      float64
+	nums     int
+	min, max string
+}
+
+func (a *sqlAggregateState) add(v string) {
+	if a.count == 0 || compareValues(v, a.min) < 0 {
+		a.min = v
+	}
+	if a.count == 0 || compareValues(v, a.max) > 0 {
+		a.max = v
+	}
+	a.count++
+	if n, err := strconv.ParseFloat(v, 64); err == nil {
+		a.sum += n
+		a.nums++
+	}
+}
+
+func (a *sqlAggregateState) result(agg string) string {
+	switch agg {
+	case "count":
+		return strconv.Itoa(a.count)
+	case "sum":
+		return numValue(a.sum).String()
+	case "avg":
+		if a.nums == 0 {
+			return ""
+		}
+		return numValue(math.Round(a.sum/float64(a.nums)*1000) / 1000).String()
+	case "min":
+		return a.min
+	default:
+		return a.max
+	}
+}
+
+// splitSQLClauses splits a query at its keywords, which must come in the
+// usual order and are only recognized outside double quotes.
+func splitSQLClauses(query string) (map[string]string, error) {
+	clauses := make(map[string]string)
+	current, start := "", 0
+	next := 0
+	inQuote := false
+	for i := 0; i < len(query); i++ {
+		switch {
+		case query[i] == '"':
+			inQuote = !inQuote
+			continue
+		case inQuote || (i > 0 && query[i-1] != ' ' && query[i-1] != '\t'):
+			continue
+		}
+		for k := next; k < len(sqlKeywords); k++ {
+			kw := sqlKeywords[k]
+			end, ok := keywordAt(query, i, kw)
+			if !ok {
+				continue
+			}
+			if current != "" {
+				clauses[current] = strings.TrimSpace(query[start:i])
+			}
+			current, start, next = kw, end, k+1
+			i = end - 1
+			break
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	clauses[current] = strings.TrimSpace(query[start:])
+	for _, kw := range sqlKeywords[1:] {
+		if v, ok := clauses[kw]; ok && v == "" {
+			return nil, fmt.Errorf("%s without an argument", strings.ToUpper(kw))
+		}
+	}
+	return clauses, nil
+}
+
+// keywordAt reports whether kw starts at i as a whole word, and where it
+// ends. The words of "group by" and "order by" may be separated by any
+// number of spaces and tabs.
+func keywordAt(s string, i int, kw string) (int, bool) {
+	for n, word := range strings.Fields(kw) {
+		if n > 0 {
+			j := i
+			for j < len(s) && (s[j] == ' ' || s[j] == '\t') {
+				j++
+			}
+			if j == i {
+				return 0, false
+			}
+			i = j
+		}
+		if len(s)-i < len(word) || !strings.EqualFold(s[i:i+len(word)], word) {
+			return 0, false
+		}
+		i += len(word)
+	}
+	return i, i == len(s) || s[i] == ' ' || s[i] == '\t'
+}
+
+// splitSQLList splits at commas outside quotes and parentheses.
+func splitSQLList(s string) []string {
+	var items []string
+	depth, start := 0, 0
+	inQuote := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(s[start:]); rest != "" || len(items) > 0 {
+		items = append(items, rest)
+	}
+	return items
+}
+
+func cutSuffixFold(s, suffix string) (string, bool) {
+	if len(s) >= len(suffix) && strings.EqualFold(s[len(s)-len(suffix):], suffix) {
+		return s[:len(s)-len(suffix)], true
+	}
+	return s, false
+}
@@ -0,0 +1,224 @@
+package logs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func sqlEntries(fields ...map[string]any) []LogEntry {
+	entries := make([]LogEntry, len(fields))
+	for i, f := range fields {
+		entries[i] = LogEntry{Path: "app.log", Fields: f}
+	}
+	return entries
+}
+
+func TestParseSQLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"empty", "", "usage:"},
+		{"select alone", "select", "usage:"},
+		{"from with two words", "select path from logs l", "FROM takes a single name"},
+		{"where without argument", "select path where", "WHERE without an argument"},
+		{"group by without argument", "select count(*) group by", "GROUP BY without an argument"},
+		{"limit not a number", "select path limit ten", `LIMIT wants a number, got "ten"`},
+		{"negative limit", "select path limit -1", `LIMIT wants a number, got "-1"`},
+		{"sum of star", "select sum(*)", "sum(*) is not supported"},
+		{"plain column not grouped", "select path, count(*)", "column path must be in GROUP BY"},
+		{"order by unknown column", "select path order by status", "ORDER BY status: not a selected column"},
+		{"order by number out of range", "select path order by 2", "ORDER BY 2: there are 1 columns"},
+		{"unterminated quote", `select path where message~"oops`, "unterminated quote"},
+		{"bad expression", "select duration_ms /", "duration_ms /: unexpected end of expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSQL(tt.query)
+			if err == nil {
+				t.Fatalf("ParseSQL(%q) succeeded, want an error", tt.query)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseSQL(%q) error = %q, want it to contain %q", tt.query, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLRun(t *testing.T) {
+	requests := sqlEntries(
+		map[string]any{"level": "error", "path": "/api", "status": 500.0, "duration_ms": 120.0},
+		map[string]any{"level": "info", "path": "/api", "status": 200.0, "duration_ms": 30.0},
+		map[string]any{"level": "error", "path": "/login", "status": 502.0, "duration_ms": 900.0},
+		map[string]any{"level": "info", "path": "/health", "status": 200.0, "duration_ms": 2.0},
+		map[string]any{"level": "error", "path": "/api", "status": 503.0, "duration_ms": 45.0},
+	)
+	tests := []struct {
+		name    string
+		query   string
+		entries []LogEntry
+		columns []string
+		rows    [][]string
+		scanned int
+	}{
+		{
+			name:    "plain columns keep entry order",
+			query:   "select path, status where level=error",
+			entries: requests,
+			columns: []string{"path", "status"},
+			rows:    [][]string{{"/api", "500"}, {"/login", "502"}, {"/api", "503"}},
+			scanned: 3,
+		},
+		{
+			name:    "select keyword optional",
+			query:   "path limit 1",
+			entries: requests,
+			columns: []string{"path"},
+			rows:    [][]string{{"/api"}},
+			scanned: 5,
+		},
+		{
+			name:    "from is ignored",
+			query:   "SELECT path FROM logs WHERE status>=502",
+			entries: requests,
+			columns: []string{"path"},
+			rows:    [][]string{{"/login"}, {"/api"}},
+			scanned: 2,
+		},
+		{
+			name:    "group by with two spaces",
+			query:   "select path, count(*) group  by path",
+			entries: requests,
+			columns: []string{"path", "count(*)"},
+			rows:    [][]string{{"/api", "3"}, {"/login", "1"}, {"/health", "1"}},
+			scanned: 5,
+		},
+		{
+			name:    "order  by with two spaces",
+			query:   "select path order  by path limit 2",
+			entries: requests,
+			columns: []string{"path"},
+			rows:    [][]string{{"/api"}, {"/api"}},
+			scanned: 5,
+		},
+		{
+			name:    "keywords inside unquoted values",
+			query:   "select path where path=/api/select group by path",
+			entries: sqlEntries(map[string]any{"path": "/api/select"}, map[string]any{"path": "/api"}),
+			columns: []string{"path"},
+			rows:    [][]string{{"/api/select"}},
+			scanned: 1,
+		},
+		{
+			name:    "column named like a keyword",
+			query:   "select from_host, count(*) as n group by from_host order by n desc",
+			entries: sqlEntries(map[string]any{"from_host": "a"}, map[string]any{"from_host": "b"}, map[string]any{"from_host": "b"}),
+			columns: []string{"from_host", "n"},
+			rows:    [][]string{{"b", "2"}, {"a", "1"}},
+			scanned: 3,
+		},
+		{
+			name:    "keyword inside a quoted value",
+			query:   `select count(*) where message~"order by"`,
+			entries: sqlEntries(map[string]any{"message": "sort order by date"}, map[string]any{"message": "other"}),
+			columns: []string{"count(*)"},
+			rows:    [][]string{{"1"}},
+			scanned: 1,
+		},
+		{
+			name:    "count over an empty buffer",
+			query:   "select count(*)",
+			entries: nil,
+			columns: []string{"count(*)"},
+			rows:    [][]string{{"0"}},
+		},
+		{
+			name:    "aggregates over no matching entries",
+			query:   "select count(*), sum(status), avg(status), max(status) where level=debug",
+			entries: requests,
+			columns: []string{"count(*)", "sum(status)", "avg(status)", "max(status)"},
+			rows:    [][]string{{"0", "0", "", ""}},
+		},
+		{
+			name:    "grouped count over an empty buffer",
+			query:   "select path, count(*) group by path",
+			entries: nil,
+			columns: []string{"path", "count(*)"},
+		},
+		{
+			name:    "order by alias",
+			query:   "select path, count(*) as errors where level=error group by path order by errors desc",
+			entries: requests,
+			columns: []string{"path", "errors"},
+			rows:    [][]string{{"/api", "2"}, {"/login", "1"}},
+			scanned: 3,
+		},
+		{
+			name:    "order by alias ascending then column number",
+			query:   "select path, count(*) as n group by path order by n asc, 1",
+			entries: requests,
+			columns: []string{"path", "n"},
+			rows:    [][]string{{"/health", "1"}, {"/login", "1"}, {"/api", "3"}},
+			scanned: 5,
+		},
+		{
+			name:    "group by alias",
+			query:   "select duration_ms / 1000 as seconds, count(*) group by seconds order by seconds",
+			entries: sqlEntries(map[string]any{"duration_ms": 500.0}, map[string]any{"duration_ms": 2000.0}, map[string]any{"duration_ms": 500.0}),
+			columns: []string{"seconds", "count(*)"},
+			rows:    [][]string{{"0.5", "2"}, {"2", "1"}},
+			scanned: 3,
+		},
+		{
+			name:    "numeric aggregates",
+			query:   "select sum(duration_ms), avg(duration_ms), min(status), max(status)",
+			entries: requests,
+			columns: []string{"sum(duration_ms)", "avg(duration_ms)", "min(status)", "max(status)"},
+			rows:    [][]string{{"1097", "219.4", "200", "503"}},
+			scanned: 5,
+		},
+		{
+			name:    "count of an expression skips missing fields",
+			query:   "select count(user), count(*)",
+			entries: sqlEntries(map[string]any{"user": "ann"}, map[string]any{}),
+			columns: []string{"count(user)", "count(*)"},
+			rows:    [][]string{{"1", "2"}},
+			scanned: 2,
+		},
+		{
+			name:    "numbers order numerically",
+			query:   "select status order by status desc limit 2",
+			entries: sqlEntries(map[string]any{"status": 99.0}, map[string]any{"status": 500.0}, map[string]any{"status": 1000.0}),
+			columns: []string{"status"},
+			rows:    [][]string{{"1000"}, {"500"}},
+			scanned: 3,
+		},
+		{
+			name:    "limit zero",
+			query:   "select path limit 0",
+			entries: requests,
+			columns: []string{"path"},
+			scanned: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseSQL(tt.query)
+			if err != nil {
+				t.Fatalf("ParseSQL(%q): %v", tt.query, err)
+			}
+			res := q.Run(tt.entries)
+			if !reflect.DeepEqual(res.Columns, tt.columns) {
+				t.Errorf("columns = %q, want %q", res.Columns, tt.columns)
+			}
+			if !reflect.DeepEqual(res.Rows, tt.rows) && len(res.Rows)+len(tt.rows) > 0 {
+				t.Errorf("rows = %q, want %q", res.Rows, tt.rows)
+			}
+			if res.Scanned != tt.scanned {
+				t.Errorf("scanned = %d, want %d", res.Scanned, tt.scanned)
+			}
+		})
+	}
+}
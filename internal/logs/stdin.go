@@ -0,0 +1,110 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// StdinPath is the file name that stands for standard input. Entries read
+// from it carry it as their Path, so parser profiles and source options
+// can match it like any file.
+const StdinPath = "-"
+
+// stdinRead is what the stdin reader hands over: the lines of one read, or
+// the error that ended reading.
+type stdinRead struct {
+	lines []rawLine
+	err   error
+}
+
+// readStdin delivers the lines of standard input until it is closed. The
+// blocking reads run in a goroutine of their own that is not waited for, as
+// a read on a terminal or pipe cannot be interrupted when ctx is canceled.
+func (t *Tailer) readStdin(ctx context.Context, entries chan LogEntry, errs chan<- error) {
+	source := resolveSource(t.source, t.sources, StdinPath)
+	parser := resolveParser(t.parser, t.profiles, StdinPath)
+	out := &sink{
+		ch:       entries,
+		policy:   source.Backpressure,
+		counters: t.counters(StdinPath),
+	}
+	rewriter := newLineRewriter(source)
+
+	r := t.stdin
+	if r == nil {
+		r = os.Stdin
+	}
+	reads := make(chan stdinRead)
+	go scanStdin(ctx, r, positiveOr(source.ReadChunkSize, defaultReadChunkSize), reads)
+
+	// Joined lines are held back until a poll interval passes without
+	// input, as the tailer does for files.
+	pollTicker := time.NewTicker(400 * time.Millisecond)
+	defer pollTicker.Stop()
+	idle := true
+	for {
+		// While paused, input is left unread and the writer blocks once
+		// the pipe is full.
+		next := reads
+		if t.isPaused(StdinPath) {
+			next = nil
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-pollTicker.C:
+			if idle {
+				t.emitLines(ctx, StdinPath, rewriter.process(nil, true), parser, out, errs)
+			}
+			idle = true
+		case read := <-next:
+			idle = false
+			if !t.emitLines(ctx, StdinPath, rewriter.process(read.lines, false), parser, out, errs) {
+				return
+			}
+			if read.err == nil {
+				continue
+			}
+			t.emitLines(ctx, StdinPath, rewriter.process(nil, true), parser, out, errs)
+			if !errors.Is(read.err, io.EOF) {
+				t.sourceFailed(errs, fmt.Errorf("read stdin: %w", read.err))
+			}
+			return
+		}
+	}
+}
+
+// scanStdin reads lines from r and sends them whenever the input is caught
+// up. The last read carries the error that ended reading.
+func scanStdin(ctx context.Context, r io.Reader, bufSize int, reads chan<- stdinRead) {
+	reader := bufio.NewReaderSize(r, bufSize)
+	var (
+		lines  []rawLine
+		offset int64
+	)
+	for {
+		text, err := reader.ReadString('\n')
+		if len(text) > 0 {
+			lines = append(lines, rawLine{text: strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r"), offset: offset})
+			offset += int64(len(text))
+		}
+		if err == nil && reader.Buffered() > 0 {
+			continue
+		}
+		select {
+		case reads <- stdinRead{lines: lines, err: err}:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+		lines = nil
+	}
+}
@@ -1,7 +1,6 @@
 package logs
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
@@ -9,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -16,252 +16,353 @@ import (
 
 // Tailer streams log entries from a set of files.
 type Tailer struct {
-	files  []string
-	parser ParserConfig
+	files    []string
+	parser   ParserConfig
+	profiles []ParserProfile
+	source   SourceOptions
+	sources  []SourceOptions
 
-	tailLines int
+	tailLines    int
+	once         bool
+	maxEntrySize int
+	transform    TransformFunc
+	stdin        io.Reader
+	entryBuffer  int
+	errorBuffer  int
+
+	mu sync.Mutex
+	// active maps the files being tailed to the functions stopping them.
+	active map[string]context.CancelFunc
+	paused map[string]bool
+	stats  map[string]*sourceCounters
+	wg     sync.WaitGroup
+	events chan SourceEvent
+	hub    *watchHub
+
+	failures atomic.Int64
 }
 
 // Options configures the behavior of a Tailer.
 type Options struct {
 	Parser    ParserConfig
+	Profiles  []ParserProfile
 	TailLines int
+	// Backpressure is the default policy applied when the entries channel
+	// is full; Sources may override it per file.
+	Backpressure BackpressurePolicy
+	Sources      []SourceOptions
+	// EntryBuffer and ErrorBuffer size the channels returned by Start.
+	EntryBuffer int
+	ErrorBuffer int
+	// ReadChunkSize is the default read buffer size per file.
+	ReadChunkSize int
+	// MaxEntrySize caps the raw bytes kept per entry. Longer lines are
+	// truncated in memory and re-read from the file on demand.
+	MaxEntrySize int
+	// Once reads the current contents of the files and stops instead of
+	// following them.
+	Once bool
+	// Transform, if set, runs on every parsed entry before it is
+	// delivered and may rewrite or drop it.
+	Transform TransformFunc
+	// Stdin is read for the file "-"; it defaults to os.Stdin.
+	Stdin io.Reader
+	// SkipExisting starts at the end of the files present at startup, so
+	// only what is appended to them is delivered. TailLines is ignored.
+	SkipExisting bool
 }
 
+const (
+	defaultEntryBuffer   = 256
+	defaultErrorBuffer   = 64
+	defaultReadChunkSize = 64 * 1024
+)
+
 // NewTailer constructs a Tailer for the provided file paths.
 func NewTailer(files []string, opts Options) *Tailer {
 	return &Tailer{
-		files:     append([]string(nil), files...),
-		parser:    opts.Parser,
-		tailLines: opts.TailLines,
+		files:        append([]string(nil), files...),
+		parser:       opts.Parser,
+		profiles:     append([]ParserProfile(nil), opts.Profiles...),
+		source:       SourceOptions{Backpressure: opts.Backpressure, ReadChunkSize: opts.ReadChunkSize},
+		sources:      append([]SourceOptions(nil), opts.Sources...),
+		tailLines:    initialTail(opts),
+		once:         opts.Once,
+		maxEntrySize: opts.MaxEntrySize,
+		transform:    opts.Transform,
+		stdin:        opts.Stdin,
+		entryBuffer:  positiveOr(opts.EntryBuffer, defaultEntryBuffer),
+		errorBuffer:  positiveOr(opts.ErrorBuffer, defaultErrorBuffer),
+		active:       make(map[string]context.CancelFunc),
+		paused:       make(map[string]bool),
+		stats:        make(map[string]*sourceCounters),
+		events:       make(chan SourceEvent, 64),
 	}
 }
 
-// Start begins streaming log entries until the context is canceled.
+// initialTail returns the tailLines of a Tailer: how many lines to read from
+// the end of files present at startup, 0 for all of them and -1 for none.
+func initialTail(opts Options) int {
+	if opts.SkipExisting {
+		return -1
+	}
+	return opts.TailLines
+}
+
+// Events reports files that started being tailed after startup, e.g. new
+// files matching a glob pattern.
+func (t *Tailer) Events() <-chan SourceEvent {
+	return t.events
+}
+
+// Start begins streaming log entries until the context is canceled, or
+// until the files are read when Once is set.
 func (t *Tailer) Start(ctx context.Context) (<-chan LogEntry, <-chan error) {
-	entries := make(chan LogEntry, 256)
-	errs := make(chan error, 64)
+	entries := make(chan LogEntry, t.entryBuffer)
+	errs := make(chan error, t.errorBuffer)
+
+	if !t.once {
+		t.startWatchHub(ctx, errs)
+	}
 
-	var wg sync.WaitGroup
 	for _, path := range t.files {
-		path := path
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			t.tailFile(ctx, path, entries, errs)
-		}()
+		if path == StdinPath {
+			t.wg.Add(1)
+			go func() {
+				defer t.wg.Done()
+				t.readStdin(ctx, entries, errs)
+			}()
+			continue
+		}
+		if isGlobPattern(path) {
+			t.watchPattern(ctx, path, entries, errs)
+			continue
+		}
+		t.startFile(ctx, path, t.tailLines, entries, errs)
 	}
 
 	go func() {
-		wg.Wait()
+		t.wg.Wait()
 		close(entries)
 		close(errs)
+		close(t.events)
 	}()
 
 	return entries, errs
 }
 
-type fileState struct {
-	offset  int64
-	pending string
+// startWatchHub starts the shared filesystem watcher. Without it, files are
+// followed by polling alone.
+func (t *Tailer) startWatchHub(ctx context.Context, errs chan<- error) {
+	hub, err := newWatchHub()
+	if err != nil {
+		errs <- fmt.Errorf("fsnotify: %w", err)
+		return
+	}
+	t.hub = hub
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		hub.run(ctx, errs)
+	}()
 }
 
-func (t *Tailer) tailFile(ctx context.Context, path string, entries chan<- LogEntry, errs chan<- error) {
-	state := &fileState{}
-	pollTicker := time.NewTicker(400 * time.Millisecond)
-	defer pollTicker.Stop()
+// startFile begins tailing path unless it is already being tailed. It
+// reports whether a new tail goroutine was started.
+func (t *Tailer) startFile(ctx context.Context, path string, tailLines int, entries chan LogEntry, errs chan<- error) bool {
+	path = filepath.Clean(path)
+	t.mu.Lock()
+	if _, ok := t.active[path]; ok {
+		t.mu.Unlock()
+		return false
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	t.active[path] = cancel
+	t.mu.Unlock()
 
-	var watcher *fsnotify.Watcher
-	dir := filepath.Dir(path)
-	if w, err := fsnotify.NewWatcher(); err == nil {
-		if err := w.Add(dir); err != nil {
-			errs <- fmt.Errorf("watch %s: %w", dir, err)
-			_ = w.Close()
-		} else {
-			watcher = w
-			defer watcher.Close()
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer cancel()
+		if isCompressed(path) {
+			t.readCompressed(ctx, path, tailLines, entries, errs)
+			return
 		}
-	} else {
-		errs <- fmt.Errorf("fsnotify: %w", err)
+		t.tailFile(ctx, path, tailLines, entries, errs)
+	}()
+	return true
+}
+
+// stopFile stops tailing path and forgets its counters, so that a file of
+// the same name created later is tailed afresh.
+func (t *Tailer) stopFile(path string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cancel, ok := t.active[path]; ok {
+		cancel()
+		delete(t.active, path)
+		delete(t.stats, path)
+		delete(t.paused, path)
 	}
+}
 
-	t.emitInitial(ctx, path, state, entries, errs)
+func (t *Tailer) tailFile(ctx context.Context, path string, tailLines int, entries chan LogEntry, errs chan<- error) {
+	source := resolveSource(t.source, t.sources, path)
+	state := &fileState{chunkSize: source.ReadChunkSize}
+	defer state.close()
+	parser := resolveParser(t.parser, t.profiles, path)
+	out := &sink{
+		ch:       entries,
+		policy:   source.Backpressure,
+		counters: t.counters(path),
+	}
+	rewriter := newLineRewriter(source)
+	pollTicker := time.NewTicker(400 * time.Millisecond)
+	defer pollTicker.Stop()
+
+	events, unsubscribe := t.watchDir(filepath.Dir(path), errs)
+	defer unsubscribe()
+	// A symlinked file is written in its target's directory, so that one
+	// is watched too, following the link when it is pointed elsewhere.
+	target := newLinkTarget(t, path, errs)
+	defer target.close()
+
+	t.emitInitial(ctx, path, tailLines, parser, state, rewriter, out, errs)
+	if t.once {
+		return
+	}
 
 	readNewData := func() {
+		if t.isPaused(path) {
+			return
+		}
 		lines, err := state.readNewLines(path)
 		if err != nil {
 			if errors.Is(err, os.ErrNotExist) {
 				return
 			}
-			errs <- fmt.Errorf("tail %s: %w", path, err)
+			t.sourceFailed(errs, fmt.Errorf("tail %s: %w", path, err))
 			return
 		}
-		for _, line := range lines {
-			if line == "" {
-				continue
-			}
-			entry, err := parseEntry(path, line, t.parser)
-			if err != nil {
-				errs <- err
-				continue
-			}
-			select {
-			case <-ctx.Done():
-				return
-			case entries <- entry:
-			}
-		}
+		out.counters.offset.Store(state.offset)
+		t.emitLines(ctx, path, rewriter.process(lines, len(lines) == 0), parser, out, errs)
+		target.update()
 	}
 
 	for {
-		if watcher == nil {
-			select {
-			case <-ctx.Done():
-				return
-			case <-pollTicker.C:
-				readNewData()
-			case <-time.After(150 * time.Millisecond):
-			}
-			continue
-		}
 		select {
 		case <-ctx.Done():
 			return
 		case <-pollTicker.C:
 			readNewData()
-		case event, ok := <-watcher.Events:
-			if !ok {
-				watcher = nil
-				continue
-			}
+		case event := <-events:
 			if eventHasPath(event, path) {
-				switch {
-				case event.Op&fsnotify.Write == fsnotify.Write:
-					readNewData()
-				case event.Op&(fsnotify.Remove|fsnotify.Rename|fsnotify.Create) != 0:
-					state.reset()
-					waitForReappear(ctx, path)
-					readNewData()
-				}
+				// Rotation and truncation are detected while reading, so
+				// every event on the path just triggers a read.
+				readNewData()
 			}
-		case err, ok := <-watcher.Errors:
-			if !ok {
-				continue
+		case event := <-target.events:
+			if eventHasPath(event, target.path) {
+				readNewData()
 			}
-			errs <- fmt.Errorf("watcher error: %w", err)
 		}
 	}
 }
 
-func (t *Tailer) emitInitial(ctx context.Context, path string, state *fileState, entries chan<- LogEntry, errs chan<- error) {
-	lines, err := state.readAll(path)
+// SetPaused stops or resumes reading path. A paused file is left unread,
+// so resuming delivers what was written meanwhile.
+func (t *Tailer) SetPaused(path string, paused bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if paused {
+		t.paused[filepath.Clean(path)] = true
+	} else {
+		delete(t.paused, filepath.Clean(path))
+	}
+}
+
+func (t *Tailer) isPaused(path string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused[path]
+}
+
+// watchDir subscribes to filesystem events of dir. Without a usable watcher
+// the returned channel is nil and callers rely on polling alone.
+func (t *Tailer) watchDir(dir string, errs chan<- error) (<-chan fsnotify.Event, func()) {
+	if t.hub == nil {
+		return nil, func() {}
+	}
+	events, unsubscribe, err := t.hub.subscribe(dir)
+	if err != nil {
+		errs <- err
+		return nil, func() {}
+	}
+	return events, unsubscribe
+}
+
+func (t *Tailer) emitInitial(ctx context.Context, path string, tailLines int, parser ParserConfig, state *fileState, rewriter *lineRewriter, out *sink, errs chan<- error) {
+	var (
+		lines []rawLine
+		err   error
+	)
+	switch {
+	case tailLines < 0:
+		err = state.skipToEnd(path)
+	case tailLines > 0:
+		lines, err = state.readTail(path, tailLines)
+	default:
+		lines, err = state.readAll(path)
+	}
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return
 		}
-		errs <- fmt.Errorf("initial read %s: %w", path, err)
+		t.sourceFailed(errs, fmt.Errorf("initial read %s: %w", path, err))
 		return
 	}
+	out.counters.offset.Store(state.offset)
+	// Reading once, nothing can follow the last line.
+	t.emitLines(ctx, path, rewriter.process(lines, t.once), parser, out, errs)
+}
 
-	if t.tailLines > 0 && len(lines) > t.tailLines {
-		lines = lines[len(lines)-t.tailLines:]
+// emitLines parses lines and delivers the resulting entries. It reports
+// false once ctx is canceled.
+func (t *Tailer) emitLines(ctx context.Context, path string, lines []rawLine, parser ParserConfig, out *sink, errs chan<- error) bool {
+	if len(lines) == 0 {
+		return true
 	}
-
+	offset := resolveSource(t.source, t.sources, path).TimeOffset
 	for _, line := range lines {
-		if line == "" {
+		if line.text == "" {
 			continue
 		}
-		entry, err := parseEntry(path, line, t.parser)
+		entry, err := parseEntry(path, line.text, parser)
 		if err != nil {
+			out.counters.parseErrors.Add(1)
 			errs <- err
 			continue
 		}
-		select {
-		case <-ctx.Done():
-			return
-		case entries <- entry:
+		entry.Offset = line.offset
+		if offset != 0 && !entry.Timestamp.IsZero() {
+			entry.Timestamp = entry.Timestamp.Add(offset)
 		}
-	}
-}
-
-func (s *fileState) readAll(path string) ([]string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-	if info, err := file.Stat(); err == nil {
-		s.offset = info.Size()
-	}
-	s.pending = ""
-	return lines, nil
-}
-
-func (s *fileState) readNewLines(path string) ([]string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	info, err := file.Stat()
-	if err != nil {
-		return nil, err
-	}
-
-	if info.Size() < s.offset {
-		s.offset = 0
-		s.pending = ""
-	}
-
-	if _, err := file.Seek(s.offset, io.SeekStart); err != nil {
-		return nil, err
-	}
-
-	var lines []string
-	reader := bufio.NewReader(file)
-	for {
-		chunk, err := reader.ReadString('\n')
-		if err != nil && !errors.Is(err, io.EOF) {
-			return lines, err
-		}
-		if len(chunk) > 0 {
-			s.offset += int64(len(chunk))
-			s.pending += chunk
-			for {
-				idx := indexOfNewline(s.pending)
-				if idx == -1 {
-					break
-				}
-				segment := s.pending[:idx]
-				if len(segment) > 0 && segment[len(segment)-1] == '\r' {
-					segment = segment[:len(segment)-1]
-				}
-				lines = append(lines, segment)
-				s.pending = s.pending[idx+1:]
+		if t.transform != nil {
+			var keep bool
+			if entry, keep = t.transform(entry); !keep {
+				continue
 			}
 		}
-		if errors.Is(err, io.EOF) {
-			break
+		// Lines of stdin and compressed files cannot be read again, so
+		// they are kept whole.
+		if t.maxEntrySize > 0 && path != StdinPath && !isCompressed(path) {
+			entry = entry.truncate(t.maxEntrySize, line.original(), line.span())
+		}
+		if !out.send(ctx, entry) {
+			return false
 		}
 	}
-
-	return lines, nil
-}
-
-func (s *fileState) reset() {
-	s.offset = 0
-	s.pending = ""
+	return true
 }
 
 func eventHasPath(event fsnotify.Event, path string) bool {
@@ -274,21 +375,6 @@ func eventHasPath(event fsnotify.Event, path string) bool {
 	return false
 }
 
-func waitForReappear(ctx context.Context, path string) {
-	t := time.NewTicker(500 * time.Millisecond)
-	defer t.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-t.C:
-			if _, err := os.Stat(path); err == nil {
-				return
-			}
-		}
-	}
-}
-
 func indexOfNewline(s string) int {
 	for i := 0; i < len(s); i++ {
 		if s[i] == '\n' {
@@ -297,3 +383,59 @@ func indexOfNewline(s string) int {
 	}
 	return -1
 }
+
+func positiveOr(v, fallback int) int {
+	if v > 0 {
+		return v
+	}
+	return fallback
+}
+
+// sourceFailed reports an I/O failure of a source, as opposed to a line
+// that merely failed to parse.
+func (t *Tailer) sourceFailed(errs chan<- error, err error) {
+	t.failures.Add(1)
+	errs <- err
+}
+
+// Failures returns how many source I/O errors were reported so far.
+func (t *Tailer) Failures() int64 {
+	return t.failures.Load()
+}
+
+// linkTarget watches the directory of the file a symlink points to.
+type linkTarget struct {
+	t           *Tailer
+	link        string
+	errs        chan<- error
+	path        string
+	events      <-chan fsnotify.Event
+	unsubscribe func()
+}
+
+func newLinkTarget(t *Tailer, link string, errs chan<- error) *linkTarget {
+	l := &linkTarget{t: t, link: link, errs: errs}
+	l.update()
+	return l
+}
+
+// update re-resolves the link and moves the watch when the target's
+// directory changed. Paths that are not symlinks are not watched twice.
+func (l *linkTarget) update() {
+	resolved, err := filepath.EvalSymlinks(l.link)
+	if err != nil || resolved == l.path {
+		return
+	}
+	l.close()
+	l.path = resolved
+	if filepath.Dir(resolved) != filepath.Dir(l.link) {
+		l.events, l.unsubscribe = l.t.watchDir(filepath.Dir(resolved), l.errs)
+	}
+}
+
+func (l *linkTarget) close() {
+	if l.unsubscribe != nil {
+		l.unsubscribe()
+	}
+	l.events, l.unsubscribe = nil, nil
+}
@@ -0,0 +1,73 @@
+package logs
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize splits lower-cased text into its runs of letters and digits, the
+// words search indexes map to entries.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool { return !isWordRune(r) })
+}
+
+// QueryToken is a token of a searched word. Only the ends of the word may
+// be parts of longer words in the text: PrefixOpen if the word starts with
+// this token and a letter or digit, SuffixOpen likewise at its end.
+type QueryToken struct {
+	Text       string
+	PrefixOpen bool
+	SuffixOpen bool
+}
+
+// QueryTokens splits a lower-cased searched word into tokens. A word
+// without letters or digits has none, and an index cannot tell which
+// entries contain it.
+func QueryTokens(word string) []QueryToken {
+	tokens := Tokenize(word)
+	startOpen := isWordRune(firstRune(word))
+	endOpen := isWordRune(lastRune(word))
+	out := make([]QueryToken, len(tokens))
+	for i, token := range tokens {
+		out[i] = QueryToken{
+			Text:       token,
+			PrefixOpen: i == 0 && startOpen,
+			SuffixOpen: i == len(tokens)-1 && endOpen,
+		}
+	}
+	return out
+}
+
+// Matches reports whether t can be vocab of the text: inside it if the
+// word may continue on both sides, at its end or start if on one, and all
+// of it otherwise.
+func (t QueryToken) Matches(vocab string) bool {
+	switch {
+	case t.PrefixOpen && t.SuffixOpen:
+		return strings.Contains(vocab, t.Text)
+	case t.PrefixOpen:
+		return strings.HasSuffix(vocab, t.Text)
+	case t.SuffixOpen:
+		return strings.HasPrefix(vocab, t.Text)
+	}
+	return vocab == t.Text
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
+}
+
+func lastRune(s string) rune {
+	r := []rune(s)
+	if len(r) == 0 {
+		return 0
+	}
+	return r[len(r)-1]
+}
@@ -0,0 +1,32 @@
+package logs
+
+// TransformFunc rewrites an entry after it is parsed. Returning false drops
+// the entry; it is then neither delivered nor counted as delivered.
+type TransformFunc func(LogEntry) (LogEntry, bool)
+
+// ChainTransforms applies transforms in order, stopping at the first that
+// drops the entry. Nil transforms are skipped; it returns nil when none is
+// left.
+func ChainTransforms(transforms ...TransformFunc) TransformFunc {
+	var fns []TransformFunc
+	for _, fn := range transforms {
+		if fn != nil {
+			fns = append(fns, fn)
+		}
+	}
+	switch len(fns) {
+	case 0:
+		return nil
+	case 1:
+		return fns[0]
+	}
+	return func(entry LogEntry) (LogEntry, bool) {
+		for _, fn := range fns {
+			var ok bool
+			if entry, ok = fn(entry); !ok {
+				return entry, false
+			}
+		}
+		return entry, true
+	}
+}
@@ -0,0 +1,101 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchHub multiplexes a single fsnotify watcher between all tailed files.
+// Each directory is watched once no matter how many files in it are tailed,
+// which keeps the tailer far below inotify instance and watch limits when
+// following many files of the same directory (e.g. /var/log/containers).
+type watchHub struct {
+	watcher *fsnotify.Watcher
+
+	mu   sync.Mutex
+	dirs map[string]map[chan fsnotify.Event]struct{}
+}
+
+func newWatchHub() (*watchHub, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &watchHub{
+		watcher: w,
+		dirs:    make(map[string]map[chan fsnotify.Event]struct{}),
+	}, nil
+}
+
+// run dispatches events to subscribers until ctx is canceled.
+func (h *watchHub) run(ctx context.Context, errs chan<- error) {
+	defer h.watcher.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-h.watcher.Events:
+			if !ok {
+				return
+			}
+			h.dispatch(event)
+		case err, ok := <-h.watcher.Errors:
+			if !ok {
+				return
+			}
+			select {
+			case errs <- fmt.Errorf("watcher error: %w", err):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (h *watchHub) dispatch(event fsnotify.Event) {
+	dir := filepath.Dir(filepath.Clean(event.Name))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.dirs[dir] {
+		// Events only trigger reads and periodic polling covers anything
+		// missed, so a slow subscriber never blocks the others.
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// subscribe returns a channel receiving events for entries of dir and a
+// function that cancels the subscription.
+func (h *watchHub) subscribe(dir string) (<-chan fsnotify.Event, func(), error) {
+	dir = filepath.Clean(dir)
+	ch := make(chan fsnotify.Event, 16)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subs, ok := h.dirs[dir]
+	if !ok {
+		if err := h.watcher.Add(dir); err != nil {
+			return nil, nil, fmt.Errorf("watch %s: %w", dir, err)
+		}
+		subs = make(map[chan fsnotify.Event]struct{})
+		h.dirs[dir] = subs
+	}
+	subs[ch] = struct{}{}
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(subs, ch)
+		if len(subs) == 0 {
+			delete(h.dirs, dir)
+			_ = h.watcher.Remove(dir)
+		}
+	}
+	return ch, unsubscribe, nil
+}
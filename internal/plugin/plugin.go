@@ -0,0 +1,185 @@
+// Package plugin runs external processors that exchange entries with
+// logsviewer as NDJSON over stdin and stdout.
+//
+// A transform processor receives every parsed entry as one export.Record
+// line and answers with one line per entry: a record that replaces the
+// entry's timestamp, message, extras and fields, or null to drop it. A
+// source processor only writes; each line it prints becomes an entry,
+// either a record or a log line parsed like the tailed files.
+//
+// Processors run through sh -c and are restarted with a growing delay when
+// they exit or stop answering. While a transform processor is down,
+// entries pass through unchanged.
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// Kind is what a processor does in the pipeline.
+type Kind string
+
+const (
+	KindTransform Kind = "transform"
+	KindSource    Kind = "source"
+)
+
+// Spec declares a processor.
+type Spec struct {
+	Name    string
+	Command string
+	Kind    Kind
+}
+
+const (
+	// minBackoff and maxBackoff bound the delay before a failed processor
+	// is started again; the delay doubles with every failure in a row.
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+	// healthyAfter is how long a processor must run for a failure not to
+	// count as one in a row.
+	healthyAfter = time.Minute
+	// replyTimeout is how long a transform processor may take per entry.
+	replyTimeout = 5 * time.Second
+)
+
+// Host runs the processors of a session.
+type Host struct {
+	ctx        context.Context
+	errs       logs.ErrorSink
+	transforms []*transformer
+	sources    []Spec
+}
+
+// NewHost prepares the processors in specs. They are started lazily, on
+// the first entry or the call to Sources, and stopped when ctx is done.
+func NewHost(ctx context.Context, specs []Spec) *Host {
+	h := &Host{ctx: ctx, errs: logs.NewErrorSink()}
+	for _, spec := range specs {
+		switch spec.Kind {
+		case KindSource:
+			h.sources = append(h.sources, spec)
+		default:
+			h.transforms = append(h.transforms, &transformer{host: h, spec: spec})
+		}
+	}
+	return h
+}
+
+// Transform returns the transform processors as one hook for
+// logs.Options.Transform, or nil if there are none.
+func (h *Host) Transform() logs.TransformFunc {
+	fns := make([]logs.TransformFunc, len(h.transforms))
+	for i, t := range h.transforms {
+		fns[i] = t.transform
+	}
+	return logs.ChainTransforms(fns...)
+}
+
+// Errors reports processor failures and what they write to stderr. It is
+// never closed.
+func (h *Host) Errors() <-chan error {
+	return h.errs
+}
+
+// report passes on an error of the named processor.
+func (h *Host) report(name string, err error) {
+	h.errs.Report(fmt.Errorf("processor %s: %w", name, err))
+}
+
+// backoff tracks the restart delay of a processor.
+type backoff struct {
+	delay   time.Duration
+	started time.Time
+	next    time.Time
+}
+
+// failed schedules the next start after a failure.
+func (b *backoff) failed() {
+	if time.Since(b.started) >= healthyAfter || b.delay == 0 {
+		b.delay = minBackoff
+	} else {
+		b.delay = min(2*b.delay, maxBackoff)
+	}
+	b.next = time.Now().Add(b.delay)
+}
+
+// process is a running processor.
+type process struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan string // stdout lines, closed at EOF
+	// stderrDone is closed once stderr is read to the end.
+	stderrDone chan struct{}
+}
+
+func (h *Host) start(spec Spec, withStdin bool) (*process, error) {
+	cmd := exec.CommandContext(h.ctx, "sh", "-c", spec.Command)
+	p := &process{cmd: cmd, lines: make(chan string), stderrDone: make(chan struct{})}
+	if withStdin {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		p.stdin = stdin
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go func() {
+		defer close(p.lines)
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			select {
+			case p.lines <- scanner.Text():
+			case <-h.ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		defer close(p.stderrDone)
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				h.report(spec.Name, fmt.Errorf("%s", line))
+			}
+		}
+	}()
+	return p, nil
+}
+
+// stop kills the process and waits for it.
+func (p *process) stop() error {
+	if p.stdin != nil {
+		p.stdin.Close()
+	}
+	p.cmd.Process.Kill()
+	return p.wait()
+}
+
+// wait returns why the process exited once its output is read; pipes must
+// not be read after Wait.
+func (p *process) wait() error {
+	for range p.lines {
+	}
+	<-p.stderrDone
+	return p.cmd.Wait()
+}
@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcuzy/logsviewer/internal/export"
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// Sources starts the source processors and returns their entries, or nil
+// if there are none. Lines that are not records are parsed with parser
+// and profiles as lines of the path "plugin:<name>". The channel is closed
+// once ctx is done.
+func (h *Host) Sources(parser logs.ParserConfig, profiles []logs.ParserProfile) <-chan logs.LogEntry {
+	if len(h.sources) == 0 {
+		return nil
+	}
+	out := make(chan logs.LogEntry, 256)
+	var wg sync.WaitGroup
+	for _, spec := range h.sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.runSource(spec, parser, profiles, out)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// runSource runs a source processor until ctx is done, restarting it
+// whenever it exits.
+func (h *Host) runSource(spec Spec, parser logs.ParserConfig, profiles []logs.ParserProfile, out chan<- logs.LogEntry) {
+	path := "plugin:" + spec.Name
+	var b backoff
+	for h.ctx.Err() == nil {
+		b.started = time.Now()
+		p, err := h.start(spec, false)
+		if err == nil {
+			for line := range p.lines {
+				if strings.TrimSpace(line) == "" {
+					continue
+				}
+				entry, ok := export.ParseRecord(line)
+				if !ok {
+					if entry, err = logs.ParseLine(path, line, parser, profiles); err != nil {
+						h.report(spec.Name, err)
+						continue
+					}
+				}
+				select {
+				case out <- entry:
+				case <-h.ctx.Done():
+				}
+			}
+			err = p.wait()
+			if err == nil {
+				err = errors.New("exited")
+			}
+		}
+		if h.ctx.Err() != nil {
+			return
+		}
+		b.failed()
+		h.report(spec.Name, fmt.Errorf("%w, restarting in %s", err, b.delay))
+		select {
+		case <-time.After(b.delay):
+		case <-h.ctx.Done():
+		}
+	}
+}
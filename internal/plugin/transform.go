@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcuzy/logsviewer/internal/export"
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// transformer exchanges entries with a transform processor, one at a time
+// as every file of the tailer calls it.
+type transformer struct {
+	host *Host
+	spec Spec
+
+	mu      sync.Mutex
+	proc    *process
+	backoff backoff
+}
+
+func (t *transformer) transform(entry logs.LogEntry) (logs.LogEntry, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.running()
+	if p == nil {
+		return entry, true
+	}
+	line, err := json.Marshal(export.NewRecord(entry))
+	if err != nil {
+		t.host.report(t.spec.Name, err)
+		return entry, true
+	}
+	if _, err := p.stdin.Write(append(line, '\n')); err != nil {
+		t.fail(err)
+		return entry, true
+	}
+	timer := time.NewTimer(replyTimeout)
+	defer timer.Stop()
+	select {
+	case reply, ok := <-p.lines:
+		if !ok {
+			t.fail(errors.New("closed its output"))
+			return entry, true
+		}
+		return t.apply(entry, reply)
+	case <-timer.C:
+		t.fail(fmt.Errorf("no reply within %s", replyTimeout))
+		return entry, true
+	case <-t.host.ctx.Done():
+		return entry, true
+	}
+}
+
+// running returns the process, starting it if it is not running and its
+// restart delay has passed, or nil.
+func (t *transformer) running() *process {
+	if t.proc != nil {
+		return t.proc
+	}
+	if time.Now().Before(t.backoff.next) || t.host.ctx.Err() != nil {
+		return nil
+	}
+	p, err := t.host.start(t.spec, true)
+	if err != nil {
+		t.host.report(t.spec.Name, err)
+		t.backoff.failed()
+		return nil
+	}
+	t.proc = p
+	t.backoff.started = time.Now()
+	return p
+}
+
+// fail stops the process after an I/O failure and schedules its restart.
+func (t *transformer) fail(err error) {
+	exitErr := t.proc.stop()
+	t.proc = nil
+	t.backoff.failed()
+	if exitErr != nil {
+		err = fmt.Errorf("%w (%v)", err, exitErr)
+	}
+	t.host.report(t.spec.Name, fmt.Errorf("%w, restarting in %s", err, t.backoff.delay))
+}
+
+// apply merges a reply into entry.
+func (t *transformer) apply(entry logs.LogEntry, reply string) (logs.LogEntry, bool) {
+	reply = strings.TrimSpace(reply)
+	if reply == "null" {
+		return entry, false
+	}
+	var rec export.Record
+	if err := json.Unmarshal([]byte(reply), &rec); err != nil {
+		t.host.report(t.spec.Name, fmt.Errorf("invalid reply: %w", err))
+		return entry, true
+	}
+	return rec.Apply(entry, nil), true
+}
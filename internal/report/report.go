@@ -0,0 +1,197 @@
+// Package report summarizes a set of log entries for sharing.
+package report
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// topN is how many message templates and error sources are listed.
+const topN = 10
+
+// Summary aggregates a set of entries.
+type Summary struct {
+	Entries  int
+	From, To time.Time
+	Levels   []Count
+	// Templates are messages with variable parts replaced, most frequent
+	// first.
+	Templates []Count
+	// ErrorSources are the files with the most error entries.
+	ErrorSources []Count
+	Sources      []SourceRate
+}
+
+// Count is a value with its number of occurrences.
+type Count struct {
+	Value string
+	N     int
+}
+
+// SourceRate is the volume of one source over the summarized time range.
+type SourceRate struct {
+	Path      string
+	Entries   int
+	PerMinute float64
+}
+
+// Build summarizes entries.
+func Build(entries []logs.LogEntry) Summary {
+	s := Summary{Entries: len(entries)}
+	levels := make(map[string]int)
+	templates := make(map[string]int)
+	errorSources := make(map[string]int)
+	sources := make(map[string]int)
+	for _, entry := range entries {
+		if ts := entry.Timestamp; !ts.IsZero() {
+			if s.From.IsZero() || ts.Before(s.From) {
+				s.From = ts
+			}
+			if ts.After(s.To) {
+				s.To = ts
+			}
+		}
+		level := entry.Level()
+		if level == "" {
+			level = "(none)"
+		}
+		levels[level]++
+		if logs.IsErrorLevel(level) {
+			errorSources[entry.Path]++
+		}
+		message := entry.Message
+		if message == "" {
+			message = entry.RawLine()
+		}
+		templates[Template(message)]++
+		sources[entry.Path]++
+	}
+
+	s.Levels = sortedCounts(levels, 0)
+	s.Templates = sortedCounts(templates, topN)
+	s.ErrorSources = sortedCounts(errorSources, topN)
+	minutes := s.To.Sub(s.From).Minutes()
+	for _, c := range sortedCounts(sources, 0) {
+		rate := SourceRate{Path: c.Value, Entries: c.N}
+		if minutes > 0 {
+			rate.PerMinute = float64(c.N) / minutes
+		}
+		s.Sources = append(s.Sources, rate)
+	}
+	return s
+}
+
+func sortedCounts(m map[string]int, limit int) []Count {
+	out := make([]Count, 0, len(m))
+	for v, n := range m {
+		out = append(out, Count{Value: v, N: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].N != out[j].N {
+			return out[i].N > out[j].N
+		}
+		return out[i].Value < out[j].Value
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+var (
+	uuidPattern   = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+	hexPattern    = regexp.MustCompile(`\b(?:0x)?[0-9a-fA-F]{8,}\b`)
+	numberPattern = regexp.MustCompile(`\b\d+(?:\.\d+)?`)
+	quotedPattern = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+)
+
+// Template replaces the variable parts of a message, such as numbers, ids
+// and quoted values, so that messages differing only in those group
+// together.
+func Template(message string) string {
+	t := quotedPattern.ReplaceAllString(message, `"<s>"`)
+	t = uuidPattern.ReplaceAllString(t, "<uuid>")
+	t = hexPattern.ReplaceAllStringFunc(t, func(s string) string {
+		// Long all-digit numbers are left to numberPattern.
+		if strings.Trim(s, "0123456789") == "" {
+			return s
+		}
+		return "<hex>"
+	})
+	t = numberPattern.ReplaceAllString(t, "<n>")
+	if len(t) > 200 {
+		t = t[:200] + "…"
+	}
+	return t
+}
+
+// WriteMarkdown writes the summary as a Markdown document.
+func (s Summary) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Log summary\n\n")
+	fmt.Fprintf(&b, "- Entries: %d\n", s.Entries)
+	if !s.From.IsZero() {
+		fmt.Fprintf(&b, "- Time range: %s – %s (%s)\n", s.From.Format(time.RFC3339), s.To.Format(time.RFC3339), s.To.Sub(s.From).Round(time.Second))
+	}
+
+	b.WriteString("\n## Levels\n\n| Level | Entries |\n|---|---:|\n")
+	for _, c := range s.Levels {
+		fmt.Fprintf(&b, "| %s | %d |\n", markdownCell(c.Value), c.N)
+	}
+	b.WriteString("\n## Top messages\n\n| Count | Message |\n|---:|---|\n")
+	for _, c := range s.Templates {
+		fmt.Fprintf(&b, "| %d | `%s` |\n", c.N, strings.ReplaceAll(markdownCell(c.Value), "`", "'"))
+	}
+	if len(s.ErrorSources) > 0 {
+		b.WriteString("\n## Top error sources\n\n| Source | Errors |\n|---|---:|\n")
+		for _, c := range s.ErrorSources {
+			fmt.Fprintf(&b, "| %s | %d |\n", markdownCell(c.Value), c.N)
+		}
+	}
+	b.WriteString("\n## Sources\n\n| Source | Entries | Per minute |\n|---|---:|---:|\n")
+	for _, r := range s.Sources {
+		fmt.Fprintf(&b, "| %s | %d | %.1f |\n", markdownCell(r.Path), r.Entries, r.PerMinute)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteText writes the summary as plain text.
+func (s Summary) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Entries: %d\n", s.Entries)
+	if !s.From.IsZero() {
+		fmt.Fprintf(&b, "Time range: %s - %s (%s)\n", s.From.Format(time.RFC3339), s.To.Format(time.RFC3339), s.To.Sub(s.From).Round(time.Second))
+	}
+	b.WriteString("\nLevels:\n")
+	for _, c := range s.Levels {
+		fmt.Fprintf(&b, "  %-10s %d\n", c.Value, c.N)
+	}
+	b.WriteString("\nTop messages:\n")
+	for _, c := range s.Templates {
+		fmt.Fprintf(&b, "  %6d  %s\n", c.N, c.Value)
+	}
+	if len(s.ErrorSources) > 0 {
+		b.WriteString("\nTop error sources:\n")
+		for _, c := range s.ErrorSources {
+			fmt.Fprintf(&b, "  %6d  %s\n", c.N, c.Value)
+		}
+	}
+	b.WriteString("\nSources:\n")
+	for _, r := range s.Sources {
+		fmt.Fprintf(&b, "  %6d  %7.1f/min  %s\n", r.Entries, r.PerMinute, r.Path)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
@@ -0,0 +1,108 @@
+// Package script runs a Starlark transform over parsed entries.
+//
+// The script defines transform(entry), which receives the entry as a dict
+// in the export.Record schema, the one processors and --tee use. It may
+// change the dict in place and return None, return a new dict that
+// replaces the entry's timestamp, message, extras and fields, or return
+// False to drop the entry. The json module is available for fields that
+// hold encoded JSON.
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+
+	starjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+
+	"github.com/marcuzy/logsviewer/internal/export"
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// maxSteps bounds the work of one call, so that a script stuck in a loop
+// fails instead of stalling the tailer.
+const maxSteps = 1_000_000
+
+// Script is a loaded transform script.
+type Script struct {
+	path      string
+	transform starlark.Callable
+	// extraFields are refreshed from the changed fields unless the script
+	// sets them itself.
+	extraFields []string
+	errs        logs.ErrorSink
+}
+
+// Load runs the script at path and looks up its transform function.
+// extraFields are the configured extra fields of the entries.
+func Load(path string, extraFields []string) (*Script, error) {
+	opts := &syntax.FileOptions{Set: true, While: true, TopLevelControl: true}
+	thread := &starlark.Thread{Name: path}
+	predeclared := starlark.StringDict{"json": starjson.Module}
+	globals, err := starlark.ExecFileOptions(opts, thread, path, nil, predeclared)
+	if err != nil {
+		return nil, fmt.Errorf("script %s: %w", path, err)
+	}
+	fn, ok := globals["transform"].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("script %s: no transform(entry) function", path)
+	}
+	return &Script{path: path, transform: fn, extraFields: extraFields, errs: logs.NewErrorSink()}, nil
+}
+
+// Transform returns the hook that runs the script for every entry. An
+// entry the script fails on passes unchanged and the error is reported.
+func (s *Script) Transform() logs.TransformFunc {
+	return func(entry logs.LogEntry) (logs.LogEntry, bool) {
+		out, keep, err := s.run(entry)
+		if err != nil {
+			s.errs.Report(fmt.Errorf("script %s: %w", s.path, err))
+			return entry, true
+		}
+		return out, keep
+	}
+}
+
+// Errors reports script failures. It is never closed.
+func (s *Script) Errors() <-chan error {
+	return s.errs
+}
+
+func (s *Script) run(entry logs.LogEntry) (logs.LogEntry, bool, error) {
+	data, err := json.Marshal(export.NewRecord(entry))
+	if err != nil {
+		return entry, true, err
+	}
+	thread := &starlark.Thread{Name: s.path}
+	thread.SetMaxExecutionSteps(maxSteps)
+	call := func(fn starlark.Value, arg starlark.Value) (starlark.Value, error) {
+		return starlark.Call(thread, fn, starlark.Tuple{arg}, nil)
+	}
+	arg, err := call(starjson.Module.Members["decode"], starlark.String(data))
+	if err != nil {
+		return entry, true, err
+	}
+	result, err := call(s.transform, arg)
+	if err != nil {
+		return entry, true, err
+	}
+	switch result {
+	case starlark.False:
+		return entry, false, nil
+	case starlark.None:
+		result = arg
+	}
+	if _, ok := result.(*starlark.Dict); !ok {
+		return entry, true, fmt.Errorf("transform returned %s, not a dict", result.Type())
+	}
+	encoded, err := call(starjson.Module.Members["encode"], result)
+	if err != nil {
+		return entry, true, err
+	}
+	var rec export.Record
+	if err := json.Unmarshal([]byte(encoded.(starlark.String)), &rec); err != nil {
+		return entry, true, fmt.Errorf("transform result: %w", err)
+	}
+	return rec.Apply(entry, s.extraFields), true, nil
+}
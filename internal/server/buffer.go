@@ -0,0 +1,81 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// Buffer keeps the most recent entries and fans new ones out to
+// subscribers. It is safe for concurrent use.
+type Buffer struct {
+	mu      sync.Mutex
+	entries []logs.LogEntry
+	limit   int
+	subs    map[chan logs.LogEntry]struct{}
+}
+
+// subscriberBuffer is how many entries a slow subscriber may lag behind
+// before entries are skipped for it.
+const subscriberBuffer = 256
+
+// NewBuffer returns a buffer holding at most limit entries; zero or less
+// keeps every entry.
+func NewBuffer(limit int) *Buffer {
+	return &Buffer{limit: limit, subs: make(map[chan logs.LogEntry]struct{})}
+}
+
+// Add appends entry, evicting the oldest one when full, and publishes it to
+// subscribers without waiting for them.
+func (b *Buffer) Add(entry logs.LogEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries = append(b.entries, entry)
+	if b.limit > 0 && len(b.entries) >= 2*b.limit {
+		// Compact occasionally instead of shifting on every append.
+		n := copy(b.entries, b.entries[len(b.entries)-b.limit:])
+		clear(b.entries[n:])
+		b.entries = b.entries[:n]
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+}
+
+// Snapshot returns the buffered entries, oldest first.
+func (b *Buffer) Snapshot() []logs.LogEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entries := b.entries
+	if b.limit > 0 && len(entries) > b.limit {
+		entries = entries[len(entries)-b.limit:]
+	}
+	return append([]logs.LogEntry(nil), entries...)
+}
+
+// Len returns the number of buffered entries.
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.limit > 0 && len(b.entries) > b.limit {
+		return b.limit
+	}
+	return len(b.entries)
+}
+
+// Subscribe returns a channel receiving entries added from now on and a
+// function that cancels the subscription.
+func (b *Buffer) Subscribe() (<-chan logs.LogEntry, func()) {
+	ch := make(chan logs.LogEntry, subscriberBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
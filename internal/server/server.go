@@ -0,0 +1,160 @@
+// Package server exposes a buffer of log entries over HTTP.
+package server
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// defaultQueryLimit caps /api/entries responses without a limit parameter.
+const defaultQueryLimit = 500
+
+// Entry is the JSON representation of a log entry.
+type Entry struct {
+	Path          string            `json:"path"`
+	Timestamp     time.Time         `json:"timestamp,omitzero"`
+	TimestampText string            `json:"timestamp_text,omitempty"`
+	Message       string            `json:"message"`
+	Extras        map[string]string `json:"extras,omitempty"`
+	Raw           string            `json:"raw"`
+}
+
+func newEntry(e logs.LogEntry) Entry {
+	return Entry{
+		Path:          e.Path,
+		Timestamp:     e.Timestamp,
+		TimestampText: e.TimestampText,
+		Message:       e.Message,
+		Extras:        e.Extras,
+		Raw:           e.RawLine(),
+	}
+}
+
+// Server serves the REST API over a Buffer:
+//
+//	GET /api/entries?q=text&limit=N  matching entries, oldest first
+//	GET /api/stream?q=text           new matching entries as server-sent events
+//	GET /api/stats                   buffer size and per-source counters
+//
+// and optionally a web UI built on this API at /.
+type Server struct {
+	buffer *Buffer
+	stats  func() []logs.SourceStats
+	mux    *http.ServeMux
+}
+
+// Options configures a Server.
+type Options struct {
+	// Stats reports per-source counters for /api/stats. Optional.
+	Stats func() []logs.SourceStats
+	// Web serves the embedded web UI at /.
+	Web bool
+}
+
+// New returns a Server for buffer.
+func New(buffer *Buffer, opts Options) *Server {
+	s := &Server{buffer: buffer, stats: opts.Stats, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /api/entries", s.handleEntries)
+	s.mux.HandleFunc("GET /api/stream", s.handleStream)
+	s.mux.HandleFunc("GET /api/stats", s.handleStats)
+	if opts.Web {
+		web, _ := fs.Sub(webFiles, "web")
+		s.mux.Handle("GET /", http.FileServerFS(web))
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleEntries(w http.ResponseWriter, r *http.Request) {
+	query := logs.ParseSearch(r.URL.Query().Get("q"))
+	limit := defaultQueryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	// Walk backwards so that the newest matches are kept under the limit.
+	snapshot := s.buffer.Snapshot()
+	var matches []Entry
+	for i := len(snapshot) - 1; i >= 0 && len(matches) < limit; i-- {
+		if query.Match(snapshot[i]) {
+			matches = append(matches, newEntry(snapshot[i]))
+		}
+	}
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+	if matches == nil {
+		matches = []Entry{}
+	}
+	writeJSON(w, matches)
+}
+
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	query := logs.ParseSearch(r.URL.Query().Get("q"))
+	entries, cancel := s.buffer.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case entry := <-entries:
+			if !query.Match(entry) {
+				continue
+			}
+			data, err := json.Marshal(newEntry(entry))
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	var sources []logs.SourceStats
+	if s.stats != nil {
+		sources = s.stats()
+	}
+	writeJSON(w, map[string]any{
+		"buffered": s.buffer.Len(),
+		"sources":  sources,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
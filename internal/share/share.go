@@ -0,0 +1,285 @@
+// Package share lets logsviewer instances attach to a running one over a
+// unix socket and look at the same live buffer, e.g. two people on a call.
+//
+// The host writes NDJSON frames to every client: the entries it has
+// buffered when the client connects, then each new entry, and its view
+// (search, filter and selected entry) whenever that changes. Clients only
+// read.
+package share
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+	"github.com/marcuzy/logsviewer/internal/store"
+)
+
+// View is the part of the host's view that clients may mirror.
+type View struct {
+	Search   string `json:"search"`
+	Filter   string `json:"filter"`
+	Selected string `json:"selected,omitempty"`
+}
+
+// frame is one line of the protocol; exactly one field is set.
+type frame struct {
+	Entry json.RawMessage `json:"entry,omitempty"`
+	View  *View           `json:"view,omitempty"`
+}
+
+// clientQueue is how many frames a client may fall behind before it is
+// disconnected rather than slowing down the host.
+const clientQueue = 4096
+
+// Host serves the session to attached clients. Write, Flush and Close make
+// it usable as a tee of the entry stream.
+type Host struct {
+	ln   net.Listener
+	path string
+
+	mu      sync.Mutex
+	buffer  []logs.LogEntry // ring of the last entries, oldest at next
+	next    int
+	full    bool
+	view    View
+	clients map[*client]struct{}
+}
+
+type client struct {
+	conn  net.Conn
+	queue chan []byte
+}
+
+// Listen starts serving on a unix socket at path, replaying up to capacity
+// entries to clients that attach. The socket is only accessible to the
+// current user.
+func Listen(path string, capacity int) (*Host, error) {
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("share socket: %w", err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("share socket: %w", err)
+	}
+	h := &Host{
+		ln:      ln,
+		path:    path,
+		buffer:  make([]logs.LogEntry, max(capacity, 1)),
+		clients: make(map[*client]struct{}),
+	}
+	go h.accept()
+	return h, nil
+}
+
+func (h *Host) accept() {
+	for {
+		conn, err := h.ln.Accept()
+		if err != nil {
+			return
+		}
+		c := &client{conn: conn, queue: make(chan []byte, clientQueue)}
+		h.mu.Lock()
+		backlog := h.backlog()
+		view := h.view
+		h.clients[c] = struct{}{}
+		h.mu.Unlock()
+		go h.serve(c, backlog, view)
+	}
+}
+
+// backlog returns the buffered entries from the oldest.
+func (h *Host) backlog() []logs.LogEntry {
+	if !h.full {
+		return append([]logs.LogEntry(nil), h.buffer[:h.next]...)
+	}
+	return append(append([]logs.LogEntry(nil), h.buffer[h.next:]...), h.buffer[:h.next]...)
+}
+
+func (h *Host) serve(c *client, backlog []logs.LogEntry, view View) {
+	defer h.drop(c)
+	w := bufio.NewWriter(c.conn)
+	for _, entry := range backlog {
+		data, err := entryFrame(entry)
+		if err != nil {
+			continue
+		}
+		if _, err := w.Write(data); err != nil {
+			return
+		}
+	}
+	if _, err := w.Write(viewFrame(view)); err != nil {
+		return
+	}
+	if err := w.Flush(); err != nil {
+		return
+	}
+	for data := range c.queue {
+		if _, err := w.Write(data); err != nil {
+			return
+		}
+		if len(c.queue) == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// drop disconnects c unless that already happened.
+func (h *Host) drop(c *client) {
+	h.mu.Lock()
+	if _, ok := h.clients[c]; ok {
+		delete(h.clients, c)
+		close(c.queue)
+	}
+	h.mu.Unlock()
+	c.conn.Close()
+}
+
+// broadcast queues data for every client; called with mu held. A client
+// that fell too far behind is disconnected.
+func (h *Host) broadcast(data []byte) {
+	for c := range h.clients {
+		select {
+		case c.queue <- data:
+		default:
+			delete(h.clients, c)
+			close(c.queue)
+			c.conn.Close()
+		}
+	}
+}
+
+// Write buffers entry and sends it to the clients.
+func (h *Host) Write(entry logs.LogEntry) error {
+	data, err := entryFrame(entry)
+	if err != nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buffer[h.next] = entry
+	h.next++
+	if h.next == len(h.buffer) {
+		h.next, h.full = 0, true
+	}
+	h.broadcast(data)
+	return nil
+}
+
+// Flush is a no-op; clients are flushed as their queues drain.
+func (h *Host) Flush() error {
+	return nil
+}
+
+// SetView sends the host's view to the clients when it changed.
+func (h *Host) SetView(view View) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if view == h.view {
+		return
+	}
+	h.view = view
+	h.broadcast(viewFrame(view))
+}
+
+// Clients returns the number of attached clients.
+func (h *Host) Clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// Close stops serving, disconnects the clients and removes the socket.
+func (h *Host) Close() error {
+	err := h.ln.Close()
+	h.mu.Lock()
+	for c := range h.clients {
+		delete(h.clients, c)
+		close(c.queue)
+	}
+	h.mu.Unlock()
+	os.Remove(h.path)
+	return err
+}
+
+func entryFrame(entry logs.LogEntry) ([]byte, error) {
+	raw, err := store.MarshalEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(frame{Entry: raw})
+	return append(data, '\n'), err
+}
+
+func viewFrame(view View) []byte {
+	data, _ := json.Marshal(frame{View: &view})
+	return append(data, '\n')
+}
+
+// Attach connects to the host serving at path and streams its entries and
+// view changes. All channels are closed when the host ends the session or
+// ctx is canceled.
+func Attach(ctx context.Context, path string) (<-chan logs.LogEntry, <-chan View, <-chan error, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("attach: %w", err)
+	}
+	entries := make(chan logs.LogEntry, 256)
+	views := make(chan View, 16)
+	errs := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		defer close(entries)
+		defer close(views)
+		defer close(errs)
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+		for scanner.Scan() {
+			var f frame
+			if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+				errs <- fmt.Errorf("attach: decode frame: %w", err)
+				return
+			}
+			switch {
+			case f.Entry != nil:
+				entry, err := store.UnmarshalEntry(f.Entry)
+				if err != nil {
+					errs <- fmt.Errorf("attach: decode entry: %w", err)
+					return
+				}
+				select {
+				case entries <- entry:
+				case <-ctx.Done():
+					return
+				}
+			case f.View != nil:
+				select {
+				case views <- *f.View:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil && ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+			errs <- fmt.Errorf("attach: %w", err)
+			return
+		}
+		if ctx.Err() == nil {
+			errs <- errors.New("the host ended the session")
+		}
+	}()
+	return entries, views, errs, nil
+}
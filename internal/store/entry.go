@@ -0,0 +1,71 @@
+package store
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// storedEntry is the serialized form of a LogEntry.
+type storedEntry struct {
+	Path          string            `json:"path"`
+	Timestamp     time.Time         `json:"ts"`
+	TimestampText string            `json:"ts_text,omitempty"`
+	Message       string            `json:"msg,omitempty"`
+	Extras        map[string]string `json:"extras,omitempty"`
+	Fields        map[string]any    `json:"fields,omitempty"`
+	Raw           string            `json:"raw"`
+	Offset        int64             `json:"offset,omitempty"`
+	Size          int               `json:"size,omitempty"`
+	Checksum      uint32            `json:"crc,omitempty"`
+	LevelName     string            `json:"level,omitempty"`
+}
+
+func newStoredEntry(entry logs.LogEntry) storedEntry {
+	entry = entry.Unpacked()
+	return storedEntry{
+		Path:          entry.Path,
+		Timestamp:     entry.Timestamp,
+		TimestampText: entry.TimestampText,
+		Message:       entry.Message,
+		Extras:        entry.Extras,
+		Fields:        entry.Fields,
+		Raw:           entry.Raw,
+		Offset:        entry.Offset,
+		Size:          entry.Size,
+		Checksum:      entry.Checksum,
+		LevelName:     entry.LevelName,
+	}
+}
+
+func (se storedEntry) entry() logs.LogEntry {
+	return logs.LogEntry{
+		Path:          se.Path,
+		Timestamp:     se.Timestamp,
+		TimestampText: se.TimestampText,
+		Message:       se.Message,
+		Extras:        se.Extras,
+		Fields:        se.Fields,
+		Raw:           se.Raw,
+		Offset:        se.Offset,
+		Size:          se.Size,
+		Checksum:      se.Checksum,
+		LevelName:     se.LevelName,
+	}
+}
+
+// MarshalEntry encodes entry as session and spill files store it, for
+// other places that move entries between processes.
+func MarshalEntry(entry logs.LogEntry) ([]byte, error) {
+	return json.Marshal(newStoredEntry(entry))
+}
+
+// UnmarshalEntry decodes an entry encoded by MarshalEntry.
+func UnmarshalEntry(data []byte) (logs.LogEntry, error) {
+	var se storedEntry
+	if err := json.Unmarshal(data, &se); err != nil {
+		return logs.LogEntry{}, err
+	}
+	return se.entry(), nil
+}
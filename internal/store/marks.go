@@ -0,0 +1,169 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// marksSuffix is appended to the name of a log file for its sidecar. The
+// tailer knows it too, so that patterns do not pick sidecars up.
+const marksSuffix = ".marks.json"
+
+// markLineSize is how much of a marked line is kept to recognize it.
+const markLineSize = 80
+
+// Mark is a bookmark or note on a line of a log file.
+type Mark struct {
+	Offset int64 `json:"offset"`
+	// Line is the beginning of the marked line; a mark is only shown on a
+	// line that still starts the same, e.g. not after the file was
+	// replaced by rotation.
+	Line     string `json:"line"`
+	Bookmark bool   `json:"bookmark,omitempty"`
+	Note     string `json:"note,omitempty"`
+}
+
+// Empty reports whether the mark neither bookmarks nor annotates.
+func (m Mark) Empty() bool {
+	return !m.Bookmark && m.Note == ""
+}
+
+type marksFile struct {
+	Path  string `json:"path"`
+	Marks []Mark `json:"marks"`
+}
+
+// Marks keeps the marks of log files in sidecar files, app.log.marks.json
+// next to app.log or, with a directory set, in that directory. Sidecars are
+// read on first use and written on every change. It is not safe for
+// concurrent use.
+type Marks struct {
+	dir   string
+	files map[string]*sourceMarks
+}
+
+type sourceMarks struct {
+	marks map[int64]Mark
+	// err is why the sidecar could not be read; it is not overwritten
+	// then.
+	err error
+}
+
+// NewMarks returns marks stored next to the log files, or in dir if it is
+// not empty.
+func NewMarks(dir string) *Marks {
+	return &Marks{dir: dir, files: make(map[string]*sourceMarks)}
+}
+
+// SidecarPath returns where the marks of the log file at path are kept.
+func (s *Marks) SidecarPath(path string) string {
+	if s.dir == "" {
+		return path + marksSuffix
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	return filepath.Join(s.dir, strings.ReplaceAll(strings.TrimPrefix(abs, "/"), "/", "%")+marksSuffix)
+}
+
+func (s *Marks) source(path string) *sourceMarks {
+	if src, ok := s.files[path]; ok {
+		return src
+	}
+	src := &sourceMarks{marks: make(map[int64]Mark)}
+	s.files[path] = src
+	data, err := os.ReadFile(s.SidecarPath(path))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			src.err = err
+		}
+		return src
+	}
+	var f marksFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		src.err = fmt.Errorf("read %s: %w", s.SidecarPath(path), err)
+		return src
+	}
+	for _, m := range f.Marks {
+		src.marks[m.Offset] = m
+	}
+	return src
+}
+
+// Get returns the mark of entry, if any.
+func (s *Marks) Get(entry logs.LogEntry) (Mark, bool) {
+	m, ok := s.source(entry.Path).marks[entry.Offset]
+	if !ok || m.Line != markLine(entry) {
+		return Mark{}, false
+	}
+	return m, true
+}
+
+// Set replaces the mark of entry and saves the sidecar; an empty mark
+// removes it.
+func (s *Marks) Set(entry logs.LogEntry, m Mark) error {
+	src := s.source(entry.Path)
+	if src.err != nil {
+		return src.err
+	}
+	if m.Empty() {
+		delete(src.marks, entry.Offset)
+	} else {
+		m.Offset, m.Line = entry.Offset, markLine(entry)
+		src.marks[entry.Offset] = m
+	}
+	return s.save(entry.Path, src)
+}
+
+func (s *Marks) save(path string, src *sourceMarks) error {
+	sidecar := s.SidecarPath(path)
+	if len(src.marks) == 0 {
+		if err := os.Remove(sidecar); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	f := marksFile{Path: path, Marks: make([]Mark, 0, len(src.marks))}
+	for _, m := range src.marks {
+		f.Marks = append(f.Marks, m)
+	}
+	sort.Slice(f.Marks, func(i, j int) bool { return f.Marks[i].Offset < f.Marks[j].Offset })
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if s.dir != "" {
+		if err := os.MkdirAll(s.dir, 0o755); err != nil {
+			return err
+		}
+	}
+	// Written aside and renamed so that a crash never leaves half a file.
+	tmp := sidecar + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, sidecar)
+}
+
+// markLine returns the beginning of entry's line as valid UTF-8, which
+// survives the JSON of the sidecar unchanged.
+func markLine(entry logs.LogEntry) string {
+	line := entry.RawLine()
+	if len(line) > markLineSize {
+		n := markLineSize
+		for n > 0 && !utf8.RuneStart(line[n]) {
+			n--
+		}
+		line = line[:n]
+	}
+	return strings.ToValidUTF8(line, "")
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// sessionVersion identifies the session file layout: a header line followed
+// by one record per ingested entry, each with its time since the start.
+const sessionVersion = 1
+
+type sessionHeader struct {
+	Version int       `json:"logsviewer_session"`
+	Started time.Time `json:"started"`
+}
+
+type sessionRecord struct {
+	Elapsed time.Duration `json:"t"`
+	Entry   storedEntry   `json:"entry"`
+}
+
+// Recorder writes ingested entries together with their arrival times so
+// that a session can be replayed later. It is not safe for concurrent use.
+type Recorder struct {
+	file    *os.File
+	w       *bufio.Writer
+	enc     *json.Encoder
+	started time.Time
+}
+
+// CreateRecorder creates or truncates the session file at path.
+func CreateRecorder(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{file: file, w: bufio.NewWriter(file), started: time.Now()}
+	r.enc = json.NewEncoder(r.w)
+	if err := r.enc.Encode(sessionHeader{Version: sessionVersion, Started: r.started}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write records entry as arriving now.
+func (r *Recorder) Write(entry logs.LogEntry) error {
+	return r.enc.Encode(sessionRecord{Elapsed: time.Since(r.started), Entry: newStoredEntry(entry)})
+}
+
+// Flush writes buffered records to the file.
+func (r *Recorder) Flush() error {
+	return r.w.Flush()
+}
+
+// Close flushes and closes the file.
+func (r *Recorder) Close() error {
+	err := r.w.Flush()
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Replay streams the entries of a recorded session, reproducing the
+// original gaps between them divided by speed. A speed of zero or less
+// sends entries without delay. Both channels are closed at the end of the
+// session or when ctx is canceled.
+func Replay(ctx context.Context, path string, speed float64) (<-chan logs.LogEntry, <-chan error) {
+	entries := make(chan logs.LogEntry, 256)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(entries)
+		defer close(errs)
+		if err := replay(ctx, path, speed, entries); err != nil {
+			errs <- fmt.Errorf("replay %s: %w", path, err)
+		}
+	}()
+	return entries, errs
+}
+
+func replay(ctx context.Context, path string, speed float64, out chan<- logs.LogEntry) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		return errors.New("empty session file")
+	}
+	var header sessionHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Version == 0 {
+		return errors.New("not a logsviewer session file")
+	}
+	if header.Version > sessionVersion {
+		return fmt.Errorf("unsupported session version %d", header.Version)
+	}
+
+	start := time.Now()
+	for scanner.Scan() {
+		var rec sessionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("decode record: %w", err)
+		}
+		if speed > 0 {
+			due := start.Add(time.Duration(float64(rec.Elapsed) / speed))
+			if wait := time.Until(due); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil
+				case <-timer.C:
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case out <- rec.Entry.entry():
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,396 @@
+// Package store provides on-disk storage for log entries that no longer fit
+// in the in-memory buffer.
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+var (
+	// spillEntries maps the sequence number of a spilled entry to the
+	// entry encoded as MarshalEntry does.
+	spillEntries = []byte("entries")
+	// spillTimes holds a key of timestamp and sequence number for every
+	// spilled entry with a timestamp, to find entries by time.
+	spillTimes = []byte("times")
+	// spillWords holds a key of token, a zero byte and sequence number for
+	// every word of every spilled entry, and spillVocab counts the entries
+	// of each token, so that searches read only entries with their words.
+	spillWords = []byte("words")
+	spillVocab = []byte("vocab")
+)
+
+// spillBatch is how many entries are written to disk in one transaction.
+const spillBatch = 256
+
+// Spill is an on-disk store of evicted entries, a bbolt database in a
+// temporary file. Entries are numbered in the order they arrive and
+// indexed by time and by word; once there are more than the limit, the
+// oldest ones are dropped.
+type Spill struct {
+	db    *bolt.DB
+	limit int
+	// count is the number of stored entries, oldest the sequence number
+	// of the oldest one and next the one the next entry gets.
+	count  int
+	oldest uint64
+	next   uint64
+	// pending are appended entries not yet written.
+	pending []logs.LogEntry
+}
+
+// OpenSpill creates a spill file inside dir that keeps at most limit
+// entries, or any number if limit is zero. An empty dir uses the system
+// temporary directory.
+func OpenSpill(dir string, limit int) (*Spill, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create spill dir: %w", err)
+		}
+	}
+	file, err := os.CreateTemp(dir, "logsviewer-spill-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("create spill file: %w", err)
+	}
+	file.Close()
+	db, err := bolt.Open(file.Name(), 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("open spill: %w", err)
+	}
+	// The file is thrown away on exit, so there is nothing to sync for.
+	db.NoSync = true
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{spillEntries, spillTimes, spillWords, spillVocab} {
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("open spill: %w", err)
+	}
+	return &Spill{db: db, limit: limit}, nil
+}
+
+// Path returns the location of the spill file.
+func (s *Spill) Path() string {
+	return s.db.Path()
+}
+
+// Len returns the number of spilled entries.
+func (s *Spill) Len() int {
+	n := s.count + len(s.pending)
+	if s.limit > 0 && n > s.limit {
+		return s.limit
+	}
+	return n
+}
+
+// Append adds entry as the newest one. Entries are written in batches;
+// reads see every appended entry.
+func (s *Spill) Append(entry logs.LogEntry) error {
+	s.pending = append(s.pending, entry)
+	if len(s.pending) < spillBatch {
+		return nil
+	}
+	return s.flush()
+}
+
+// flush writes the pending entries and drops the oldest ones beyond the
+// limit.
+func (s *Spill) flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		entries, times := tx.Bucket(spillEntries), tx.Bucket(spillTimes)
+		for _, entry := range s.pending {
+			data, err := MarshalEntry(entry)
+			if err != nil {
+				return fmt.Errorf("encode spilled entry: %w", err)
+			}
+			seq := seqKey(s.next)
+			if err := entries.Put(seq, data); err != nil {
+				return err
+			}
+			if key, ok := timeKey(entry.Timestamp, s.next); ok {
+				if err := times.Put(key, nil); err != nil {
+					return err
+				}
+			}
+			if err := indexWords(tx, entry, s.next, 1); err != nil {
+				return err
+			}
+			s.next++
+			s.count++
+		}
+		for s.limit > 0 && s.count > s.limit {
+			if err := s.dropOldest(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	s.pending = s.pending[:0]
+	if err != nil {
+		return fmt.Errorf("write spill: %w", err)
+	}
+	return nil
+}
+
+// dropOldest deletes the oldest stored entry and its index keys.
+func (s *Spill) dropOldest(tx *bolt.Tx) error {
+	entries := tx.Bucket(spillEntries)
+	seq, data := entries.Cursor().First()
+	if seq == nil {
+		s.count = 0
+		return nil
+	}
+	entry, err := UnmarshalEntry(data)
+	if err != nil {
+		return fmt.Errorf("decode spilled entry: %w", err)
+	}
+	n := binary.BigEndian.Uint64(seq)
+	if key, ok := timeKey(entry.Timestamp, n); ok {
+		if err := tx.Bucket(spillTimes).Delete(key); err != nil {
+			return err
+		}
+	}
+	if err := indexWords(tx, entry, n, -1); err != nil {
+		return err
+	}
+	if err := entries.Delete(seq); err != nil {
+		return err
+	}
+	s.count--
+	s.oldest = n + 1
+	return nil
+}
+
+// Oldest returns the sequence number of the oldest entry the spill holds.
+func (s *Spill) Oldest() uint64 {
+	return s.oldest
+}
+
+// entryWords returns the tokens of the text Matches looks at in entry. A
+// timestamp is shown in the zone the viewer is in at the time, so only the
+// text of entries without one is indexed; see Search.
+func entryWords(entry logs.LogEntry) map[string]bool {
+	words := make(map[string]bool)
+	index := func(text string) {
+		for _, token := range logs.Tokenize(strings.ToLower(text)) {
+			words[token] = true
+		}
+	}
+	index(entry.Message)
+	index(entry.RawLine())
+	index(entry.Path)
+	for _, v := range entry.Extras {
+		index(v)
+	}
+	if entry.Timestamp.IsZero() {
+		index(entry.TimestampText)
+	}
+	return words
+}
+
+// indexWords adds (delta 1) or removes (-1) the word keys of entry seq.
+func indexWords(tx *bolt.Tx, entry logs.LogEntry, seq uint64, delta int) error {
+	words, vocab := tx.Bucket(spillWords), tx.Bucket(spillVocab)
+	for token := range entryWords(entry) {
+		key := append([]byte(token+"\x00"), seqKey(seq)...)
+		var count uint64
+		if v := vocab.Get([]byte(token)); v != nil {
+			count = binary.BigEndian.Uint64(v)
+		}
+		count += uint64(delta)
+		var err error
+		if delta > 0 {
+			err = words.Put(key, nil)
+		} else {
+			err = words.Delete(key)
+		}
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			err = vocab.Delete([]byte(token))
+		} else {
+			err = vocab.Put([]byte(token), seqKey(count))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search calls fn, from the newest to the oldest until it returns false,
+// for the spilled entries numbered from on that may contain all of words,
+// and returns the number the next spilled entry gets. fn still has to
+// match the entries: words narrow the entries read to those with their
+// tokens, except words of digits alone, which a timestamp may hold.
+func (s *Spill) Search(words []string, from uint64, fn func(seq uint64, entry logs.LogEntry) bool) (uint64, error) {
+	if err := s.flush(); err != nil {
+		return s.next, err
+	}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		candidates := s.candidates(tx, words, from)
+		entries := tx.Bucket(spillEntries)
+		visit := func(seq, data []byte) (bool, error) {
+			entry, err := UnmarshalEntry(data)
+			if err != nil {
+				return false, fmt.Errorf("decode spilled entry: %w", err)
+			}
+			return fn(binary.BigEndian.Uint64(seq), entry), nil
+		}
+		if candidates == nil {
+			c := entries.Cursor()
+			for seq, data := c.Last(); seq != nil && binary.BigEndian.Uint64(seq) >= from; seq, data = c.Prev() {
+				if more, err := visit(seq, data); !more || err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		seqs := make([]uint64, 0, len(candidates))
+		for seq := range candidates {
+			seqs = append(seqs, seq)
+		}
+		slices.Sort(seqs)
+		for i := len(seqs) - 1; i >= 0; i-- {
+			key := seqKey(seqs[i])
+			data := entries.Get(key)
+			if data == nil {
+				continue
+			}
+			if more, err := visit(key, data); !more || err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return s.next, err
+}
+
+// candidates returns the entries numbered from on that may contain all
+// of words, or nil if the words do not narrow them.
+func (s *Spill) candidates(tx *bolt.Tx, words []string, from uint64) map[uint64]bool {
+	var set map[uint64]bool
+	vocab, postings := tx.Bucket(spillVocab).Cursor(), tx.Bucket(spillWords).Cursor()
+	for _, word := range words {
+		tokens := logs.QueryTokens(word)
+		if len(tokens) == 0 || digitsOnly(tokens) {
+			continue
+		}
+		for _, token := range tokens {
+			found := make(map[uint64]bool)
+			add := func(v []byte) {
+				prefix := append(slices.Clip(v), 0)
+				for key, _ := postings.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = postings.Next() {
+					if seq := binary.BigEndian.Uint64(key[len(prefix):]); seq >= from && (set == nil || set[seq]) {
+						found[seq] = true
+					}
+				}
+			}
+			if token.PrefixOpen {
+				for v, _ := vocab.First(); v != nil; v, _ = vocab.Next() {
+					if token.Matches(string(v)) {
+						add(v)
+					}
+				}
+			} else {
+				// The token starts the vocabulary it matches.
+				start := []byte(token.Text)
+				for v, _ := vocab.Seek(start); v != nil && bytes.HasPrefix(v, start); v, _ = vocab.Next() {
+					if token.Matches(string(v)) {
+						add(v)
+					}
+				}
+			}
+			set = found
+		}
+	}
+	return set
+}
+
+// digitsOnly reports whether tokens are all numbers, which a timestamp may
+// hold.
+func digitsOnly(tokens []logs.QueryToken) bool {
+	for _, token := range tokens {
+		for _, r := range token.Text {
+			if !unicode.IsDigit(r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Since returns up to n spilled entries, oldest first, starting with the
+// earliest one whose timestamp is at or after t.
+func (s *Spill) Since(t time.Time, n int) ([]logs.LogEntry, error) {
+	if err := s.flush(); err != nil {
+		return nil, err
+	}
+	start, ok := timeKey(t, 0)
+	if !ok {
+		return nil, nil
+	}
+	var out []logs.LogEntry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		key, _ := tx.Bucket(spillTimes).Cursor().Seek(start)
+		if key == nil {
+			return nil
+		}
+		c := tx.Bucket(spillEntries).Cursor()
+		for seq, data := c.Seek(key[8:]); seq != nil && len(out) < n; seq, data = c.Next() {
+			entry, err := UnmarshalEntry(data)
+			if err != nil {
+				return fmt.Errorf("decode spilled entry: %w", err)
+			}
+			out = append(out, entry)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// Close removes the spill file.
+func (s *Spill) Close() error {
+	name := s.db.Path()
+	err := s.db.Close()
+	if rmErr := os.Remove(name); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+func seqKey(seq uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, seq)
+}
+
+// timeKey orders entries by timestamp and then by arrival. Entries without
+// a timestamp, or with one nanoseconds since 1970 cannot hold, have none.
+func timeKey(t time.Time, seq uint64) ([]byte, bool) {
+	if t.IsZero() || t.Year() < 1678 || t.Year() > 2261 {
+		return nil, false
+	}
+	key := binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano())^(1<<63))
+	return append(key, seqKey(seq)...), true
+}
@@ -0,0 +1,356 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// AlertRule raises an alert for every arriving entry that matches Filter,
+// a logs.ParseFilter expression, or with Threshold set, whenever more than
+// Threshold entries match within Window.
+type AlertRule struct {
+	Name      string
+	Filter    string
+	Threshold int
+	Window    time.Duration
+	// Quiet suppresses the terminal bell; the status line badge is still
+	// shown.
+	Quiet bool
+	// Notify also shows a desktop notification, at most once per notify
+	// interval.
+	Notify bool
+	// Command is run through sh with the matching entry on stdin, at most
+	// once per notify interval. It is a template like PipeCommand.
+	Command string
+	// Pause stops following when the rule fires and selects the entry it
+	// fired on, or the first entry of the threshold window.
+	Pause bool
+}
+
+// bellInterval is the minimum time between two bells, so that a burst of
+// matching entries rings once.
+const bellInterval = 2 * time.Second
+
+// defaultNotifyInterval is the default minimum time between two desktop
+// notifications or commands of a rule.
+const defaultNotifyInterval = 30 * time.Second
+
+type alertRule struct {
+	name    string
+	filter  logs.Filter
+	quiet   bool
+	notify  bool
+	command string
+	pause   bool
+
+	threshold int
+	window    time.Duration
+	// hits are the matches within the window, oldest first; firing is set
+	// while there are more than threshold of them.
+	hits   []alertHit
+	firing bool
+
+	// lastNotified is when the rule last notified or ran its command;
+	// suppressed counts the matches since then.
+	lastNotified time.Time
+	suppressed   int
+}
+
+type alertHit struct {
+	at  time.Time
+	key string
+}
+
+// hit records a match of the rule. It reports whether the rule fires and
+// the key of the entry to jump to: the entry itself, or for threshold
+// rules the first entry of the window that crossed the threshold. The
+// window is measured in entry timestamps so that a backlog read at once
+// is judged by when it was logged.
+func (r *alertRule) hit(entry logs.LogEntry) (string, bool) {
+	key := entryKey(entry)
+	if r.threshold <= 0 {
+		return key, true
+	}
+	at := entry.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	r.hits = append(r.hits, alertHit{at: at, key: key})
+	cutoff := at.Add(-r.window)
+	drop := 0
+	for drop < len(r.hits) && r.hits[drop].at.Before(cutoff) {
+		drop++
+	}
+	r.hits = r.hits[drop:]
+	if len(r.hits) <= r.threshold {
+		r.firing = false
+		return "", false
+	}
+	if r.firing {
+		return "", false
+	}
+	r.firing = true
+	return r.hits[0].key, true
+}
+
+// alerts tracks the rule matches not yet acknowledged with "!".
+type alerts struct {
+	rules    []alertRule
+	counts   map[string]int
+	last     string // key of the latest matching entry
+	lastBell time.Time
+
+	notifyInterval time.Duration
+	// commands are the rule commands due to run, taken by the model after
+	// each batch of entries; running limits how many run at once.
+	commands []tea.Cmd
+	running  chan struct{}
+	// pauseKey is the entry to stop at when a rule with pause fired, and
+	// pauseRule that rule.
+	pauseKey  string
+	pauseRule string
+
+	// snoozed maps rule names to the end of their snooze; snoozeAll
+	// snoozes every rule.
+	snoozed   map[string]time.Time
+	snoozeAll time.Time
+}
+
+func newAlerts(rules []AlertRule, notifyInterval time.Duration, commandLimit int) (*alerts, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	if notifyInterval <= 0 {
+		notifyInterval = defaultNotifyInterval
+	}
+	if commandLimit <= 0 {
+		commandLimit = defaultAlertCommandLimit
+	}
+	a := &alerts{
+		counts:         make(map[string]int),
+		snoozed:        make(map[string]time.Time),
+		notifyInterval: notifyInterval,
+		running:        make(chan struct{}, commandLimit),
+	}
+	for _, r := range rules {
+		filter, err := logs.ParseFilter(r.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("alert %q: %w", r.Name, err)
+		}
+		name := r.Name
+		if name == "" {
+			name = r.Filter
+		}
+		a.rules = append(a.rules, alertRule{
+			name:      name,
+			filter:    filter,
+			quiet:     r.Quiet,
+			notify:    r.Notify,
+			command:   r.Command,
+			pause:     r.Pause,
+			threshold: r.Threshold,
+			window:    r.Window,
+		})
+	}
+	return a, nil
+}
+
+// observe checks entry against the rules, rings the bell for a match of a
+// rule that is not quiet and sends desktop notifications. A notification
+// due within the notify interval of the previous one is dropped and
+// counted in the next. Snoozed rules only count their matches. Text is
+// matched against the timestamp as shown in loc.
+func (a *alerts) observe(entry logs.LogEntry, loc *time.Location) error {
+	ring := false
+	var notifyErr error
+	for i := range a.rules {
+		r := &a.rules[i]
+		if !r.filter.In(loc).Match(entry) {
+			continue
+		}
+		key, fired := r.hit(entry)
+		if !fired {
+			continue
+		}
+		a.counts[r.name]++
+		a.last = key
+		if a.isSnoozed(r.name) {
+			continue
+		}
+		ring = ring || !r.quiet
+		if r.pause && a.pauseKey == "" {
+			a.pauseKey, a.pauseRule = key, r.name
+		}
+		if !r.notify && r.command == "" {
+			continue
+		}
+		if time.Since(r.lastNotified) < a.notifyInterval {
+			r.suppressed++
+			continue
+		}
+		body := entry.Message
+		if body == "" {
+			body = entry.RawLine()
+		}
+		if r.threshold > 0 {
+			body = fmt.Sprintf("more than %d matches within %s, latest: %s", r.threshold, r.window, body)
+		}
+		if r.suppressed > 0 {
+			body += fmt.Sprintf("\n(+%d more since the last notification)", r.suppressed)
+		}
+		r.lastNotified = time.Now()
+		r.suppressed = 0
+		if r.command != "" {
+			a.commands = append(a.commands, a.runCommand(r.name, r.command, entry))
+		}
+		if !r.notify {
+			continue
+		}
+		if err := notifyDesktop("logsviewer: "+r.name, body); err != nil && notifyErr == nil {
+			notifyErr = fmt.Errorf("notify: %w", err)
+		}
+	}
+	if ring && time.Since(a.lastBell) >= bellInterval {
+		a.lastBell = time.Now()
+		ringBell()
+	}
+	return notifyErr
+}
+
+// ringBell sends BEL to the terminal. Terminals beep, flash or mark the
+// tab or pane, depending on their settings.
+func ringBell() {
+	os.Stdout.WriteString("\a")
+}
+
+func (a *alerts) isSnoozed(name string) bool {
+	now := time.Now()
+	return now.Before(a.snoozeAll) || now.Before(a.snoozed[name])
+}
+
+// snoozeStatus lists the active snoozes with their remaining time.
+func (a *alerts) snoozeStatus() string {
+	if a == nil {
+		return ""
+	}
+	now := time.Now()
+	if now.Before(a.snoozeAll) {
+		return "alerts snoozed " + a.snoozeAll.Sub(now).Round(time.Second).String()
+	}
+	var parts []string
+	for name, until := range a.snoozed {
+		if now.Before(until) {
+			parts = append(parts, name+" "+until.Sub(now).Round(time.Second).String())
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	sort.Strings(parts)
+	return "snoozed: " + strings.Join(parts, ", ")
+}
+
+// badge summarizes the unacknowledged matches for the status line.
+func (a *alerts) badge() string {
+	if a == nil || len(a.counts) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(a.counts))
+	for name := range a.counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s ×%d", name, a.counts[name])
+	}
+	return "⚑ " + strings.Join(parts, ", ")
+}
+
+// acknowledgeAlerts clears the alert badge and selects the latest matching
+// entry, or the start of the latest threshold window, if it is shown.
+func (m *Model) acknowledgeAlerts() {
+	if m.alerts == nil || len(m.alerts.counts) == 0 {
+		m.statusMessage = "no alerts"
+		return
+	}
+	clear(m.alerts.counts)
+	m.selectEntryKey(m.alerts.last)
+	m.statusMessage = "alerts acknowledged"
+}
+
+// defaultSnooze is how long :snooze mutes alerts without a duration.
+const defaultSnooze = 10 * time.Minute
+
+// snoozeCommand mutes alert rules: ":snooze [rule] [duration]". Without a
+// rule every rule is snoozed; the duration defaults to 10 minutes. Matches
+// are still counted in the badge.
+func (m *Model) snoozeCommand(line string) (tea.Cmd, error) {
+	if m.alerts == nil {
+		return nil, fmt.Errorf("no alerts configured")
+	}
+	rule, d := "", defaultSnooze
+	for _, arg := range strings.Fields(line) {
+		if v, err := time.ParseDuration(arg); err == nil && v > 0 {
+			d = v
+			continue
+		}
+		if rule != "" || !m.alerts.hasRule(arg) {
+			return nil, fmt.Errorf("usage: snooze [rule] [duration], rules: %s", strings.Join(m.alerts.ruleNames(), ", "))
+		}
+		rule = arg
+	}
+	until := time.Now().Add(d)
+	if rule == "" {
+		m.alerts.snoozeAll = until
+		m.statusMessage = "alerts snoozed for " + d.String()
+	} else {
+		m.alerts.snoozed[rule] = until
+		m.statusMessage = fmt.Sprintf("alert %s snoozed for %s", rule, d)
+	}
+	return nil, nil
+}
+
+// unsnoozeCommand ends snoozes: ":unsnooze [rule]", every snooze without a
+// rule.
+func (m *Model) unsnoozeCommand(rule string) (tea.Cmd, error) {
+	if m.alerts == nil {
+		return nil, fmt.Errorf("no alerts configured")
+	}
+	if rule == "" {
+		m.alerts.snoozeAll = time.Time{}
+		clear(m.alerts.snoozed)
+		m.statusMessage = "alerts unsnoozed"
+		return nil, nil
+	}
+	if !m.alerts.hasRule(rule) {
+		return nil, fmt.Errorf("unknown rule %q", rule)
+	}
+	delete(m.alerts.snoozed, rule)
+	m.statusMessage = "alert " + rule + " unsnoozed"
+	return nil, nil
+}
+
+func (a *alerts) hasRule(name string) bool {
+	for _, r := range a.rules {
+		if r.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *alerts) ruleNames() []string {
+	names := make([]string, len(a.rules))
+	for i, r := range a.rules {
+		names[i] = r.name
+	}
+	return names
+}
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcuzy/logsviewer/internal/export"
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// defaultAlertCommandLimit is how many alert commands may run at once by
+// default.
+const defaultAlertCommandLimit = 4
+
+type alertCommandMsg struct {
+	rule string
+	err  error
+}
+
+// runCommand returns a command running an alert rule's command for entry.
+// When the concurrency limit is reached the run is skipped rather than
+// queued, so a slow pager cannot pile up work.
+func (a *alerts) runCommand(rule, command string, entry logs.LogEntry) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case a.running <- struct{}{}:
+			defer func() { <-a.running }()
+		default:
+			return alertCommandMsg{rule: rule, err: fmt.Errorf("skipped, %d commands already running", cap(a.running))}
+		}
+		expanded, err := renderEntryTemplate(command, entry)
+		if err != nil {
+			return alertCommandMsg{rule: rule, err: err}
+		}
+		var input bytes.Buffer
+		if err := export.Write(&input, []logs.LogEntry{entry}, export.Options{Format: export.NDJSON}); err != nil {
+			return alertCommandMsg{rule: rule, err: err}
+		}
+		cmd := exec.Command("sh", "-c", expanded)
+		cmd.Stdin = &input
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				err = fmt.Errorf("%w: %s", err, firstLine(msg))
+			}
+			return alertCommandMsg{rule: rule, err: err}
+		}
+		return alertCommandMsg{rule: rule}
+	}
+}
+
+// takeCommands returns the alert commands due since the last call.
+func (a *alerts) takeCommands() []tea.Cmd {
+	if a == nil || len(a.commands) == 0 {
+		return nil
+	}
+	cmds := a.commands
+	a.commands = nil
+	return cmds
+}
+
+func (m *Model) handleAlertCommand(msg alertCommandMsg) {
+	if msg.err != nil {
+		m.errorMessage = fmt.Sprintf("alert %s: command: %v", msg.rule, msg.err)
+	}
+}
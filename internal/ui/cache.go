@@ -0,0 +1,41 @@
+package ui
+
+// renderCache keeps rendered detail content for recently selected entries,
+// evicting the oldest key once capacity is reached.
+type renderCache struct {
+	capacity int
+	values   map[string]string
+	order    []string
+}
+
+func newRenderCache(capacity int) *renderCache {
+	return &renderCache{
+		capacity: capacity,
+		values:   make(map[string]string, capacity),
+	}
+}
+
+func (c *renderCache) Get(key string) (string, bool) {
+	val, ok := c.values[key]
+	return val, ok
+}
+
+func (c *renderCache) Put(key, val string) {
+	if _, ok := c.values[key]; ok {
+		c.values[key] = val
+		return
+	}
+	if len(c.order) >= c.capacity {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		delete(c.values, oldest)
+	}
+	c.order = append(c.order, key)
+	c.values[key] = val
+}
+
+// Reset drops every cached value.
+func (c *renderCache) Reset() {
+	c.values = make(map[string]string, c.capacity)
+	c.order = nil
+}
@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// Clipboard modes accepted by the clipboard option.
+const (
+	// ClipboardAuto uses the OS clipboard and additionally OSC52 when
+	// running over SSH or when no OS clipboard is available.
+	ClipboardAuto = "auto"
+	// ClipboardOS uses only the OS clipboard.
+	ClipboardOS = "os"
+	// ClipboardOSC52 only asks the terminal to set the clipboard.
+	ClipboardOSC52 = "osc52"
+)
+
+// copyToClipboard places text on the clipboard according to mode. OSC52
+// reaches the clipboard of the machine the terminal runs on, which is what
+// a user attached over SSH or to a remote tmux session expects.
+func copyToClipboard(mode, text string) error {
+	switch mode {
+	case ClipboardOS:
+		return clipboard.WriteAll(text)
+	case ClipboardOSC52:
+		return writeOSC52(text)
+	}
+	err := clipboard.WriteAll(text)
+	if err != nil || os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "" {
+		return writeOSC52(text)
+	}
+	return nil
+}
+
+// writeOSC52 emits the OSC52 sequence to the terminal, wrapped for tmux or
+// screen when running inside one so that it is passed through.
+func writeOSC52(text string) error {
+	seq := osc52.New(text)
+	if os.Getenv("TMUX") != "" {
+		seq = seq.Tmux()
+	} else if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		seq = seq.Screen()
+	}
+	_, err := seq.WriteTo(os.Stdout)
+	return err
+}
+
+// copyEntries copies the original lines of the visual selection, or of the
+// selected entry, one per line; with the pretty copy format, each entry is
+// indented JSON instead.
+func (m *Model) copyEntries() {
+	entries := m.selectedEntries()
+	m.visualAnchor = ""
+	if entries == nil {
+		item, ok := m.list.SelectedItem().(logItem)
+		if !ok {
+			return
+		}
+		entries = []logs.LogEntry{item.entry}
+	}
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		if m.copyFormat == "pretty" {
+			lines[i] = entry.PrettyJSON()
+			continue
+		}
+		raw, err := entry.FullRaw()
+		if err != nil {
+			raw = entry.RawLine()
+		}
+		lines[i] = raw
+	}
+	what := "entry"
+	if len(entries) > 1 {
+		what = fmt.Sprintf("%d entries", len(entries))
+	}
+	m.copyText(strings.Join(lines, "\n"), what)
+}
+
+// copyMessage copies the message of the selected entry.
+func (m *Model) copyMessage() {
+	item, ok := m.list.SelectedItem().(logItem)
+	if !ok {
+		return
+	}
+	message := item.entry.Message
+	if message == "" {
+		message = item.entry.RawLine()
+	}
+	m.copyText(message, "message")
+}
+
+func (m *Model) copyText(text, what string) {
+	if err := copyToClipboard(m.clipboard, text); err != nil {
+		m.errorMessage = fmt.Sprintf("copy: %v", err)
+		return
+	}
+	m.statusMessage = "copied " + what
+}
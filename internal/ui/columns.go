@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// Column is a column of the table the list is drawn as when columns are
+// configured.
+type Column struct {
+	// Field is a field name, or @timestamp, @level, @message or @file.
+	Field string
+	// Title heads the column; it defaults to Field.
+	Title string
+	// Width is in terminal cells; zero shares the width the other columns
+	// leave.
+	Width int
+	// Right aligns the values to the right, e.g. for numbers.
+	Right bool
+}
+
+// columnGap separates the columns of a row.
+const columnGap = "  "
+
+// columnDelegate draws each entry as one line of aligned columns, colored
+// by level as levelDelegate does.
+type columnDelegate struct {
+	levelDelegate
+	columns []Column
+}
+
+func newColumnDelegate(levels levelDelegate, columns []Column) columnDelegate {
+	return columnDelegate{levelDelegate: levels, columns: columns}
+}
+
+func (d columnDelegate) Height() int                         { return 1 }
+func (d columnDelegate) Spacing() int                        { return 0 }
+func (d columnDelegate) Update(tea.Msg, *list.Model) tea.Cmd { return nil }
+
+// Render draws the row with the styles of the default delegate's title
+// line, so that the selection looks as without columns.
+func (d columnDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	li, ok := item.(logItem)
+	if !ok {
+		return
+	}
+	style := d.Styles.NormalTitle
+	if index == m.Index() {
+		style = d.Styles.SelectedTitle
+	} else if ls, ok := d.style(entryLevel(li.entry, d.field)); ok {
+		style = ls.apply(style)
+	}
+	width := m.Width() - style.GetHorizontalFrameSize()
+	markers := li.markers()
+	row := markers + d.row(func(c Column) string {
+		if c.Field == "@timestamp" {
+			return li.timestamp()
+		}
+		return columnValue(li.entry, c.Field)
+	}, width-lipgloss.Width(markers))
+	fmt.Fprint(w, style.Render(row))
+}
+
+// tableHeader returns the list title followed by the column titles, drawn
+// above the list when it is a table.
+func (m Model) tableHeader(width int) string {
+	title := m.list.Styles.TitleBar.Render(m.list.Styles.Title.Render(m.list.Title))
+	return lipgloss.JoinVertical(lipgloss.Left, title, m.columns.header(width))
+}
+
+// header returns the line of column titles for a list width wide.
+func (d columnDelegate) header(width int) string {
+	style := d.Styles.NormalTitle.Bold(true).Faint(true)
+	return style.Render(d.row(func(c Column) string {
+		if c.Title != "" {
+			return c.Title
+		}
+		return c.Field
+	}, width-style.GetHorizontalFrameSize()))
+}
+
+// row lays out the values of the columns in width cells.
+func (d columnDelegate) row(value func(Column) string, width int) string {
+	widths := d.widths(width)
+	cells := make([]string, len(d.columns))
+	for i, c := range d.columns {
+		cells[i] = fitCell(sanitizeText(value(c), false), widths[i], c.Right)
+	}
+	return strings.TrimRight(strings.Join(cells, columnGap), " ")
+}
+
+// widths returns the width of each column; the columns without a width
+// share what is left, at least one cell each.
+func (d columnDelegate) widths(total int) []int {
+	widths := make([]int, len(d.columns))
+	left := total - len(columnGap)*(len(d.columns)-1)
+	flexible := 0
+	for i, c := range d.columns {
+		if c.Width > 0 {
+			widths[i] = c.Width
+			left -= c.Width
+		} else {
+			flexible++
+		}
+	}
+	for i, c := range d.columns {
+		if c.Width == 0 {
+			widths[i] = max(left/flexible, 1)
+		}
+	}
+	return widths
+}
+
+// fitCell truncates or pads s to exactly width cells.
+func fitCell(s string, width int, right bool) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if lipgloss.Width(s) > width {
+		if width <= 1 {
+			return truncateWidth(s, width)
+		}
+		s = truncateWidth(s, width-1) + "…"
+	}
+	pad := strings.Repeat(" ", width-lipgloss.Width(s))
+	if right {
+		return pad + s
+	}
+	return s + pad
+}
+
+// columnValue returns what the column of field shows for entry.
+func columnValue(entry logs.LogEntry, field string) string {
+	switch field {
+	case "@timestamp":
+		return entry.DisplayTimestamp()
+	case "@level":
+		return entry.Level()
+	case "@message":
+		if entry.Message == "" {
+			return entry.RawPreview()
+		}
+		return entry.Message
+	case "@file":
+		return filepath.Base(entry.Path)
+	}
+	if v, ok := entry.Field(field); ok {
+		return logs.FormatValue(v)
+	}
+	return entry.ExtraValue(field)
+}
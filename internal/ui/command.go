@@ -0,0 +1,314 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcuzy/logsviewer/internal/export"
+	"github.com/marcuzy/logsviewer/internal/logs"
+	"github.com/marcuzy/logsviewer/internal/report"
+)
+
+// uiCommand implements a ":" command given the text after its name. The
+// returned error is shown in the status line; the command may start work
+// in the background by returning a tea.Cmd.
+type uiCommand func(m *Model, args string) (tea.Cmd, error)
+
+var commands = map[string]uiCommand{
+	"export":   (*Model).exportCommand,
+	"write":    (*Model).exportCommand,
+	"bundle":   (*Model).bundleCommand,
+	"report":   (*Model).reportCommand,
+	"pipe":     (*Model).pipeCommandLine,
+	"pipe-all": (*Model).pipeAllCommand,
+	"search":   (*Model).searchCommand,
+	"jump":     (*Model).jumpCommand,
+	"send":     (*Model).sendCommand,
+	"watch":    (*Model).watchCommand,
+	"unwatch":  (*Model).unwatchCommand,
+	"snooze":   (*Model).snoozeCommand,
+	"unsnooze": (*Model).unsnoozeCommand,
+	"filter":   (*Model).filterCommand,
+	"count":    (*Model).countCommand,
+	"extract":  (*Model).extractCommand,
+	"skew":     (*Model).skewCommand,
+	"note":     (*Model).noteCommand,
+	"marks":    (*Model).marksCommand,
+	"sql":      (*Model).sqlCommand,
+	"preset":   (*Model).presetCommand,
+
+	"pause-source":  (*Model).pauseSourceCommand,
+	"resume-source": (*Model).resumeSourceCommand,
+}
+
+func (m *Model) beginCommand() {
+	m.commandActive = true
+	m.focus = focusList
+	m.commandInput.SetValue("")
+	m.commandInput.Focus()
+}
+
+func (m *Model) cancelCommand() {
+	m.commandActive = false
+	m.commandInput.Blur()
+	m.commandInput.SetValue("")
+}
+
+// runCommand executes the command line typed after ":".
+func (m *Model) runCommand(line string) tea.Cmd {
+	m.cancelCommand()
+	cmd, err := m.execCommand(line)
+	if err != nil {
+		m.errorMessage = err.Error()
+	}
+	return cmd
+}
+
+// execCommand parses and executes a command line without the leading ":".
+func (m *Model) execCommand(line string) (tea.Cmd, error) {
+	name, args, _ := strings.Cut(strings.TrimSpace(line), " ")
+	if name == "" {
+		return nil, nil
+	}
+	command, ok := commands[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown command %q", name)
+	}
+	cmd, err := command(m, strings.TrimSpace(args))
+	if err != nil {
+		return cmd, fmt.Errorf("%s: %w", name, err)
+	}
+	return cmd, nil
+}
+
+// exportCommand writes the entries currently shown in the list to a file:
+// ":export path [format] [col1,col2,...]". Without a format it is derived
+// from the file extension; the column list applies to CSV and Markdown.
+// With a visual selection active only the selected range is written,
+// preceded by its sources and time range.
+func (m *Model) exportCommand(line string) (tea.Cmd, error) {
+	args := strings.Fields(line)
+	if len(args) == 0 || len(args) > 3 {
+		return nil, fmt.Errorf("usage: export <file> [ndjson|json|text|csv|markdown] [columns]")
+	}
+	path := args[0]
+	opts := export.Options{Format: export.FormatFor(path)}
+	for i, arg := range args[1:] {
+		if f, err := export.ParseFormat(arg); err == nil && i == 0 {
+			opts.Format = f
+			continue
+		}
+		opts.Columns = strings.Split(arg, ",")
+	}
+	if opts.Columns != nil && !opts.Format.Tabular() {
+		return nil, fmt.Errorf("columns apply to csv and markdown only, got format %s", opts.Format)
+	}
+	if opts.Format.Tabular() && opts.Columns == nil {
+		opts.Columns = append([]string(nil), export.DefaultColumns...)
+		for _, name := range m.extraFields {
+			if name != "level" && name != "@file" {
+				opts.Columns = append(opts.Columns, name)
+			}
+		}
+	}
+
+	entries := m.visibleEntries()
+	if selected := m.selectedEntries(); selected != nil {
+		entries = selected
+		meta := export.Describe(entries)
+		opts.Meta = &meta
+		m.visualAnchor = ""
+	}
+	if err := export.WriteFile(path, entries, opts); err != nil {
+		return nil, err
+	}
+	m.statusMessage = fmt.Sprintf("exported %d entries to %s", len(entries), path)
+	return nil, nil
+}
+
+// defaultBundleContext is how many entries per source and direction
+// :bundle includes by default.
+const defaultBundleContext = 20
+
+// bundleCommand writes the selected entry together with the entries around
+// it from every source: ":bundle path [N]".
+func (m *Model) bundleCommand(line string) (tea.Cmd, error) {
+	args := strings.Fields(line)
+	if len(args) == 0 || len(args) > 2 {
+		return nil, fmt.Errorf("usage: bundle <file> [entries per source]")
+	}
+	n := defaultBundleContext
+	if len(args) == 2 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid context size %q", args[1])
+		}
+		n = v
+	}
+	item, ok := m.list.SelectedItem().(logItem)
+	if !ok {
+		return nil, fmt.Errorf("no entry selected")
+	}
+	context := export.ContextAround(m.entries.Oldest(0), item.entry, n)
+	if err := export.WriteBundle(args[0], item.entry, context); err != nil {
+		return nil, err
+	}
+	m.statusMessage = fmt.Sprintf("bundled %d entries to %s", len(context), args[0])
+	return nil, nil
+}
+
+// reportCommand writes a summary of the buffered entries: ":report path".
+// Files ending in .md get Markdown, others plain text.
+func (m *Model) reportCommand(line string) (tea.Cmd, error) {
+	args := strings.Fields(line)
+	if len(args) != 1 {
+		return nil, fmt.Errorf("usage: report <file.md|file.txt>")
+	}
+	path := args[0]
+	summary := report.Build(m.entries.Oldest(0))
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".md") {
+		err = summary.WriteMarkdown(file)
+	} else {
+		err = summary.WriteText(file)
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.statusMessage = fmt.Sprintf("report of %d entries written to %s", summary.Entries, path)
+	return nil, nil
+}
+
+// pipeCommandLine pipes the selection into a command: ":pipe [command]".
+// Without a command the configured pipe_command is used.
+func (m *Model) pipeCommandLine(command string) (tea.Cmd, error) {
+	entries := m.pipeTargets()
+	m.visualAnchor = ""
+	return m.pipeEntries(command, entries)
+}
+
+// pipeAllCommand pipes every entry shown in the list, oldest first:
+// ":pipe-all [command]".
+func (m *Model) pipeAllCommand(command string) (tea.Cmd, error) {
+	return m.pipeEntries(command, m.visibleEntries())
+}
+
+// searchCommand applies a search as "/" does: ":search [text]". Without
+// text the search is cleared.
+func (m *Model) searchCommand(query string) (tea.Cmd, error) {
+	m.commitSearch(query)
+	return nil, nil
+}
+
+// jumpTimeLayouts are the time formats accepted by :jump. Those without a
+// date refer to the day of the selected entry. Times without a zone are in
+// the zone the list shows.
+var jumpTimeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "15:04:05", "15:04"}
+
+// jumpCommand selects the first shown entry at or after a time:
+// ":jump 2024-05-01T10:30:00Z" or ":jump 10:30".
+func (m *Model) jumpCommand(arg string) (tea.Cmd, error) {
+	if arg == "" {
+		return nil, fmt.Errorf("usage: jump <time>")
+	}
+	loc := m.displayLocation()
+	ref := time.Now().In(loc)
+	if item, ok := m.list.SelectedItem().(logItem); ok && !item.entry.Timestamp.IsZero() {
+		ref = item.entry.Timestamp.In(loc)
+	}
+	var (
+		target time.Time
+		err    error
+	)
+	for _, layout := range jumpTimeLayouts {
+		if target, err = time.ParseInLocation(layout, arg, loc); err == nil {
+			if !strings.Contains(layout, "2006") {
+				target = time.Date(ref.Year(), ref.Month(), ref.Day(),
+					target.Hour(), target.Minute(), target.Second(), 0, loc)
+			}
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid time %q", arg)
+	}
+	if m.spill != nil && m.tab == "" && !m.narrowed() && m.beforeDisplayed(target) {
+		if m.spillWindow {
+			m.rebuildList()
+		}
+		if ok, err := m.jumpIntoSpill(target); ok || err != nil {
+			return nil, err
+		}
+	}
+	// The list shows the newest entry first.
+	for i := len(m.displayEntries) - 1; i >= 0; i-- {
+		ts := m.displayEntries[i].Timestamp
+		if !ts.IsZero() && !ts.Before(target) {
+			m.list.Select(i)
+			m.needViewportSync = true
+			m.statusMessage = "jumped to " + m.displayTime(m.displayEntries[i])
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("no entry at or after %s", target.Format(time.RFC3339))
+}
+
+// beforeDisplayed reports whether t is earlier than every listed entry
+// with a timestamp.
+func (m Model) beforeDisplayed(t time.Time) bool {
+	for i := len(m.displayEntries) - 1; i >= 0; i-- {
+		if ts := m.displayEntries[i].Timestamp; !ts.IsZero() {
+			return t.Before(ts)
+		}
+	}
+	return true
+}
+
+// spillJumpWindow is how many spilled entries :jump lists from the time it
+// jumps to.
+const spillJumpWindow = 500
+
+// jumpIntoSpill lists the spilled entries from target on below the buffer
+// and selects the first of them, for a time already evicted from memory.
+// The next rebuild of the list drops them again.
+func (m *Model) jumpIntoSpill(target time.Time) (bool, error) {
+	window, err := m.spill.Since(target, spillJumpWindow)
+	if err != nil || len(window) == 0 {
+		return false, err
+	}
+	extraField := m.currentExtraField()
+	items := m.list.Items()
+	for i := len(window) - 1; i >= 0; i-- {
+		m.displayEntries = append(m.displayEntries, window[i])
+		items = append(items, m.newLogItem(window[i], extraField))
+	}
+	m.list.SetItems(items)
+	m.spillWindow = true
+	m.searchMatchCount = len(m.displayEntries)
+	last := len(m.displayEntries) - 1
+	m.list.Select(last)
+	m.needViewportSync = true
+	m.statusMessage = fmt.Sprintf("jumped to %s (%d entries from disk)", m.displayTime(m.displayEntries[last]), len(window))
+	return true, nil
+}
+
+// visibleEntries returns the entries shown in the list, oldest first.
+func (m Model) visibleEntries() []logs.LogEntry {
+	out := make([]logs.LogEntry, len(m.displayEntries))
+	for i, entry := range m.displayEntries {
+		out[len(out)-1-i] = entry
+	}
+	return out
+}
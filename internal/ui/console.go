@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// consoleTopValues is how many values :count lists, and consoleMaxLines
+// how many :extract shows.
+const (
+	consoleTopValues = 30
+	consoleMaxLines  = 5000
+)
+
+// filterCommand narrows the list to entries matching a filter expression,
+// on top of the / search: ":filter status>=500". Without an expression it
+// removes the filter.
+func (m *Model) filterCommand(expr string) (tea.Cmd, error) {
+	filter, err := logs.ParseFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	key := m.selectionKey()
+	m.listFilter, m.listFilterExpr, m.listFilterName = filter, expr, ""
+	m.rebuildList()
+	m.selectEntryKey(key)
+	if expr == "" {
+		m.statusMessage = "filter removed"
+	} else {
+		m.statusMessage = fmt.Sprintf("filter: %d entries", len(m.displayEntries))
+	}
+	return nil, nil
+}
+
+// countCommand tallies the values of a field or expression over the
+// buffer: ":count level", ":count method + \" \" + path where status>=500".
+func (m *Model) countCommand(line string) (tea.Cmd, error) {
+	field, entries, err := m.consoleQuery("count", line)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	total := 0
+	for _, entry := range entries {
+		if v, ok := field.Value(entry); ok {
+			counts[v]++
+			total++
+		}
+	}
+	values := make([]string, 0, len(counts))
+	for v := range counts {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+		return values[i] < values[j]
+	})
+	var b strings.Builder
+	fmt.Fprintf(&b, "count %s\n%d of %d entries have it, %d distinct values\n\n", line, total, len(entries), len(values))
+	for i, v := range values {
+		if i == consoleTopValues {
+			fmt.Fprintf(&b, "... %d more\n", len(values)-i)
+			break
+		}
+		fmt.Fprintf(&b, "%7d  %5.1f%%  %s\n", counts[v], percentOf(int64(counts[v]), int64(total)), v)
+	}
+	m.showConsoleOutput(b.String())
+	return nil, nil
+}
+
+// extractCommand lists the value of a field or expression for every
+// entry that has it, oldest first: ":extract latency_ms / 1000".
+func (m *Model) extractCommand(line string) (tea.Cmd, error) {
+	field, entries, err := m.consoleQuery("extract", line)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	n := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		v, ok := field.Value(entries[i])
+		if !ok {
+			continue
+		}
+		if n == consoleMaxLines {
+			b.WriteString("...\n")
+			break
+		}
+		n++
+		fmt.Fprintf(&b, "%s  %s\n", m.displayTime(entries[i]), v)
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("no entry has %s", line)
+	}
+	m.showConsoleOutput(b.String())
+	return nil, nil
+}
+
+// consoleQuery parses "expression [where filter]" and returns the
+// expression with the listed entries that match the filter, newest first.
+func (m Model) consoleQuery(name, line string) (logs.ComputedField, []logs.LogEntry, error) {
+	expr, where, _ := strings.Cut(line, " where ")
+	if strings.TrimSpace(expr) == "" {
+		return logs.ComputedField{}, nil, fmt.Errorf("usage: %s <field or expression> [where <filter>]", name)
+	}
+	field, err := logs.ParseComputedField("value = " + expr)
+	if err != nil {
+		return logs.ComputedField{}, nil, err
+	}
+	filter, err := logs.ParseFilter(where)
+	if err != nil {
+		return logs.ComputedField{}, nil, err
+	}
+	filter = filter.In(m.displayLocation())
+	var entries []logs.LogEntry
+	for _, entry := range m.displayEntries {
+		if filter.Match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return field, entries, nil
+}
+
+// showConsoleOutput shows command output in the detail pane until the
+// selection changes, as for :pipe.
+func (m *Model) showConsoleOutput(text string) {
+	m.debug, m.showWatches, m.showSources = false, false, false
+	m.viewport.SetContent(text)
+	m.viewport.GotoTop()
+}
@@ -0,0 +1,24 @@
+package ui
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// ControlMsg asks a running Model to execute a ":" command line on behalf
+// of an external client, such as the control socket. The outcome is sent
+// on Reply, which must have room for one value.
+type ControlMsg struct {
+	Command string
+	Reply   chan<- ControlReply
+}
+
+// ControlReply is the outcome of a ControlMsg: the status line after the
+// command, or the error it failed with.
+type ControlReply struct {
+	Status string
+	Err    error
+}
+
+func (m *Model) handleControl(msg ControlMsg) tea.Cmd {
+	cmd, err := m.execCommand(msg.Command)
+	msg.Reply <- ControlReply{Status: m.statusMessage, Err: err}
+	return cmd
+}
@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// debugRefreshInterval is how often the debug and sources panels refresh
+// while open.
+const debugRefreshInterval = time.Second
+
+type debugTickMsg struct{}
+
+func debugTick() tea.Cmd {
+	return tea.Tick(debugRefreshInterval, func(time.Time) tea.Msg {
+		return debugTickMsg{}
+	})
+}
+
+// toggleDebug shows or hides the pipeline debug panel in place of the detail
+// pane.
+func (m *Model) toggleDebug() tea.Cmd {
+	m.debug = !m.debug
+	m.needViewportSync = true
+	if !m.debug {
+		return nil
+	}
+	m.showWatches = false
+	m.showSources = false
+	return debugTick()
+}
+
+// debugView renders the state of the ingestion pipeline: how far each source
+// has been read compared to its size on disk, how full the queues between
+// the tailer and the list are, and runtime counters.
+func (m Model) debugView() string {
+	var b strings.Builder
+	b.WriteString("Pipeline (D to close)\n\n")
+
+	if m.stats != nil {
+		b.WriteString("Sources:\n")
+		for _, s := range m.stats() {
+			behind := ""
+			if info, err := os.Stat(s.Path); err == nil && info.Size() > s.Offset {
+				behind = fmt.Sprintf("  behind %d B", info.Size()-s.Offset)
+			}
+			fmt.Fprintf(&b, "  %s\n    offset %d%s\n    delivered %d  dropped %d  parse errors %d (%.1f%%)\n",
+				s.Path, s.Offset, behind, s.Delivered, s.Dropped, s.ParseErrors,
+				percentOf(s.ParseErrors, s.Delivered+s.ParseErrors))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("Queues:\n")
+	if m.entryCh != nil {
+		fmt.Fprintf(&b, "  entries channel %d/%d\n", len(m.entryCh), cap(m.entryCh))
+	}
+	if m.errCh != nil {
+		fmt.Fprintf(&b, "  errors channel  %d/%d\n", len(m.errCh), cap(m.errCh))
+	}
+	fmt.Fprintf(&b, "  pending refresh %d\n", len(m.pending))
+	fmt.Fprintf(&b, "  buffered        %d\n", m.entries.Len())
+	if m.spill != nil {
+		fmt.Fprintf(&b, "  on disk         %d\n", m.spill.Len())
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	b.WriteString("\nRuntime:\n")
+	fmt.Fprintf(&b, "  goroutines %d\n", runtime.NumGoroutine())
+	fmt.Fprintf(&b, "  heap %s in use, %d objects\n", formatBytes(mem.HeapInuse), mem.HeapObjects)
+	fmt.Fprintf(&b, "  gc runs %d, pause total %s, last pause %s\n",
+		mem.NumGC, time.Duration(mem.PauseTotalNs), time.Duration(mem.PauseNs[(mem.NumGC+255)%256]))
+	return b.String()
+}
+
+func percentOf(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/template"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// defaultCallerFields are checked for a source location when caller_fields
+// is not configured: zap and zerolog use "caller", slog "source", logrus
+// "file".
+var defaultCallerFields = []string{"caller", "source", "file"}
+
+// sourceLocation is the data of editor command templates.
+type sourceLocation struct {
+	File string
+	Line int
+}
+
+type editorDoneMsg struct {
+	err error
+}
+
+// callerLocation finds the source location of entry in the first caller
+// field present. Values may be "path/file.go:42" (an optional column is
+// ignored) or an object with "file" and "line" keys.
+func callerLocation(entry logs.LogEntry, fields []string) (sourceLocation, bool) {
+	if len(fields) == 0 {
+		fields = defaultCallerFields
+	}
+	for _, name := range fields {
+		value, _ := entry.Field(name)
+		switch v := value.(type) {
+		case string:
+			if loc, ok := parseFileLine(v); ok {
+				return loc, true
+			}
+		case map[string]any:
+			file, _ := v["file"].(string)
+			line, _ := strconv.Atoi(logs.FormatValue(v["line"]))
+			if file != "" {
+				return sourceLocation{File: file, Line: line}, true
+			}
+		}
+	}
+	return sourceLocation{}, false
+}
+
+func parseFileLine(s string) (sourceLocation, bool) {
+	s = strings.TrimSpace(s)
+	file, rest, ok := strings.Cut(s, ":")
+	for ok {
+		// Skip colons that are part of the path, e.g. C:\ on Windows.
+		lineText, _, _ := strings.Cut(rest, ":")
+		if line, err := strconv.Atoi(lineText); err == nil {
+			return sourceLocation{File: file, Line: line}, file != ""
+		}
+		var more string
+		more, rest, ok = strings.Cut(rest, ":")
+		file += ":" + more
+	}
+	return sourceLocation{}, false
+}
+
+// editorCommand returns the configured editor template, falling back to
+// $VISUAL or $EDITOR with the "+line file" convention most editors accept.
+func (m Model) editorCommand() string {
+	if m.editor != "" {
+		return m.editor
+	}
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := os.Getenv(env); editor != "" {
+			return editor + " +{{.Line}} {{quote .File}}"
+		}
+	}
+	return ""
+}
+
+// openInEditor opens the source location of the selected entry. The editor
+// gets the terminal until it exits, so terminal editors work as well.
+func (m *Model) openInEditor() tea.Cmd {
+	item, ok := m.list.SelectedItem().(logItem)
+	if !ok {
+		return nil
+	}
+	loc, ok := callerLocation(item.entry, m.callerFields)
+	if !ok {
+		m.errorMessage = "no source location in this entry"
+		return nil
+	}
+	command := m.editorCommand()
+	if command == "" {
+		m.errorMessage = "editor_command is not configured and $EDITOR is not set"
+		return nil
+	}
+	tmpl, err := template.New("editor").Funcs(templateFuncs).Parse(command)
+	if err != nil {
+		m.errorMessage = fmt.Sprintf("editor: %v", err)
+		return nil
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, loc); err != nil {
+		m.errorMessage = fmt.Sprintf("editor: %v", err)
+		return nil
+	}
+	m.statusMessage = fmt.Sprintf("opening %s:%d", loc.File, loc.Line)
+	return tea.ExecProcess(exec.Command("sh", "-c", b.String()), func(err error) tea.Msg {
+		return editorDoneMsg{err: err}
+	})
+}
@@ -0,0 +1,150 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// jsonClass is the kind of JSON token a byte of the detail belongs to.
+type jsonClass uint8
+
+const (
+	classPlain jsonClass = iota
+	classKey
+	classString
+	classNumber
+	classLiteral
+)
+
+var jsonStyles = [...]lipgloss.Style{
+	classPlain:   lipgloss.NewStyle(),
+	classKey:     lipgloss.NewStyle().Foreground(lipgloss.Color("6")),
+	classString:  lipgloss.NewStyle().Foreground(lipgloss.Color("2")),
+	classNumber:  lipgloss.NewStyle().Foreground(lipgloss.Color("5")),
+	classLiteral: lipgloss.NewStyle().Foreground(lipgloss.Color("3")),
+}
+
+// highlightDetail colors the keys, strings, numbers and literals of the
+// pretty-printed JSON in content and shows the occurrences of words, the
+// text being searched for, in reverse video on top of that. Content that is
+// not JSON only gets the search matches.
+func highlightDetail(content string, words []string) string {
+	classes := make([]jsonClass, len(content))
+	if strings.HasPrefix(content, "{") || strings.HasPrefix(content, "[") {
+		classifyJSON(content, classes)
+	}
+	matched := searchMatches(content, words)
+	if matched == nil && !strings.HasPrefix(content, "{") && !strings.HasPrefix(content, "[") {
+		return content
+	}
+	var b strings.Builder
+	b.Grow(len(content) * 2)
+	for start := 0; start < len(content); {
+		if content[start] == '\n' {
+			b.WriteByte('\n')
+			start++
+			continue
+		}
+		isMatch := matched != nil && matched[start]
+		end := start + 1
+		for end < len(content) && content[end] != '\n' && classes[end] == classes[start] &&
+			(matched != nil && matched[end]) == isMatch {
+			end++
+		}
+		style := jsonStyles[classes[start]]
+		if isMatch {
+			style = style.Reverse(true)
+		}
+		run := content[start:end]
+		if classes[start] == classPlain && !isMatch {
+			b.WriteString(run)
+		} else {
+			b.WriteString(style.Render(run))
+		}
+		start = end
+	}
+	return b.String()
+}
+
+// classifyJSON marks the tokens of JSON text in classes; it is lenient, so
+// that a truncation note or other trailing text does not matter.
+func classifyJSON(s string, classes []jsonClass) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' && s[end] != '\n' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(s))
+			class := classString
+			rest := strings.TrimLeft(s[end:], " \t")
+			if strings.HasPrefix(rest, ":") {
+				class = classKey
+			}
+			fillClass(classes[i:end], class)
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(s) && strings.IndexByte("0123456789.eE+-", s[end]) >= 0 {
+				end++
+			}
+			fillClass(classes[i:end], classNumber)
+			i = end
+		case c == 't' || c == 'f' || c == 'n':
+			end := i
+			for end < len(s) && s[end] >= 'a' && s[end] <= 'z' {
+				end++
+			}
+			switch s[i:end] {
+			case "true", "false", "null":
+				fillClass(classes[i:end], classLiteral)
+			}
+			i = max(end, i+1)
+		default:
+			i++
+		}
+	}
+}
+
+func fillClass(classes []jsonClass, class jsonClass) {
+	for i := range classes {
+		classes[i] = class
+	}
+}
+
+// searchMatches marks the bytes of content that are part of a
+// case-insensitive occurrence of one of words, or returns nil if there is
+// none.
+func searchMatches(content string, words []string) []bool {
+	if len(words) == 0 {
+		return nil
+	}
+	lower := strings.ToLower(content)
+	if len(lower) != len(content) {
+		// Lower-casing changed the length, so offsets would not line up.
+		lower = content
+	}
+	var matched []bool
+	for _, word := range words {
+		for from := 0; ; {
+			i := strings.Index(lower[from:], word)
+			if i < 0 {
+				break
+			}
+			if matched == nil {
+				matched = make([]bool, len(content))
+			}
+			for j := from + i; j < from+i+len(word); j++ {
+				matched[j] = true
+			}
+			from += i + len(word)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"fmt"
+	"os/exec"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// KeyAction binds a key to a shell command, a template like PipeCommand
+// expanded for the selected entry: {{quote .Fields.order_id}}.
+type KeyAction struct {
+	// Key is written as Bubble Tea names keys, e.g. "ctrl+o" or "O".
+	Key     string
+	Command string
+	// Interactive gives the command the terminal until it exits, as for
+	// the editor; otherwise it runs like | with the entries on stdin and
+	// its output shown in the detail pane.
+	Interactive bool
+}
+
+type keyActionDoneMsg struct {
+	key string
+	err error
+}
+
+// runKeyAction runs the action bound to a key for the selection, or the
+// selected entry.
+func (m *Model) runKeyAction(action KeyAction) tea.Cmd {
+	entries := m.pipeTargets()
+	if len(entries) == 0 {
+		m.errorMessage = "no entry selected"
+		return nil
+	}
+	if !action.Interactive {
+		cmd, err := m.pipeEntries(action.Command, entries)
+		if err != nil {
+			m.errorMessage = fmt.Sprintf("%s: %v", action.Key, err)
+		}
+		return cmd
+	}
+	expanded, err := renderEntryTemplate(action.Command, entries[0])
+	if err != nil {
+		m.errorMessage = fmt.Sprintf("%s: %v", action.Key, err)
+		return nil
+	}
+	m.statusMessage = "running " + expanded
+	key := action.Key
+	return tea.ExecProcess(exec.Command("sh", "-c", expanded), func(err error) tea.Msg {
+		return keyActionDoneMsg{key: key, err: err}
+	})
+}
+
+func (m *Model) handleKeyActionDone(msg keyActionDoneMsg) {
+	if msg.err != nil {
+		m.errorMessage = fmt.Sprintf("%s: %v", msg.key, msg.err)
+	}
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Layouts accepted by the layout option.
+const (
+	// LayoutVertical puts the list left of the detail pane.
+	LayoutVertical = "vertical"
+	// LayoutHorizontal puts the list above the detail pane.
+	LayoutHorizontal = "horizontal"
+	// LayoutAuto is horizontal on terminals narrower than autoLayoutWidth
+	// and vertical otherwise.
+	LayoutAuto = "auto"
+)
+
+const (
+	autoLayoutWidth = 100
+	// splitStep is how much + and - move the split between the panes.
+	splitStep = 0.05
+	minSplit  = 0.2
+	maxSplit  = 0.8
+)
+
+// horizontal reports whether the list is shown above the detail pane.
+func (m Model) horizontal() bool {
+	switch m.layout {
+	case LayoutHorizontal:
+		return true
+	case LayoutAuto:
+		return m.width < autoLayoutWidth
+	}
+	return false
+}
+
+// splitRatio returns the share of the screen the list takes.
+func (m Model) splitRatio() float64 {
+	if m.split <= 0 {
+		return 0.5
+	}
+	return m.split
+}
+
+// resizeSplit grows (delta > 0) or shrinks the list pane.
+func (m *Model) resizeSplit(delta float64) {
+	m.split = min(max(m.splitRatio()+delta, minSplit), maxSplit)
+	m.relayout()
+	m.statusMessage = fmt.Sprintf("list: %.0f%%", m.split*100)
+}
+
+// toggleLayout switches between the list beside and above the detail pane.
+func (m *Model) toggleLayout() {
+	if m.horizontal() {
+		m.layout = LayoutVertical
+	} else {
+		m.layout = LayoutHorizontal
+	}
+	m.relayout()
+	m.statusMessage = "layout: " + m.layout
+}
+
+func (m *Model) relayout() {
+	if m.ready {
+		m.handleWindowSize(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+	}
+}
@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// defaultLevelColors colors rows by the usual severity names; info and
+// anything unlisted keep the default color.
+var defaultLevelColors = map[string]string{
+	"fatal":     "red",
+	"panic":     "red",
+	"critical":  "red",
+	"crit":      "red",
+	"emergency": "red",
+	"emerg":     "red",
+	"alert":     "red",
+	"error":     "red",
+	"err":       "red",
+	"warn":      "yellow",
+	"warning":   "yellow",
+	"debug":     "dim",
+	"trace":     "dim",
+}
+
+// colorNames maps the color names accepted in level_colors to terminal
+// colors; other values are passed to lipgloss as is ("208", "#ff8800").
+var colorNames = map[string]string{
+	"black":   "0",
+	"red":     "1",
+	"green":   "2",
+	"yellow":  "3",
+	"blue":    "4",
+	"magenta": "5",
+	"cyan":    "6",
+	"white":   "7",
+	"gray":    "8",
+	"grey":    "8",
+}
+
+// levelDelegate draws list rows in the color of their entry's level.
+type levelDelegate struct {
+	list.DefaultDelegate
+	// field is where the level is read from; empty means the usual level
+	// fields.
+	field  string
+	styles map[string]levelStyle
+}
+
+type levelStyle struct {
+	color lipgloss.TerminalColor
+	faint bool
+}
+
+func (s levelStyle) apply(style lipgloss.Style) lipgloss.Style {
+	if s.faint {
+		return style.Faint(true)
+	}
+	return style.Foreground(s.color)
+}
+
+// newLevelDelegate returns base coloring rows by level. colors, from the
+// configuration, add to and override the defaults; "default" or "none"
+// leaves a level uncolored.
+func newLevelDelegate(base list.DefaultDelegate, field string, colors map[string]string) levelDelegate {
+	merged := make(map[string]string, len(defaultLevelColors)+len(colors))
+	for level, color := range defaultLevelColors {
+		merged[level] = color
+	}
+	for level, color := range colors {
+		merged[strings.ToLower(level)] = strings.ToLower(strings.TrimSpace(color))
+	}
+	d := levelDelegate{DefaultDelegate: base, field: field, styles: make(map[string]levelStyle)}
+	for level, color := range merged {
+		switch color {
+		case "", "default", "none":
+			continue
+		case "dim", "faint":
+			d.styles[level] = levelStyle{faint: true}
+		default:
+			if c, ok := colorNames[color]; ok {
+				color = c
+			}
+			d.styles[level] = levelStyle{color: lipgloss.Color(color)}
+		}
+	}
+	return d
+}
+
+// entryLevel returns the lower-cased level of entry read from field, or
+// from the usual level fields if field is empty.
+func entryLevel(entry logs.LogEntry, field string) string {
+	if field == "" {
+		return entry.Level()
+	}
+	if v, ok := entry.Field(field); ok {
+		return strings.ToLower(logs.FormatValue(v))
+	}
+	return strings.ToLower(entry.Extras[field])
+}
+
+// style returns the style for level, falling back to the one of its
+// normalized severity so that e.g. pino's 50 is colored as error.
+func (d levelDelegate) style(level string) (levelStyle, bool) {
+	if style, ok := d.styles[level]; ok {
+		return style, true
+	}
+	style, ok := d.styles[logs.ParseSeverity(level).String()]
+	return style, ok
+}
+
+// Render draws the row with the level style applied to the unselected
+// title and description; the selected row keeps its highlight.
+func (d levelDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	if li, ok := item.(logItem); ok {
+		if style, ok := d.style(entryLevel(li.entry, d.field)); ok {
+			dd := d.DefaultDelegate
+			dd.Styles.NormalTitle = style.apply(dd.Styles.NormalTitle)
+			dd.Styles.NormalDesc = style.apply(dd.Styles.NormalDesc)
+			dd.Render(w, m, index, item)
+			return
+		}
+	}
+	d.DefaultDelegate.Render(w, m, index, item)
+}
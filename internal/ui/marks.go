@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+	"github.com/marcuzy/logsviewer/internal/store"
+)
+
+// markable reports whether entry comes from a file, whose marks can be
+// kept by offset, rather than stdin or a source processor.
+func markable(entry logs.LogEntry) bool {
+	return entry.Path != "" && entry.Path != logs.StdinPath && !strings.HasPrefix(entry.Path, "plugin:")
+}
+
+func (m Model) entryMark(entry logs.LogEntry) (store.Mark, bool) {
+	if m.marks == nil || !markable(entry) {
+		return store.Mark{}, false
+	}
+	return m.marks.Get(entry)
+}
+
+// updateMark changes the mark of the selected entry with change, saves it
+// and redraws the row.
+func (m *Model) updateMark(change func(*store.Mark)) (store.Mark, error) {
+	item, ok := m.list.SelectedItem().(logItem)
+	if !ok {
+		return store.Mark{}, fmt.Errorf("no entry selected")
+	}
+	if !markable(item.entry) {
+		return store.Mark{}, fmt.Errorf("entries of %s cannot be marked", item.entry.Path)
+	}
+	mark := item.mark
+	change(&mark)
+	if err := m.marks.Set(item.entry, mark); err != nil {
+		return store.Mark{}, fmt.Errorf("save marks: %w", err)
+	}
+	item.mark = mark
+	m.list.SetItem(m.list.Index(), item)
+	return mark, nil
+}
+
+// toggleBookmark bookmarks the selected entry or removes its bookmark.
+func (m *Model) toggleBookmark() {
+	mark, err := m.updateMark(func(mark *store.Mark) { mark.Bookmark = !mark.Bookmark })
+	if err != nil {
+		m.errorMessage = err.Error()
+		return
+	}
+	if mark.Bookmark {
+		m.statusMessage = "bookmarked"
+	} else {
+		m.statusMessage = "bookmark removed"
+	}
+}
+
+// noteCommand annotates the selected entry: ":note text". Without text the
+// note is removed.
+func (m *Model) noteCommand(text string) (tea.Cmd, error) {
+	mark, err := m.updateMark(func(mark *store.Mark) { mark.Note = strings.TrimSpace(text) })
+	if err != nil {
+		return nil, err
+	}
+	if mark.Note == "" {
+		m.statusMessage = "note removed"
+	} else {
+		m.statusMessage = "note saved"
+	}
+	return nil, nil
+}
+
+// stepMark selects the next (delta 1) or previous (-1) shown entry that is
+// bookmarked or annotated, wrapping around.
+func (m *Model) stepMark(delta int) {
+	count := len(m.displayEntries)
+	idx := max(m.list.Index(), 0)
+	for step := 1; step <= count; step++ {
+		i := ((idx+delta*step)%count + count) % count
+		if _, ok := m.entryMark(m.displayEntries[i]); ok {
+			m.list.Select(i)
+			m.needViewportSync = true
+			return
+		}
+	}
+	m.statusMessage = "no marked entries shown"
+}
+
+// marksCommand lists the buffered entries that are bookmarked or
+// annotated, newest first, including those the search or filter hides.
+func (m *Model) marksCommand(string) (tea.Cmd, error) {
+	var b strings.Builder
+	n, hidden := 0, 0
+	for i := 0; i < m.entries.Len(); i++ {
+		entry := m.entries.At(i)
+		mark, ok := m.entryMark(entry)
+		if !ok {
+			continue
+		}
+		n++
+		flag := " "
+		if mark.Bookmark {
+			flag = "★"
+		}
+		suffix := ""
+		if !m.entryVisible(entry) {
+			suffix = "  (hidden)"
+			hidden++
+		}
+		fmt.Fprintf(&b, "%s %s  %s%s\n", flag, m.displayTime(entry), sanitizeText(entry.Message, false), suffix)
+		if mark.Note != "" {
+			fmt.Fprintf(&b, "    ✎ %s\n", sanitizeText(mark.Note, false))
+		}
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("no marked entries")
+	}
+	header := fmt.Sprintf("%d marked entries", n)
+	if hidden > 0 {
+		header += fmt.Sprintf(", %d hidden by search or filter", hidden)
+	}
+	m.showConsoleOutput(header + "\n\n" + b.String())
+	return nil, nil
+}
@@ -3,7 +3,9 @@ package ui
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -12,25 +14,132 @@ import (
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/marcuzy/logsviewer/internal/logs"
+	"github.com/marcuzy/logsviewer/internal/store"
 )
 
-const statusBarHeight = 1
+const (
+	statusBarHeight = 1
+	prettyCacheSize = 128
+	// maxEntryBatch caps how many waiting entries one message delivers.
+	maxEntryBatch = 1024
+)
 
 // Model implements the Bubble Tea program for the logs viewer.
 type Model struct {
 	list     list.Model
 	viewport viewport.Model
 
-	entries        []logs.LogEntry
+	entries *entryRing
+	spill   *store.Spill
+	// spillWindow is set while entries :jump loaded from the spill are
+	// listed below the buffer.
+	spillWindow  bool
+	spillMatches spillMatches
+	// searchIndex, if enabled, knows which buffered entries contain a
+	// word.
+	searchIndex *searchIndex
+	stats       func() []logs.SourceStats
+
+	refreshRate    int
+	compressRaw    bool
+	pending        []logs.LogEntry
+	flushScheduled bool
 	displayEntries []logs.LogEntry
 
-	entryCh <-chan logs.LogEntry
-	errCh   <-chan error
-	cancel  context.CancelFunc
+	entryCh  <-chan logs.LogEntry
+	errCh    <-chan error
+	sourceCh <-chan logs.SourceEvent
+	cancel   context.CancelFunc
 
 	extraFields     []string
 	extraFieldIndex int
-	maxEntries      int
+
+	timestampField string
+	messageField   string
+	remapFields    bool
+	saveMapping    func(timestampField, messageField string) error
+	pipeCommand    string
+	traceURL       string
+	editor         string
+	callerFields   []string
+	clipboard      string
+	copyFormat     string
+	sendTargets    []SendTarget
+	alerts         *alerts
+	wizard         *fieldWizard
+	wizardSample   []logs.LogEntry
+	wizardChecked  bool
+
+	// tab is the source whose entries alone are listed, empty for all;
+	// sourceEntries buffers up to perSourceItems of each source's entries
+	// for its tab.
+	tab            string
+	sourceEntries  map[string]*entryRing
+	perSourceItems int
+
+	// layout is one of the Layout constants and split the list's share
+	// of the screen.
+	layout string
+	split  float64
+	// columns is the table delegate of the list, nil without columns.
+	columns *columnDelegate
+
+	debug bool
+
+	watches     []*watch
+	showWatches bool
+	showSources bool
+
+	// paused freezes the list; pausedNew counts the entries buffered since.
+	paused    bool
+	pausedNew int
+	// relativeTime shows the age of entries instead of their timestamp.
+	relativeTime bool
+	// location is the zone timestamps are shown in, configLocation the
+	// configured one Z cycles back to.
+	location       *time.Location
+	configLocation *time.Location
+	// follow keeps the newest entry selected; moving the selection away
+	// from it switches to browsing.
+	follow bool
+
+	// lastSeen is when each source last produced an entry, and silent the
+	// sources quiet for longer than silenceTimeout.
+	silenceTimeout time.Duration
+	silenceBell    bool
+	started        time.Time
+	lastSeen       map[string]time.Time
+	silent         map[string]time.Time
+
+	spikes    *rateSpikes
+	rows      *rowTemplates
+	styleANSI bool
+
+	// hiddenSources are paused in display mode, readPaused in read mode
+	// through setSourcePaused.
+	sourcePauseMode string
+	setSourcePaused func(path string, paused bool)
+	hiddenSources   map[string]bool
+	readPaused      map[string]bool
+
+	onViewChange func(ViewState)
+	reportedView ViewState
+
+	marks *store.Marks
+
+	// listFilter narrows the list on top of the search; see :filter.
+	listFilter     logs.Filter
+	listFilterExpr string
+	// listFilterName is the preset the filter came from, if any.
+	listFilterName string
+	filterPresets  map[string]string
+	// minSeverity hides entries below a level; see the l key.
+	minSeverity logs.Severity
+	levelField  string
+
+	// keyActions maps keys to configured commands; they take precedence
+	// over the built-in keys.
+	keyActions map[string]KeyAction
 
 	width  int
 	height int
@@ -42,10 +151,17 @@ type Model struct {
 	searchActive     bool
 	searchInput      textinput.Model
 	searchQuery      string
+	searchFilter     logs.Filter
 	searchMatchCount int
 
+	commandActive bool
+	commandInput  textinput.Model
+	visualAnchor  string
+
 	focus            focusArea
 	needViewportSync bool
+	detailKey        string
+	prettyCache      *renderCache
 
 	styles styles
 }
@@ -70,21 +186,133 @@ func (f focusArea) String() string {
 type Options struct {
 	Entries  <-chan logs.LogEntry
 	Errors   <-chan error
+	Sources  <-chan logs.SourceEvent
 	Cancel   context.CancelFunc
 	Extra    []string
 	MaxItems int
+	// PerSourceItems sizes the buffer each source keeps for its tab; zero
+	// means MaxItems.
+	PerSourceItems int
+	// RefreshRate caps how many times per second incoming entries are
+	// applied to the list; zero applies every entry immediately.
+	RefreshRate int
+	// CompressRaw keeps raw lines deflated in memory, trading CPU on
+	// search and display for a smaller footprint per entry.
+	CompressRaw bool
+
+	// Spill receives entries evicted from the in-memory buffer so they stay
+	// searchable. Optional.
+	Spill *store.Spill
+	// Stats reports per-source delivery counters. Optional.
+	Stats func() []logs.SourceStats
+
+	TimestampField string
+	MessageField   string
+	// SaveMapping persists a field mapping chosen in the mapping wizard.
+	SaveMapping func(timestampField, messageField string) error
+
+	// PipeCommand is the command template the "|" key pipes entries into.
+	PipeCommand string
+	// TraceURL is the URL template the "T" key opens for the selected
+	// entry, e.g. https://jaeger.example.com/trace/{{.Fields.trace_id}}.
+	TraceURL string
+	// EditorCommand opens a source location; {{.File}} and {{.Line}} are
+	// replaced. Defaults to $VISUAL or $EDITOR.
+	EditorCommand string
+	// CallerFields are the fields searched for a source location.
+	CallerFields []string
+	// Clipboard selects how "y" and "Y" copy: ClipboardAuto, ClipboardOS or
+	// ClipboardOSC52.
+	Clipboard string
+	// CopyFormat is what "y" copies of an entry: "raw" for its original
+	// line, "pretty" for indented JSON.
+	CopyFormat string
+	// Layout places the list beside (LayoutVertical, the default) or above
+	// (LayoutHorizontal) the detail pane, or picks by width (LayoutAuto).
+	Layout string
+	// Split is the share of the screen the list takes, 0.5 if zero.
+	Split float64
+	// Columns, if any, draw the list as a table of one line per entry.
+	Columns []Column
+	// SearchIndex indexes the words of buffered entries so that searching
+	// a large buffer does not scan all of it.
+	SearchIndex bool
+	// Location is the zone timestamps are shown in, local time if nil.
+	Location *time.Location
+	// RelativeTime starts with ages ("3s ago") shown instead of
+	// timestamps; R toggles it.
+	RelativeTime bool
+	// SendTargets are the webhooks :send can post entries to.
+	SendTargets []SendTarget
+	// Alerts are checked against every arriving entry.
+	Alerts []AlertRule
+	// NotifyInterval is the minimum time between two desktop notifications
+	// or commands of an alert rule.
+	NotifyInterval time.Duration
+	// AlertCommandLimit caps how many alert commands run at once.
+	AlertCommandLimit int
+	// Watches are filter expressions counted in the watch panel.
+	Watches []string
+	// SilenceTimeout, when positive, flags sources that produce no entries
+	// for this long; SilenceBell also rings the terminal bell.
+	SilenceTimeout time.Duration
+	SilenceBell    bool
+	// RowTemplate and RowDescriptionTemplate replace the two lines of a
+	// list row, rendered like the command templates with styling
+	// functions added.
+	RowTemplate            string
+	RowDescriptionTemplate string
+	// KeyActions bind keys to commands run for the selected entry.
+	KeyActions []KeyAction
+	// SourcePauseMode is the default mode of :pause-source, display or
+	// read; SetSourcePaused stops reading a source and is required for
+	// read.
+	SourcePauseMode string
+	SetSourcePaused func(path string, paused bool)
+	// StyleANSI shows the colors producers write in list rows instead of
+	// dropping them.
+	StyleANSI bool
+	// FilterPresets are named filter expressions applied with F or
+	// :preset.
+	FilterPresets map[string]string
+	// LevelField is the field whose value colors list rows; empty means
+	// the usual level fields. LevelColors maps its values to colors on top
+	// of the defaults for common severities.
+	LevelField  string
+	LevelColors map[string]string
+	// MarksDir keeps the sidecar files of bookmarks and notes in one
+	// directory instead of next to the log files.
+	MarksDir string
+	// OnViewChange is called from Update whenever the search, filter or
+	// selection changed, e.g. to share the view with attached instances.
+	OnViewChange func(ViewState)
+	// SpikeSigma, when positive, marks the entries of seconds whose rate
+	// exceeds the rolling average by this many standard deviations.
+	SpikeSigma float64
 }
 
 // NewModel constructs a Model with sensible defaults.
 func NewModel(opts Options) Model {
 	st := defaultStyles()
+	if opts.PerSourceItems <= 0 {
+		opts.PerSourceItems = opts.MaxItems
+	}
 
 	items := []list.Item{}
 	delegate := list.NewDefaultDelegate()
 	delegate.ShowDescription = true
 
-	ls := list.New(items, delegate, 0, 0)
+	levels := newLevelDelegate(delegate, opts.LevelField, opts.LevelColors)
+	var itemDelegate list.ItemDelegate = levels
+	var columns *columnDelegate
+	if len(opts.Columns) > 0 {
+		d := newColumnDelegate(levels, opts.Columns)
+		itemDelegate, columns = d, &d
+	}
+	ls := list.New(items, itemDelegate, 0, 0)
 	ls.Title = "Logs"
+	// With columns the title is drawn above the column titles instead.
+	ls.SetShowTitle(columns == nil)
 	ls.SetShowHelp(false)
 	ls.SetShowStatusBar(false)
 	ls.SetFilteringEnabled(true)
@@ -95,25 +323,107 @@ func NewModel(opts Options) Model {
 	ti.Placeholder = "search"
 	ti.CharLimit = 256
 	ti.Blur()
+	ci := textinput.New()
+	ci.Prompt = ":"
+	ci.CharLimit = 512
+	ci.Blur()
+
+	al, alertErr := newAlerts(opts.Alerts, opts.NotifyInterval, opts.AlertCommandLimit)
+	errorMessage := ""
+	if alertErr != nil {
+		errorMessage = alertErr.Error()
+	}
+	rows, rowErr := newRowTemplates(opts.RowTemplate, opts.RowDescriptionTemplate)
+	if rowErr != nil {
+		errorMessage = rowErr.Error()
+	}
 
-	return Model{
-		list:          ls,
-		viewport:      vp,
-		entryCh:       opts.Entries,
-		errCh:         opts.Errors,
-		cancel:        opts.Cancel,
-		extraFields:   append([]string(nil), opts.Extra...),
-		maxEntries:    opts.MaxItems,
-		statusMessage: "tailing...",
-		searchInput:   ti,
-		focus:         focusList,
-		styles:        st,
+	m := Model{
+		list:           ls,
+		viewport:       vp,
+		follow:         true,
+		relativeTime:   opts.RelativeTime,
+		location:       opts.Location,
+		configLocation: opts.Location,
+		sourceEntries:  make(map[string]*entryRing),
+		perSourceItems: opts.PerSourceItems,
+		searchIndex:    newOptionalSearchIndex(opts.SearchIndex, opts.Location),
+		entries:        newEntryRing(opts.MaxItems),
+		spill:          opts.Spill,
+		stats:          opts.Stats,
+		refreshRate:    opts.RefreshRate,
+		compressRaw:    opts.CompressRaw,
+		prettyCache:    newRenderCache(prettyCacheSize),
+		entryCh:        opts.Entries,
+		errCh:          opts.Errors,
+		sourceCh:       opts.Sources,
+		cancel:         opts.Cancel,
+		extraFields:    append([]string(nil), opts.Extra...),
+		timestampField: opts.TimestampField,
+		messageField:   opts.MessageField,
+		saveMapping:    opts.SaveMapping,
+		pipeCommand:    opts.PipeCommand,
+		traceURL:       opts.TraceURL,
+		editor:         opts.EditorCommand,
+		callerFields:   opts.CallerFields,
+		clipboard:      opts.Clipboard,
+		copyFormat:     opts.CopyFormat,
+		layout:         opts.Layout,
+		columns:        columns,
+		split:          opts.Split,
+		sendTargets:    opts.SendTargets,
+		alerts:         al,
+		silenceTimeout: opts.SilenceTimeout,
+		silenceBell:    opts.SilenceBell,
+		spikes:         newRateSpikes(opts.SpikeSigma),
+		rows:           rows,
+		started:        time.Now(),
+		lastSeen:       make(map[string]time.Time),
+		silent:         make(map[string]time.Time),
+		errorMessage:   errorMessage,
+		statusMessage:  "tailing...",
+		searchInput:    ti,
+		commandInput:   ci,
+		focus:          focusList,
+		styles:         st,
+
+		sourcePauseMode: opts.SourcePauseMode,
+		setSourcePaused: opts.SetSourcePaused,
+		hiddenSources:   make(map[string]bool),
+		readPaused:      make(map[string]bool),
+		styleANSI:       opts.StyleANSI,
+		onViewChange:    opts.OnViewChange,
+		marks:           store.NewMarks(opts.MarksDir),
+		levelField:      opts.LevelField,
+		filterPresets:   opts.FilterPresets,
 	}
+	if len(opts.KeyActions) > 0 {
+		m.keyActions = make(map[string]KeyAction, len(opts.KeyActions))
+		for _, a := range opts.KeyActions {
+			m.keyActions[a.Key] = a
+		}
+	}
+	if m.sourcePauseMode == "" {
+		m.sourcePauseMode = SourcePauseDisplay
+	}
+	for _, expr := range opts.Watches {
+		if err := m.addWatch(expr); err != nil {
+			m.errorMessage = fmt.Sprintf("watch %q: %v", expr, err)
+		}
+	}
+	return m
 }
 
 // Init implements tea.Model.
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(m.waitForEntry(), m.waitForError())
+	cmds := []tea.Cmd{m.waitForEntry(), m.waitForError(), m.waitForSource()}
+	if m.silenceTimeout > 0 {
+		cmds = append(cmds, silenceTick())
+	}
+	if m.relativeTime {
+		cmds = append(cmds, relativeTick())
+	}
+	return tea.Batch(cmds...)
 }
 
 // Update reacts to incoming messages.
@@ -128,10 +438,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		key := msg.String()
 		if key == "ctrl+c" {
-			if m.cancel != nil {
-				m.cancel()
-			}
-			return m, tea.Quit
+			return m.quit()
+		}
+		if m.wizard != nil {
+			m.handleWizardKey(key)
+			keyHandled = true
+			break
 		}
 		if m.searchActive {
 			switch key {
@@ -149,21 +461,145 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			keyHandled = true
 			break
 		}
+		if m.commandActive {
+			switch key {
+			case "enter":
+				if cmd := m.runCommand(m.commandInput.Value()); cmd != nil {
+					cmds = append(cmds, cmd)
+				}
+			case "esc":
+				m.cancelCommand()
+			default:
+				var cmd tea.Cmd
+				m.commandInput, cmd = m.commandInput.Update(msg)
+				if cmd != nil {
+					cmds = append(cmds, cmd)
+				}
+			}
+			keyHandled = true
+			break
+		}
+		if action, ok := m.keyActions[key]; ok {
+			if cmd := m.runKeyAction(action); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+			keyHandled = true
+			break
+		}
 		switch key {
 		case "q":
-			if m.cancel != nil {
-				m.cancel()
-			}
-			return m, tea.Quit
+			return m.quit()
 		case "/":
 			m.beginSearch()
 			keyHandled = true
+		case ":":
+			m.beginCommand()
+			keyHandled = true
+		case "T":
+			m.openTrace()
+			keyHandled = true
+		case "Z":
+			m.cycleTimezone()
+			keyHandled = true
+		case "R":
+			if cmd := m.toggleRelativeTime(); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+			keyHandled = true
+		case "ctrl+s":
+			m.beginCommand()
+			m.commandInput.SetValue("export ")
+			m.commandInput.CursorEnd()
+			keyHandled = true
+		case "e":
+			if cmd := m.openInEditor(); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+			keyHandled = true
+		case "|":
+			if m.pipeCommand == "" {
+				m.beginCommand()
+				m.commandInput.SetValue("pipe ")
+				m.commandInput.CursorEnd()
+			} else if cmd := m.runCommand("pipe"); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+			keyHandled = true
+		case "!":
+			m.acknowledgeAlerts()
+			keyHandled = true
+		case "p":
+			m.togglePause()
+			keyHandled = true
+		case "y":
+			m.copyEntries()
+			keyHandled = true
+		case "Y":
+			m.copyMessage()
+			keyHandled = true
+		case "v":
+			m.toggleVisual()
+			keyHandled = true
 		case "esc":
-			if m.searchQuery != "" {
+			if m.visualAnchor != "" {
+				m.toggleVisual()
+				keyHandled = true
+			} else if m.searchQuery != "" {
 				m.applySearch("")
 				m.statusMessage = "search cleared"
 				keyHandled = true
 			}
+		case "M":
+			m.openWizard(m.sampleEntries(wizardSampleSize))
+			keyHandled = true
+		case "D":
+			if cmd := m.toggleDebug(); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+			keyHandled = true
+		case "W":
+			m.toggleWatchPanel()
+			keyHandled = true
+		case "S":
+			if cmd := m.toggleSourcesPanel(); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+			keyHandled = true
+		case "m":
+			m.toggleBookmark()
+			keyHandled = true
+		case "l":
+			m.cycleMinSeverity()
+			keyHandled = true
+		case "F":
+			m.cyclePreset()
+			keyHandled = true
+		case "0", "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			keyHandled = m.tabKey(int(key[0] - '0'))
+		case "}":
+			m.stepTab(1)
+			keyHandled = true
+		case "{":
+			m.stepTab(-1)
+			keyHandled = true
+		case "+":
+			m.resizeSplit(splitStep)
+			keyHandled = true
+		case "-":
+			m.resizeSplit(-splitStep)
+			keyHandled = true
+		case "L":
+			m.toggleLayout()
+			keyHandled = true
+		case "a":
+			m.toggleFollow()
+			keyHandled = true
+		case "]", "'":
+			m.stepMark(1)
+			keyHandled = true
+		case "[", "`":
+			m.stepMark(-1)
+			keyHandled = true
 		case "f":
 			if len(m.extraFields) > 1 {
 				m.extraFieldIndex = (m.extraFieldIndex + 1) % len(m.extraFields)
@@ -208,14 +644,58 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.handleWindowSize(msg)
 	case logEntryMsg:
-		m.appendEntry(msg.entry)
+		if cmd := m.queueEntries(msg.entries); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
 		cmds = append(cmds, m.waitForEntry())
+	case flushMsg:
+		m.flushPending()
+	case silenceTickMsg:
+		m.checkSilence()
+		cmds = append(cmds, silenceTick())
+	case relativeTickMsg:
+		// Redrawing is enough to bring the ages up to date.
+		if m.relativeTime {
+			cmds = append(cmds, relativeTick())
+		}
+	case debugTickMsg:
+		if m.debug || m.showSources {
+			cmds = append(cmds, debugTick())
+		}
 	case streamClosedMsg:
+		m.flushPending()
 		m.entryCh = nil
 		m.statusMessage = "input stream closed"
+		m.checkFieldMapping()
+	case editorDoneMsg:
+		if msg.err != nil {
+			m.errorMessage = fmt.Sprintf("editor: %v", msg.err)
+		}
+	case pipeResultMsg:
+		m.handlePipeResult(msg)
+	case keyActionDoneMsg:
+		m.handleKeyActionDone(msg)
+	case alertCommandMsg:
+		m.handleAlertCommand(msg)
+	case sendResultMsg:
+		m.handleSendResult(msg)
+	case ControlMsg:
+		if cmd := m.handleControl(msg); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	case ViewMsg:
+		m.applyView(msg.View)
 	case errMsg:
 		m.errorMessage = msg.err.Error()
 		cmds = append(cmds, m.waitForError())
+	case sourceMsg:
+		switch msg.event.Kind {
+		case logs.SourceAdded:
+			m.statusMessage = "new file: " + msg.event.Path
+		case logs.SourceRemoved:
+			m.statusMessage = "file removed: " + msg.event.Path
+		}
+		cmds = append(cmds, m.waitForSource())
 	}
 
 	switch msg := msg.(type) {
@@ -246,15 +726,38 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	cmds = append(cmds, m.alerts.takeCommands()...)
+
+	if _, ok := msg.(tea.KeyMsg); ok && m.follow && m.list.Index() > 0 {
+		m.follow = false
+		m.statusMessage = "browsing (a to follow newest)"
+	}
+
 	newSelection := m.selectionKey()
 	if m.needViewportSync || newSelection != prevSelection {
 		m.updateViewportFromSelection()
 		m.needViewportSync = false
 	}
+	m.reportView()
 
 	return m, tea.Batch(cmds...)
 }
 
+// quit applies buffered entries and stops the sources before exiting, so
+// the final model reflects everything that was read.
+func (m Model) quit() (tea.Model, tea.Cmd) {
+	m.flushPending()
+	if m.cancel != nil {
+		m.cancel()
+	}
+	return m, tea.Quit
+}
+
+// Entries returns the buffered entries from the oldest to the newest.
+func (m Model) Entries() []logs.LogEntry {
+	return m.entries.Oldest(0)
+}
+
 // View renders the UI.
 func (m Model) View() string {
 	if !m.ready {
@@ -262,8 +765,39 @@ func (m Model) View() string {
 	}
 
 	listView := m.styles.list.Render(m.list.View())
-	detailView := m.styles.detail.Render(m.viewport.View())
+	if m.columns != nil {
+		listView = m.styles.list.Render(lipgloss.JoinVertical(lipgloss.Left, m.tableHeader(m.list.Width()), m.list.View()))
+	}
+	detailContent := m.viewport.View()
+	if m.wizard != nil {
+		detailContent = lipgloss.NewStyle().
+			Width(m.viewport.Width).
+			Height(m.viewport.Height).
+			Render(m.wizard.view(m.viewport.Height))
+	} else if m.debug {
+		detailContent = lipgloss.NewStyle().
+			Width(m.viewport.Width).
+			Height(m.viewport.Height).
+			MaxHeight(m.viewport.Height).
+			Render(m.debugView())
+	} else if m.showWatches {
+		detailContent = lipgloss.NewStyle().
+			Width(m.viewport.Width).
+			Height(m.viewport.Height).
+			MaxHeight(m.viewport.Height).
+			Render(m.watchView())
+	} else if m.showSources {
+		detailContent = lipgloss.NewStyle().
+			Width(m.viewport.Width).
+			Height(m.viewport.Height).
+			MaxHeight(m.viewport.Height).
+			Render(m.sourcesView())
+	}
+	detailView := m.styles.detail.Render(detailContent)
 	content := lipgloss.JoinHorizontal(lipgloss.Top, listView, detailView)
+	if m.horizontal() {
+		content = lipgloss.JoinVertical(lipgloss.Left, listView, detailView)
+	}
 
 	status := m.statusLine()
 	if status != "" {
@@ -278,7 +812,7 @@ func (m *Model) handleWindowSize(msg tea.WindowSizeMsg) {
 	m.height = msg.Height
 	m.ready = true
 
-	listWidth := m.width / 2
+	listWidth := int(float64(m.width) * m.splitRatio())
 	if listWidth < 40 {
 		listWidth = 40
 	}
@@ -297,40 +831,209 @@ func (m *Model) handleWindowSize(msg tea.WindowSizeMsg) {
 	}
 	detailHeight := listHeight
 
+	if m.horizontal() {
+		// Both panes span the width, less their padding.
+		listWidth = max(m.width-2, 20)
+		detailWidth = listWidth
+		height := max(m.height-statusBarHeight, 6)
+		listHeight = max(int(float64(height)*m.splitRatio()), 3)
+		detailHeight = max(height-listHeight, 3)
+	}
+
+	if m.columns != nil {
+		listHeight = max(listHeight-lipgloss.Height(m.tableHeader(listWidth)), 1)
+	}
 	m.list.SetSize(listWidth, listHeight)
 	m.viewport.Width = detailWidth
 	m.viewport.Height = detailHeight
-	m.viewport.SetContent(m.viewport.View())
+	m.detailKey = ""
+	m.needViewportSync = true
 	if m.width > 8 {
 		m.searchInput.Width = m.width - 8
+		m.commandInput.Width = m.width - 8
 	} else {
 		m.searchInput.Width = m.width
+		m.commandInput.Width = m.width
 	}
 }
 
-func (m *Model) appendEntry(entry logs.LogEntry) {
-	m.entries = append([]logs.LogEntry{entry}, m.entries...)
-	if m.maxEntries > 0 && len(m.entries) > m.maxEntries {
-		m.entries = m.entries[:m.maxEntries]
+// appendEntries adds a batch of entries (oldest first) to the buffer and
+// updates the displayed list once for the whole batch.
+func (m *Model) appendEntries(batch []logs.LogEntry) {
+	fresh := make([]logs.LogEntry, 0, len(batch))
+	newSpike := false
+	for _, entry := range batch {
+		if m.remapFields {
+			entry = entry.WithMapping(m.timestampField, m.messageField)
+		}
+		m.observeForWizard(entry)
+		m.lastSeen[entry.Path] = time.Now()
+		delete(m.silent, entry.Path)
+		for _, w := range m.watches {
+			w.observe(entry, m.displayLocation())
+		}
+		if m.spikes != nil && m.spikes.observe(entry) {
+			newSpike = true
+		}
+		if m.alerts != nil {
+			if err := m.alerts.observe(entry, m.displayLocation()); err != nil {
+				m.errorMessage = err.Error()
+			}
+		}
+		if m.compressRaw {
+			entry = entry.Pack()
+		}
+		if m.searchIndex != nil {
+			m.searchIndex.add(entry)
+		}
+		evicted, ok := m.entries.Push(entry)
+		if ok {
+			m.spillEntry(evicted)
+			if m.searchIndex != nil {
+				m.searchIndex.evict()
+			}
+		}
+		if tabEvicted, tabOK := m.pushSourceEntry(entry); m.tab != "" {
+			// A tab lists from its own buffer.
+			evicted, ok = tabEvicted, tabOK && tabEvicted.Path == m.tab
+		}
+		if ok {
+			// An entry of this very batch may already be evicted when the
+			// batch is larger than the buffer.
+			if len(fresh) > 0 && len(m.displayEntries) == 0 && entryKey(fresh[0]) == entryKey(evicted) {
+				fresh = fresh[1:]
+			} else {
+				m.dropDisplayed(evicted)
+			}
+		}
+		if m.paused {
+			m.pausedNew++
+		} else if m.entryVisible(entry) {
+			fresh = append(fresh, entry)
+		}
 	}
 
-	m.rebuildList()
+	if len(fresh) > 0 {
+		// Only the fresh entries are turned into items; they go on top,
+		// so the selected entry moves down by as many rows.
+		selected := m.list.Index()
+		extraField := m.currentExtraField()
+		slices.Reverse(fresh)
+		items := make([]list.Item, len(fresh))
+		for i, entry := range fresh {
+			items[i] = m.newLogItem(entry, extraField)
+		}
+		m.displayEntries = slices.Insert(m.displayEntries, 0, fresh...)
+		m.list.SetItems(slices.Insert(m.list.Items(), 0, items...))
+		m.searchMatchCount = len(m.displayEntries)
+		m.needViewportSync = true
+		if m.follow || selected < 0 {
+			m.list.Select(0)
+		} else {
+			m.list.Select(selected + len(fresh))
+		}
+	}
+
+	// Entries listed before their second turned into a spike are marked
+	// by listing them again.
+	if newSpike && !m.paused {
+		key := m.selectionKey()
+		m.rebuildList()
+		m.selectEntryKey(key)
+	}
 
 	if m.list.Index() <= 0 {
 		m.list.Select(0)
 	}
+	if m.alerts != nil && m.alerts.pauseKey != "" {
+		m.pauseAt(m.alerts.pauseKey, m.alerts.pauseRule)
+		m.alerts.pauseKey, m.alerts.pauseRule = "", ""
+	}
+}
+
+// queueEntries buffers incoming entries. Buffered entries are applied at
+// most refreshRate times per second so that bursts don't rebuild the list
+// for every single line.
+func (m *Model) queueEntries(entries []logs.LogEntry) tea.Cmd {
+	if m.refreshRate <= 0 {
+		m.appendEntries(entries)
+		return nil
+	}
+	m.pending = append(m.pending, entries...)
+	if m.flushScheduled {
+		return nil
+	}
+	m.flushScheduled = true
+	return tea.Tick(time.Second/time.Duration(m.refreshRate), func(time.Time) tea.Msg {
+		return flushMsg{}
+	})
+}
+
+func (m *Model) flushPending() {
+	m.flushScheduled = false
+	if len(m.pending) == 0 {
+		return
+	}
+	batch := m.pending
+	m.pending = nil
+	m.appendEntries(batch)
+}
+
+// dropDisplayed removes an entry evicted from the buffer from the displayed
+// list. Evicted entries are always the oldest, so only the tail is checked.
+func (m *Model) dropDisplayed(evicted logs.LogEntry) {
+	if m.spill != nil && m.tab == "" && (m.narrowed() || m.spillWindow) {
+		// Spilled entries remain part of search results and of what
+		// :jump loaded from the spill.
+		return
+	}
+	last := len(m.displayEntries) - 1
+	if last < 0 || entryKey(m.displayEntries[last]) != entryKey(evicted) {
+		return
+	}
+	m.displayEntries = m.displayEntries[:last]
+	m.list.RemoveItem(last)
+	m.searchMatchCount = len(m.displayEntries)
+	if idx := m.list.Index(); idx >= last && last > 0 {
+		m.list.Select(last - 1)
+	}
+	m.needViewportSync = true
+}
+
+// narrowed reports whether anything hides entries from the list.
+func (m Model) narrowed() bool {
+	return m.searchQuery != "" || !m.listFilter.Empty() || len(m.hiddenSources) > 0 || m.minSeverity != logs.SeverityUnknown || m.tab != ""
+}
+
+// entryVisible reports whether entry passes the active search and filters.
+func (m Model) entryVisible(entry logs.LogEntry) bool {
+	if m.hiddenSources[entry.Path] || (m.tab != "" && entry.Path != m.tab) || !m.severityVisible(entry) {
+		return false
+	}
+	// Text is matched against timestamps as the list shows them.
+	loc := m.displayLocation()
+	if !m.searchFilter.In(loc).Match(entry) {
+		return false
+	}
+	return m.listFilter.In(loc).Match(entry)
+}
+
+// sampleEntries returns up to n of the oldest buffered entries.
+func (m Model) sampleEntries(n int) []logs.LogEntry {
+	return m.entries.Oldest(n)
 }
 
 func (m *Model) rebuildList() {
 	entries := m.filteredEntries()
 	m.displayEntries = entries
+	m.spillWindow = false
 	m.searchMatchCount = len(entries)
 	m.needViewportSync = true
 
 	items := make([]list.Item, len(entries))
 	extraField := m.currentExtraField()
 	for i, entry := range entries {
-		items[i] = logItem{entry: entry, extraField: extraField}
+		items[i] = m.newLogItem(entry, extraField)
 	}
 
 	curIndex := m.list.Index()
@@ -356,6 +1059,7 @@ func (m *Model) updateViewportFromSelection() {
 	defer func() { m.needViewportSync = false }()
 	item := m.list.SelectedItem()
 	if item == nil {
+		m.detailKey = ""
 		m.viewport.SetContent("")
 		return
 	}
@@ -363,43 +1067,134 @@ func (m *Model) updateViewportFromSelection() {
 	if !ok {
 		return
 	}
-	content := logItem.entry.PrettyJSON()
+	key := entryKey(logItem.entry)
+	if key == m.detailKey {
+		return
+	}
+	m.detailKey = key
+	m.viewport.SetContent(highlightDetail(m.prettyContent(logItem.entry), m.searchFilter.Words()))
+}
+
+// prettyContent returns the detail rendering of entry, computing it only on
+// first use.
+func (m *Model) prettyContent(entry logs.LogEntry) string {
+	key := entryKey(entry)
+	if content, ok := m.prettyCache.Get(key); ok {
+		return content
+	}
+	content := entry.PrettyJSON()
 	if content == "" {
-		content = logItem.entry.Raw
+		content = entry.RawLine()
 	}
-	m.viewport.SetContent(content)
+	content = sanitizeText(content, true)
+	m.prettyCache.Put(key, content)
+	return content
 }
 
 func (m *Model) filteredEntries() []logs.LogEntry {
-	if m.searchQuery == "" {
-		return append([]logs.LogEntry(nil), m.entries...)
+	if !m.narrowed() {
+		return m.entries.Newest(0)
+	}
+	ring := m.entries
+	if m.tab != "" {
+		ring = m.sourceEntries[m.tab]
+		if ring == nil {
+			return nil
+		}
+	}
+	var candidates []bool
+	if m.searchIndex != nil && m.tab == "" {
+		candidates, _ = m.searchIndex.candidates(m.searchFilter.Words(), ring.Len())
 	}
-	query := strings.ToLower(m.searchQuery)
-	matches := make([]logs.LogEntry, 0, len(m.entries))
-	for _, entry := range m.entries {
-		if entryMatchesQuery(entry, query) {
+	matches := make([]logs.LogEntry, 0, ring.Len())
+	for i := 0; i < ring.Len(); i++ {
+		if candidates != nil && !candidates[i] {
+			continue
+		}
+		entry := ring.At(i)
+		if m.entryVisible(entry) {
 			matches = append(matches, entry)
 		}
 	}
+	if m.spill != nil && m.tab == "" {
+		matches = append(matches, m.spilledMatches()...)
+	}
 	return matches
 }
 
+func (m *Model) spillEntry(entry logs.LogEntry) {
+	if m.spill == nil {
+		return
+	}
+	if err := m.spill.Append(entry); err != nil {
+		m.errorMessage = err.Error()
+	}
+}
+
 func (m Model) statusLine() string {
 	var parts []string
-	if m.searchActive {
+	if m.commandActive {
+		parts = append(parts, m.commandInput.View())
+	} else if m.searchActive {
 		parts = append(parts, "search "+m.searchInput.View())
 	} else if m.searchQuery != "" {
 		parts = append(parts, fmt.Sprintf("/%s (%d)", m.searchQuery, m.searchMatchCount))
 	}
+	if m.minSeverity != logs.SeverityUnknown {
+		parts = append(parts, "level: "+m.minSeverity.String()+"+")
+	}
+	if m.listFilterName != "" {
+		parts = append(parts, "preset: "+m.listFilterName)
+	} else if m.listFilterExpr != "" {
+		parts = append(parts, "filter: "+m.listFilterExpr)
+	}
+	if !m.follow {
+		parts = append(parts, "browse (a to follow)")
+	}
+	if loc := m.displayLocation(); loc != time.Local {
+		parts = append(parts, "tz: "+loc.String())
+	}
+	if m.paused {
+		parts = append(parts, fmt.Sprintf("PAUSED (%d new, p to resume)", m.pausedNew))
+	}
+	if badge := m.alerts.badge(); badge != "" {
+		parts = append(parts, badge)
+	}
+	if snoozed := m.alerts.snoozeStatus(); snoozed != "" {
+		parts = append(parts, snoozed)
+	}
+	if badge := m.silenceBadge(); badge != "" {
+		parts = append(parts, badge)
+	}
+	if badge := m.sourcePauseBadge(); badge != "" {
+		parts = append(parts, badge)
+	}
+	if item, ok := m.list.SelectedItem().(logItem); ok && item.spike {
+		if reason, ok := m.spikes.spike(item.entry); ok {
+			parts = append(parts, "spike: "+reason)
+		}
+	}
+	if item, ok := m.list.SelectedItem().(logItem); ok && item.mark.Note != "" {
+		parts = append(parts, "note: "+sanitizeText(item.mark.Note, false))
+	}
 	parts = append(parts, fmt.Sprintf("focus: %s", m.focus.String()))
+	if visual := m.visualStatus(); visual != "" {
+		parts = append(parts, visual)
+	}
 	if extra := m.currentExtraField(); extra != "" {
 		parts = append(parts, fmt.Sprintf("extra: %s", extra))
 	}
+	if dropped := m.droppedEntries(); dropped > 0 {
+		parts = append(parts, fmt.Sprintf("dropped: %d", dropped))
+	}
+	if m.spill != nil && m.spill.Len() > 0 {
+		parts = append(parts, fmt.Sprintf("on disk: %d", m.spill.Len()))
+	}
 	if m.statusMessage != "" {
 		parts = append(parts, m.statusMessage)
 	}
 	if m.errorMessage != "" {
-		parts = append(parts, "error: "+m.errorMessage)
+		parts = append(parts, "error: "+sanitizeText(m.errorMessage, false))
 	}
 	if len(parts) == 0 {
 		return ""
@@ -407,6 +1202,17 @@ func (m Model) statusLine() string {
 	return strings.Join(parts, "  |  ")
 }
 
+func (m Model) droppedEntries() int64 {
+	if m.stats == nil {
+		return 0
+	}
+	var total int64
+	for _, s := range m.stats() {
+		total += s.Dropped
+	}
+	return total
+}
+
 func (m Model) currentExtraField() string {
 	if len(m.extraFields) == 0 {
 		return ""
@@ -418,12 +1224,29 @@ func (m Model) waitForEntry() tea.Cmd {
 	if m.entryCh == nil {
 		return nil
 	}
+	ch := m.entryCh
 	return func() tea.Msg {
-		entry, ok := <-m.entryCh
+		entry, ok := <-ch
 		if !ok {
 			return streamClosedMsg{}
 		}
-		return logEntryMsg{entry: entry}
+		// Take what else is already waiting, so that a burst costs one
+		// Update per batch rather than per line. A close seen here is
+		// reported by the next wait.
+		entries := []logs.LogEntry{entry}
+		for len(entries) < maxEntryBatch {
+			select {
+			case entry, ok := <-ch:
+				if !ok {
+					return logEntryMsg{entries: entries}
+				}
+				entries = append(entries, entry)
+				continue
+			default:
+			}
+			break
+		}
+		return logEntryMsg{entries: entries}
 	}
 }
 
@@ -440,12 +1263,33 @@ func (m Model) waitForError() tea.Cmd {
 	}
 }
 
+func (m Model) waitForSource() tea.Cmd {
+	if m.sourceCh == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		ev, ok := <-m.sourceCh
+		if !ok {
+			return nil
+		}
+		return sourceMsg{event: ev}
+	}
+}
+
+type sourceMsg struct {
+	event logs.SourceEvent
+}
+
+// logEntryMsg delivers the entries that arrived since the last one, oldest
+// first.
 type logEntryMsg struct {
-	entry logs.LogEntry
+	entries []logs.LogEntry
 }
 
 type streamClosedMsg struct{}
 
+type flushMsg struct{}
+
 type errMsg struct {
 	err error
 }
@@ -453,30 +1297,90 @@ type errMsg struct {
 type logItem struct {
 	entry      logs.LogEntry
 	extraField string
+	spike      bool
+	rows       *rowTemplates
+	styled     bool
+	mark       store.Mark
+	// relative shows the age of the entry instead of its timestamp.
+	relative bool
+	location *time.Location
+}
+
+func (m Model) newLogItem(entry logs.LogEntry, extraField string) logItem {
+	_, spike := m.spikes.spike(entry)
+	mark, _ := m.entryMark(entry)
+	return logItem{entry: entry, extraField: extraField, spike: spike, rows: m.rows, styled: m.styleANSI, mark: mark, relative: m.relativeTime, location: m.displayLocation()}
+}
+
+// timestamp returns the timestamp shown in the row, computed when drawn so
+// that a relative one is current.
+func (i logItem) timestamp() string {
+	if i.relative && !i.entry.Timestamp.IsZero() {
+		return relativeAge(i.entry.Timestamp, time.Now())
+	}
+	return i.absoluteTimestamp()
+}
+
+// absoluteTimestamp returns the timestamp in the zone of the list.
+func (i logItem) absoluteTimestamp() string {
+	if i.location != nil {
+		return i.entry.DisplayTimestampIn(i.location)
+	}
+	return i.entry.DisplayTimestamp()
+}
+
+// markers returns the symbols shown before the row: ▲ for a rate spike,
+// ★ for a bookmark and ✎ for a note.
+func (i logItem) markers() string {
+	var b strings.Builder
+	if i.spike {
+		b.WriteString("▲ ")
+	}
+	if i.mark.Bookmark {
+		b.WriteString("★ ")
+	}
+	if i.mark.Note != "" {
+		b.WriteString("✎ ")
+	}
+	return b.String()
 }
 
 func (i logItem) Title() string {
-	ts := i.entry.DisplayTimestamp()
+	if i.rows != nil {
+		if title, ok := i.rows.render(i.rows.title, i.entry); ok {
+			return i.markers() + title
+		}
+	}
+	ts := i.timestamp()
 	message := i.entry.Message
 	if message == "" {
-		message = i.entry.Raw
+		message = i.entry.RawPreview()
 	}
 	if ts != "" {
-		return fmt.Sprintf("%s  %s", ts, message)
+		message = fmt.Sprintf("%s  %s", ts, message)
 	}
-	return message
+	message = i.markers() + message
+	if i.styled {
+		return sanitizeStyled(message)
+	}
+	return sanitizeText(message, false)
 }
 
 func (i logItem) Description() string {
+	if i.rows != nil {
+		if desc, ok := i.rows.render(i.rows.desc, i.entry); ok {
+			return desc
+		}
+	}
 	val := i.entry.ExtraValue(i.extraField)
 	if val == "" {
-		return i.entry.Path
+		return sanitizeText(i.entry.Path, false)
 	}
-	return val
+	return sanitizeText(val, false)
 }
 
 func (i logItem) FilterValue() string {
-	values := []string{i.entry.Message, i.entry.Raw, i.entry.DisplayTimestamp()}
+	values := []string{i.entry.Message, i.entry.RawPreview(), i.absoluteTimestamp()}
 	for k, v := range i.entry.Extras {
 		values = append(values, fmt.Sprintf("%s:%s", k, v))
 	}
@@ -524,7 +1428,9 @@ func (m *Model) commitSearch(query string) {
 }
 
 func (m *Model) applySearch(query string) {
-	m.searchQuery = query
+	m.searchQuery, m.searchFilter = query, logs.ParseSearch(query)
+	// The detail shows the matches of the search.
+	m.detailKey = ""
 	m.rebuildList()
 	if len(m.displayEntries) == 0 {
 		m.list.ResetSelected()
@@ -558,31 +1464,24 @@ func (m *Model) stepMatch(delta int) {
 
 func (m Model) selectionKey() string {
 	if item, ok := m.list.SelectedItem().(logItem); ok {
-		return item.entry.Path + "\x00" + item.entry.Raw
+		return entryKey(item.entry)
 	}
 	return ""
 }
 
-func entryMatchesQuery(entry logs.LogEntry, query string) bool {
-	if query == "" {
-		return true
-	}
-	if strings.Contains(strings.ToLower(entry.Message), query) {
-		return true
-	}
-	if strings.Contains(strings.ToLower(entry.Raw), query) {
-		return true
-	}
-	if ts := entry.DisplayTimestamp(); ts != "" && strings.Contains(strings.ToLower(ts), query) {
-		return true
-	}
-	if strings.Contains(strings.ToLower(entry.Path), query) {
-		return true
-	}
-	for _, val := range entry.Extras {
-		if strings.Contains(strings.ToLower(val), query) {
+// selectEntryKey selects the displayed entry with key, reporting whether it
+// was found.
+func (m *Model) selectEntryKey(key string) bool {
+	for i, entry := range m.displayEntries {
+		if entryKey(entry) == key {
+			m.list.Select(i)
+			m.needViewportSync = true
 			return true
 		}
 	}
 	return false
 }
+
+func entryKey(entry logs.LogEntry) string {
+	return entry.Key()
+}
@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+)
+
+// openURL opens target in the default browser without waiting for it.
+func openURL(target string) error {
+	if _, err := url.ParseRequestURI(target); err != nil {
+		return fmt.Errorf("invalid URL %q", target)
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// openTrace opens the trace of the selected entry using the configured
+// trace URL template.
+func (m *Model) openTrace() {
+	if m.traceURL == "" {
+		m.errorMessage = "trace_url is not configured"
+		return
+	}
+	item, ok := m.list.SelectedItem().(logItem)
+	if !ok {
+		return
+	}
+	target, err := renderEntryTemplate(m.traceURL, item.entry)
+	if err != nil {
+		m.errorMessage = fmt.Sprintf("trace: %v", err)
+		return
+	}
+	if err := openURL(target); err != nil {
+		m.errorMessage = fmt.Sprintf("trace: %v", err)
+		return
+	}
+	m.statusMessage = "opened " + target
+}
@@ -0,0 +1,43 @@
+package ui
+
+// togglePause stops or resumes following. While paused, arriving entries
+// are buffered but the list stays as it is; resuming shows them and keeps
+// the selected entry.
+func (m *Model) togglePause() {
+	if !m.paused {
+		m.paused = true
+		m.statusMessage = "paused"
+		return
+	}
+	key := m.selectionKey()
+	m.paused = false
+	m.pausedNew = 0
+	m.rebuildList()
+	m.selectEntryKey(key)
+	m.statusMessage = "following"
+}
+
+// pauseAt pauses following and selects the entry with key, after an alert
+// rule with pause fired.
+func (m *Model) pauseAt(key, rule string) {
+	if !m.paused {
+		m.paused = true
+		m.pausedNew = 0
+	}
+	m.selectEntryKey(key)
+	m.statusMessage = "paused on alert " + rule
+}
+
+// toggleFollow switches between following, where the newest entry is kept
+// selected as entries arrive, and browsing, where the selected entry stays
+// selected while newer ones are listed above it.
+func (m *Model) toggleFollow() {
+	m.follow = !m.follow
+	if !m.follow {
+		m.statusMessage = "browsing: selection stays on its entry"
+		return
+	}
+	m.list.Select(0)
+	m.needViewportSync = true
+	m.statusMessage = "following newest entry"
+}
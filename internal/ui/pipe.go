@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcuzy/logsviewer/internal/export"
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+type pipeResultMsg struct {
+	command string
+	count   int
+	output  string
+	err     error
+}
+
+// pipeEntries runs command through sh with entries written to its stdin as
+// NDJSON. The command is a template expanded for the first entry. Its
+// output is shown in the detail pane once it finishes.
+func (m *Model) pipeEntries(command string, entries []logs.LogEntry) (tea.Cmd, error) {
+	if command == "" {
+		command = m.pipeCommand
+	}
+	if command == "" {
+		return nil, fmt.Errorf("no command given and pipe_command is not configured")
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no entries to pipe")
+	}
+	expanded, err := renderEntryTemplate(command, entries[0])
+	if err != nil {
+		return nil, err
+	}
+	var input bytes.Buffer
+	if err := export.Write(&input, entries, export.Options{Format: export.NDJSON}); err != nil {
+		return nil, err
+	}
+
+	m.statusMessage = "running " + expanded
+	return func() tea.Msg {
+		cmd := exec.Command("sh", "-c", expanded)
+		cmd.Stdin = &input
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				err = fmt.Errorf("%w: %s", err, firstLine(msg))
+			}
+		}
+		return pipeResultMsg{command: expanded, count: len(entries), output: stdout.String(), err: err}
+	}, nil
+}
+
+func (m *Model) handlePipeResult(msg pipeResultMsg) {
+	if msg.err != nil {
+		m.errorMessage = fmt.Sprintf("pipe: %v", msg.err)
+	} else {
+		m.statusMessage = fmt.Sprintf("piped %d entries to %s", msg.count, msg.command)
+	}
+	if msg.output != "" {
+		// Shown until the selection changes.
+		m.viewport.SetContent(msg.output)
+		m.viewport.GotoTop()
+	}
+}
+
+// pipeTargets returns the entries the pipe key acts on: the visual
+// selection if any, otherwise the selected entry.
+func (m Model) pipeTargets() []logs.LogEntry {
+	if selected := m.selectedEntries(); selected != nil {
+		return selected
+	}
+	if item, ok := m.list.SelectedItem().(logItem); ok {
+		return []logs.LogEntry{item.entry}
+	}
+	return nil
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// presetNames returns the names of the configured filter presets in the
+// order F cycles through them.
+func (m Model) presetNames() []string {
+	names := make([]string, 0, len(m.filterPresets))
+	for name := range m.filterPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyPreset makes the named preset the list filter, as :filter would;
+// an empty name removes the filter.
+func (m *Model) applyPreset(name string) error {
+	expr := ""
+	if name != "" {
+		var ok bool
+		if expr, ok = m.filterPresets[name]; !ok {
+			return fmt.Errorf("no filter preset %q (have: %s)", name, strings.Join(m.presetNames(), ", "))
+		}
+	}
+	filter, err := logs.ParseFilter(expr)
+	if err != nil {
+		return fmt.Errorf("preset %s: %w", name, err)
+	}
+	key := m.selectionKey()
+	m.listFilter, m.listFilterExpr, m.listFilterName = filter, expr, name
+	m.rebuildList()
+	m.selectEntryKey(key)
+	if name == "" {
+		m.statusMessage = "filter removed"
+	} else {
+		m.statusMessage = fmt.Sprintf("preset %s: %d entries", name, len(m.displayEntries))
+	}
+	return nil
+}
+
+// cyclePreset applies the preset after the active one, and no filter after
+// the last.
+func (m *Model) cyclePreset() {
+	names := m.presetNames()
+	if len(names) == 0 {
+		m.statusMessage = "no filter presets configured"
+		return
+	}
+	next := names[0]
+	if m.listFilterName != "" {
+		i := sort.SearchStrings(names, m.listFilterName)
+		if i+1 < len(names) {
+			next = names[i+1]
+		} else {
+			next = ""
+		}
+	}
+	if err := m.applyPreset(next); err != nil {
+		m.errorMessage = err.Error()
+	}
+}
+
+// presetCommand applies a preset by name or number: ":preset errors".
+// Without an argument it lists the presets.
+func (m *Model) presetCommand(arg string) (tea.Cmd, error) {
+	names := m.presetNames()
+	if arg == "" {
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no filter presets configured")
+		}
+		var b strings.Builder
+		b.WriteString("filter presets (:preset <name or number>, F cycles)\n\n")
+		for i, name := range names {
+			active := " "
+			if name == m.listFilterName {
+				active = "*"
+			}
+			fmt.Fprintf(&b, "%s %d  %-16s %s\n", active, i+1, name, m.filterPresets[name])
+		}
+		m.showConsoleOutput(b.String())
+		return nil, nil
+	}
+	var n int
+	if _, err := fmt.Sscanf(arg, "%d", &n); err == nil && fmt.Sprint(n) == arg {
+		if n < 1 || n > len(names) {
+			return nil, fmt.Errorf("there are %d presets", len(names))
+		}
+		arg = names[n-1]
+	}
+	return nil, m.applyPreset(arg)
+}
@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// relativeRefreshInterval is how often the list is redrawn while it shows
+// relative timestamps, so that they keep counting.
+const relativeRefreshInterval = time.Second
+
+type relativeTickMsg struct{}
+
+func relativeTick() tea.Cmd {
+	return tea.Tick(relativeRefreshInterval, func(time.Time) tea.Msg {
+		return relativeTickMsg{}
+	})
+}
+
+// toggleRelativeTime switches the list between absolute timestamps and
+// their age, "3s ago".
+func (m *Model) toggleRelativeTime() tea.Cmd {
+	m.relativeTime = !m.relativeTime
+	key := m.selectionKey()
+	m.rebuildList()
+	m.selectEntryKey(key)
+	if !m.relativeTime {
+		m.statusMessage = "absolute timestamps"
+		return nil
+	}
+	m.statusMessage = "relative timestamps"
+	return relativeTick()
+}
+
+// relativeAge describes how long before now t was, in its largest unit:
+// "now", "42s ago", "5m ago", "3h ago", "2d ago"; a t after now, e.g.
+// from a skewed clock, is "in 5s".
+func relativeAge(t, now time.Time) string {
+	d := now.Sub(t)
+	format := "%s ago"
+	if d < 0 {
+		d, format = -d, "in %s"
+	}
+	var span string
+	switch {
+	case d < time.Second:
+		return "now"
+	case d < time.Minute:
+		span = fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		span = fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 48*time.Hour:
+		span = fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		span = fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	}
+	return fmt.Sprintf(format, span)
+}
@@ -0,0 +1,96 @@
+package ui
+
+import "github.com/marcuzy/logsviewer/internal/logs"
+
+// entryRing stores log entries in a fixed-capacity circular buffer so that
+// appending is O(1) regardless of how many entries are kept. A capacity of
+// zero means the buffer grows without bound.
+type entryRing struct {
+	items []logs.LogEntry
+	start int // index of the oldest entry
+	size  int
+	limit int
+}
+
+func newEntryRing(limit int) *entryRing {
+	if limit < 0 {
+		limit = 0
+	}
+	return &entryRing{limit: limit}
+}
+
+// Len returns the number of stored entries.
+func (r *entryRing) Len() int {
+	return r.size
+}
+
+// Push appends entry as the newest one. When the buffer is full the oldest
+// entry is evicted and returned with ok set to true.
+func (r *entryRing) Push(entry logs.LogEntry) (evicted logs.LogEntry, ok bool) {
+	if r.limit == 0 || r.size < r.limit {
+		if r.size == len(r.items) {
+			r.grow()
+		}
+		r.items[(r.start+r.size)%len(r.items)] = entry
+		r.size++
+		return logs.LogEntry{}, false
+	}
+	evicted = r.items[r.start]
+	r.items[r.start] = entry
+	r.start = (r.start + 1) % len(r.items)
+	return evicted, true
+}
+
+func (r *entryRing) grow() {
+	capacity := len(r.items) * 2
+	if capacity == 0 {
+		capacity = 64
+	}
+	if r.limit > 0 && capacity > r.limit {
+		capacity = r.limit
+	}
+	items := make([]logs.LogEntry, capacity)
+	for i := 0; i < r.size; i++ {
+		items[i] = r.items[(r.start+i)%len(r.items)]
+	}
+	r.items = items
+	r.start = 0
+}
+
+// At returns the i-th entry counting from the newest (i == 0).
+func (r *entryRing) At(i int) logs.LogEntry {
+	return r.items[r.index(i)]
+}
+
+// Set replaces the i-th entry counting from the newest.
+func (r *entryRing) Set(i int, entry logs.LogEntry) {
+	r.items[r.index(i)] = entry
+}
+
+func (r *entryRing) index(i int) int {
+	return (r.start + r.size - 1 - i) % len(r.items)
+}
+
+// Newest returns up to n entries ordered newest first; n <= 0 returns all.
+func (r *entryRing) Newest(n int) []logs.LogEntry {
+	if n <= 0 || n > r.size {
+		n = r.size
+	}
+	out := make([]logs.LogEntry, n)
+	for i := 0; i < n; i++ {
+		out[i] = r.At(i)
+	}
+	return out
+}
+
+// Oldest returns up to n entries ordered oldest first.
+func (r *entryRing) Oldest(n int) []logs.LogEntry {
+	if n <= 0 || n > r.size {
+		n = r.size
+	}
+	out := make([]logs.LogEntry, n)
+	for i := 0; i < n; i++ {
+		out[i] = r.items[(r.start+i)%len(r.items)]
+	}
+	return out
+}
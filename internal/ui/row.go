@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// rowFuncs add styling to the entry template functions for list rows:
+// {{color "196" .Message}}, {{bg "52" "FAIL"}}, {{bold .Level}} and
+// {{faint .Path}}.
+var rowFuncs = template.FuncMap{
+	"color": func(c, s string) string { return lipgloss.NewStyle().Foreground(lipgloss.Color(c)).Render(s) },
+	"bg":    func(c, s string) string { return lipgloss.NewStyle().Background(lipgloss.Color(c)).Render(s) },
+	"bold":  func(s string) string { return lipgloss.NewStyle().Bold(true).Render(s) },
+	"faint": func(s string) string { return lipgloss.NewStyle().Faint(true).Render(s) },
+}
+
+// rowTemplates render the two lines of a list row in place of the
+// timestamp and message and the extra field. Either may be nil.
+type rowTemplates struct {
+	title *template.Template
+	desc  *template.Template
+}
+
+func newRowTemplates(title, desc string) (*rowTemplates, error) {
+	if title == "" && desc == "" {
+		return nil, nil
+	}
+	r := &rowTemplates{}
+	var err error
+	if r.title, err = parseRowTemplate("row_template", title); err != nil {
+		return nil, err
+	}
+	if r.desc, err = parseRowTemplate("row_description_template", desc); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func parseRowTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return template.New(name).Funcs(templateFuncs).Funcs(rowFuncs).Parse(text)
+}
+
+// render expands tmpl for entry. Missing fields render empty, as a row
+// template is applied to every entry; ok is false when there is no
+// template or it fails, so that the default row is shown.
+func (r *rowTemplates) render(tmpl *template.Template, entry logs.LogEntry) (string, bool) {
+	if tmpl == nil {
+		return "", false
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, newEntryTemplateData(entry).sanitized()); err != nil {
+		return "", false
+	}
+	// A row is a single line.
+	return strings.ReplaceAll(b.String(), "\n", " "), true
+}
+
+// sanitized returns d with its values made safe to draw; the escape
+// sequences a row template adds itself are kept.
+func (d entryTemplateData) sanitized() entryTemplateData {
+	clean := func(values map[string]string) map[string]string {
+		out := make(map[string]string, len(values))
+		for k, v := range values {
+			out[k] = sanitizeText(v, false)
+		}
+		return out
+	}
+	d.Path = sanitizeText(d.Path, false)
+	d.Timestamp = sanitizeText(d.Timestamp, false)
+	d.Message = sanitizeText(d.Message, false)
+	d.Level = sanitizeText(d.Level, false)
+	d.Raw = sanitizeText(d.Raw, false)
+	d.Fields = clean(d.Fields)
+	d.Extras = clean(d.Extras)
+	return d
+}
@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// sanitizeText makes text from a log safe to draw: escape sequences a
+// producer wrote are removed, and control characters and bytes that are
+// not UTF-8 are shown as Go-style escapes (\x1b, \u0085), so one garbage
+// line cannot move the cursor or switch the terminal's character set.
+// multiline keeps newlines and tabs for the detail pane.
+func sanitizeText(s string, multiline bool) string {
+	return sanitize(s, multiline, false)
+}
+
+// sanitizeStyled is sanitizeText for a single line that keeps the colors
+// and text attributes (SGR sequences) of the producer, ending them with a
+// reset so they do not leak into what is drawn after.
+func sanitizeStyled(s string) string {
+	return sanitize(s, false, true)
+}
+
+func sanitize(s string, multiline, keepSGR bool) string {
+	if isDisplaySafe(s, multiline) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	styled := false
+	for i := 0; i < len(s); {
+		if n := logs.ANSISequenceLen(s[i:]); n > 0 {
+			if keepSGR && isSGR(s[i:i+n]) {
+				b.WriteString(s[i : i+n])
+				styled = true
+			}
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			fmt.Fprintf(&b, `\x%02x`, s[i])
+		case multiline && (r == '\n' || r == '\t'):
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteByte(' ')
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case r >= 0x80 && r < 0xa0:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	if styled {
+		b.WriteString("\x1b[0m")
+	}
+	return b.String()
+}
+
+// isSGR reports whether seq, a complete escape sequence, only sets colors
+// or text attributes.
+func isSGR(seq string) bool {
+	if len(seq) < 3 || seq[1] != '[' || seq[len(seq)-1] != 'm' {
+		return false
+	}
+	for _, c := range seq[2 : len(seq)-1] {
+		if (c < '0' || c > '9') && c != ';' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// isDisplaySafe reports whether s can be drawn as is, which is the case
+// for nearly every line; it only looks at bytes, so it is cheap.
+func isDisplaySafe(s string, multiline bool) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x20 && c < 0x7f {
+			continue
+		}
+		if multiline && (c == '\n' || c == '\t') {
+			continue
+		}
+		if c >= 0x80 {
+			// Non-ASCII text is checked rune by rune.
+			return utf8.ValidString(s) && !hasC1Control(s)
+		}
+		return false
+	}
+	return true
+}
+
+func hasC1Control(s string) bool {
+	for _, r := range s {
+		if r >= 0x80 && r < 0xa0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// searchIndex maps the words of buffered entries to the entries containing
+// them, so that a search narrows the buffer to candidates before matching
+// instead of lower-casing every entry on every keystroke. Entries are
+// numbered in arrival order; it is kept in step with the buffer, entry i of
+// the buffer counting from the newest being number next-1-i.
+type searchIndex struct {
+	postings map[string][]int
+	// location is the zone timestamps are indexed in, the one the list
+	// shows them in.
+	location *time.Location
+	next     int
+	// oldest is the number of the oldest buffered entry; postings of
+	// older ones are dropped now and then.
+	oldest  int
+	evicted int
+}
+
+func newSearchIndex(loc *time.Location) *searchIndex {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &searchIndex{postings: make(map[string][]int), location: loc}
+}
+
+func newOptionalSearchIndex(enabled bool, loc *time.Location) *searchIndex {
+	if !enabled {
+		return nil
+	}
+	return newSearchIndex(loc)
+}
+
+// rebuildSearchIndex indexes the buffered entries anew, after their text
+// or the zone of their timestamps changed.
+func (m *Model) rebuildSearchIndex() {
+	if m.searchIndex == nil {
+		return
+	}
+	m.searchIndex = newSearchIndex(m.displayLocation())
+	for _, entry := range m.entries.Oldest(0) {
+		m.searchIndex.add(entry)
+	}
+}
+
+// add indexes entry as the newest one, with the text Matches looks at.
+func (x *searchIndex) add(entry logs.LogEntry) {
+	seen := make(map[string]bool)
+	index := func(text string) {
+		for _, token := range logs.Tokenize(strings.ToLower(text)) {
+			if !seen[token] {
+				seen[token] = true
+				x.postings[token] = append(x.postings[token], x.next)
+			}
+		}
+	}
+	index(entry.Message)
+	index(entry.RawPreview())
+	index(entry.DisplayTimestampIn(x.location))
+	index(entry.Path)
+	for _, v := range entry.Extras {
+		index(v)
+	}
+	x.next++
+}
+
+// evict forgets the oldest entry.
+func (x *searchIndex) evict() {
+	x.oldest++
+	x.evicted++
+	if x.evicted < x.next-x.oldest {
+		return
+	}
+	// Drop what points before the buffer once that is as much as the
+	// buffer itself, which keeps eviction cheap on average.
+	x.evicted = 0
+	for token, list := range x.postings {
+		i := 0
+		for i < len(list) && list[i] < x.oldest {
+			i++
+		}
+		if i == len(list) {
+			delete(x.postings, token)
+		} else if i > 0 {
+			x.postings[token] = append(list[:0:0], list[i:]...)
+		}
+	}
+}
+
+// candidates returns which of the size newest entries, counting from the
+// newest, may contain all of words, or false if the index cannot tell,
+// e.g. for a word without letters or digits.
+func (x *searchIndex) candidates(words []string, size int) ([]bool, bool) {
+	var mask []bool
+	for _, word := range words {
+		tokens := logs.QueryTokens(word)
+		if len(tokens) == 0 {
+			return nil, false
+		}
+		for _, token := range tokens {
+			found := make([]bool, size)
+			for vocab, list := range x.postings {
+				if !token.Matches(vocab) {
+					continue
+				}
+				for _, n := range list {
+					if pos := x.next - 1 - n; n >= x.oldest && pos < size {
+						found[pos] = true
+					}
+				}
+			}
+			if mask == nil {
+				mask = found
+				continue
+			}
+			for i := range mask {
+				mask[i] = mask[i] && found[i]
+			}
+		}
+	}
+	return mask, mask != nil
+}
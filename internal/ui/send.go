@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// SendTarget is a webhook that :send posts entries to.
+type SendTarget struct {
+	Name string
+	URL  string
+	// Template renders the request body from sendTemplateData. Defaults to
+	// defaultSendTemplate, which suits Slack incoming webhooks.
+	Template string
+	Headers  map[string]string
+}
+
+// defaultSendTemplate posts the original lines as a Slack message.
+const defaultSendTemplate = `{"text": {{json .Text}}}`
+
+// sendTimeout bounds a webhook request.
+const sendTimeout = 10 * time.Second
+
+// sendTemplateData is what send templates can refer to: the entries with
+// the same fields as command templates, their original lines joined by
+// newlines, and their number.
+type sendTemplateData struct {
+	Entries []entryTemplateData
+	Text    string
+	Count   int
+}
+
+type sendResultMsg struct {
+	target string
+	count  int
+	err    error
+}
+
+// sendCommand posts the selection to a configured webhook: ":send
+// [target]". The target may be omitted when only one is configured.
+func (m *Model) sendCommand(name string) (tea.Cmd, error) {
+	target, err := m.sendTarget(name)
+	if err != nil {
+		return nil, err
+	}
+	entries := m.pipeTargets()
+	m.visualAnchor = ""
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no entries to send")
+	}
+	body, err := renderSendTemplate(target.Template, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	m.statusMessage = fmt.Sprintf("sending %d entries to %s", len(entries), target.Name)
+	return func() tea.Msg {
+		err := postWebhook(target, body)
+		return sendResultMsg{target: target.Name, count: len(entries), err: err}
+	}, nil
+}
+
+func (m Model) sendTarget(name string) (SendTarget, error) {
+	if len(m.sendTargets) == 0 {
+		return SendTarget{}, fmt.Errorf("send_targets is not configured")
+	}
+	if name == "" {
+		if len(m.sendTargets) > 1 {
+			return SendTarget{}, fmt.Errorf("choose a target: %s", strings.Join(m.sendTargetNames(), ", "))
+		}
+		return m.sendTargets[0], nil
+	}
+	for _, t := range m.sendTargets {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return SendTarget{}, fmt.Errorf("unknown target %q (configured: %s)", name, strings.Join(m.sendTargetNames(), ", "))
+}
+
+func (m Model) sendTargetNames() []string {
+	names := make([]string, len(m.sendTargets))
+	for i, t := range m.sendTargets {
+		names[i] = t.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+func renderSendTemplate(text string, entries []logs.LogEntry) ([]byte, error) {
+	if text == "" {
+		text = defaultSendTemplate
+	}
+	tmpl, err := template.New("send").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	data := sendTemplateData{Count: len(entries)}
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		data.Entries = append(data.Entries, newEntryTemplateData(entry))
+		lines[i] = entry.RawLine()
+	}
+	data.Text = strings.Join(lines, "\n")
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func postWebhook(target SendTarget, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range target.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, firstLine(strings.TrimSpace(string(msg))))
+	}
+	return nil
+}
+
+func (m *Model) handleSendResult(msg sendResultMsg) {
+	if msg.err != nil {
+		m.errorMessage = fmt.Sprintf("send to %s: %v", msg.target, msg.err)
+		return
+	}
+	m.statusMessage = fmt.Sprintf("sent %d entries to %s", msg.count, msg.target)
+}
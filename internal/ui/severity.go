@@ -0,0 +1,32 @@
+package ui
+
+import "github.com/marcuzy/logsviewer/internal/logs"
+
+// severitySteps are the minimum levels the l key cycles through; unknown
+// shows every entry.
+var severitySteps = []logs.Severity{logs.SeverityUnknown, logs.SeverityInfo, logs.SeverityWarn, logs.SeverityError}
+
+// cycleMinSeverity moves the minimum-level filter to its next step. While it
+// is set, entries without a recognizable level are hidden too.
+func (m *Model) cycleMinSeverity() {
+	next := severitySteps[0]
+	for i, s := range severitySteps {
+		if s == m.minSeverity {
+			next = severitySteps[(i+1)%len(severitySteps)]
+			break
+		}
+	}
+	key := m.selectionKey()
+	m.minSeverity = next
+	m.rebuildList()
+	m.selectEntryKey(key)
+	if next == logs.SeverityUnknown {
+		m.statusMessage = "showing all levels"
+	} else {
+		m.statusMessage = "showing " + next.String() + " and above"
+	}
+}
+
+func (m Model) severityVisible(entry logs.LogEntry) bool {
+	return m.minSeverity == logs.SeverityUnknown || logs.ParseSeverity(entryLevel(entry, m.levelField)) >= m.minSeverity
+}
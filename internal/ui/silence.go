@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// silenceCheckInterval is how often sources are checked for silence.
+const silenceCheckInterval = time.Second
+
+type silenceTickMsg struct{}
+
+func silenceTick() tea.Cmd {
+	return tea.Tick(silenceCheckInterval, func(time.Time) tea.Msg {
+		return silenceTickMsg{}
+	})
+}
+
+// checkSilence updates the set of sources that have not produced an entry
+// for the silence timeout, ringing the bell when one falls silent. Sources
+// that have not produced any entry yet count from the start of the
+// session.
+func (m *Model) checkSilence() {
+	now := time.Now()
+	paths := make(map[string]bool)
+	if m.stats != nil {
+		for _, s := range m.stats() {
+			paths[s.Path] = true
+		}
+	}
+	for path := range m.lastSeen {
+		paths[path] = true
+	}
+	newlySilent := false
+	for path := range paths {
+		last, ok := m.lastSeen[path]
+		if !ok {
+			last = m.started
+		}
+		if now.Sub(last) < m.silenceTimeout {
+			delete(m.silent, path)
+			continue
+		}
+		if _, ok := m.silent[path]; !ok {
+			newlySilent = true
+		}
+		m.silent[path] = last
+	}
+	if newlySilent && m.silenceBell {
+		ringBell()
+	}
+}
+
+// silenceBadge lists the silent sources for the status line.
+func (m Model) silenceBadge() string {
+	if len(m.silent) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(m.silent))
+	for path, last := range m.silent {
+		names = append(names, fmt.Sprintf("%s %s", filepath.Base(path), time.Since(last).Truncate(time.Second)))
+	}
+	sort.Strings(names)
+	return "silent: " + strings.Join(names, ", ")
+}
@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// skewCommand estimates how far the clocks of the sources are apart from
+// entries that share a correlation value: ":skew request_id [where ...]".
+// For every value seen in two sources the first entries of each are
+// compared; the median difference to the source sharing the most values
+// is the suggested time_offset of each other source.
+func (m *Model) skewCommand(line string) (tea.Cmd, error) {
+	field, entries, err := m.consoleQuery("skew", line)
+	if err != nil {
+		return nil, err
+	}
+	// first holds the earliest timestamp per value and source.
+	first := make(map[string]map[string]time.Time)
+	for _, entry := range entries {
+		if entry.Timestamp.IsZero() {
+			continue
+		}
+		v, ok := field.Value(entry)
+		if !ok || v == "" {
+			continue
+		}
+		bySource := first[v]
+		if bySource == nil {
+			bySource = make(map[string]time.Time)
+			first[v] = bySource
+		}
+		if ts, ok := bySource[entry.Path]; !ok || entry.Timestamp.Before(ts) {
+			bySource[entry.Path] = entry.Timestamp
+		}
+	}
+
+	shared := make(map[string]int)
+	for _, bySource := range first {
+		if len(bySource) < 2 {
+			continue
+		}
+		for path := range bySource {
+			shared[path]++
+		}
+	}
+	if len(shared) < 2 {
+		return nil, fmt.Errorf("no %s value appears in two sources", line)
+	}
+	paths := make([]string, 0, len(shared))
+	for path := range shared {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if shared[paths[i]] != shared[paths[j]] {
+			return shared[paths[i]] > shared[paths[j]]
+		}
+		return paths[i] < paths[j]
+	})
+	ref := paths[0]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "skew by %s, relative to %s\n\n", line, ref)
+	fmt.Fprintf(&b, "%-24s %10s %8s %10s\n", "source", "offset", "samples", "spread")
+	var config strings.Builder
+	for _, path := range paths[1:] {
+		var diffs []time.Duration
+		for _, bySource := range first {
+			refTS, ok1 := bySource[ref]
+			ts, ok2 := bySource[path]
+			if ok1 && ok2 {
+				diffs = append(diffs, refTS.Sub(ts))
+			}
+		}
+		if len(diffs) == 0 {
+			fmt.Fprintf(&b, "%-24s %10s %8d\n", filepath.Base(path), "-", 0)
+			continue
+		}
+		sort.Slice(diffs, func(i, j int) bool { return diffs[i] < diffs[j] })
+		offset := diffs[len(diffs)/2].Round(time.Millisecond)
+		// The interquartile range tells a steady skew from noise.
+		spread := (diffs[len(diffs)*3/4] - diffs[len(diffs)/4]).Round(time.Millisecond)
+		fmt.Fprintf(&b, "%-24s %10s %8d %10s\n", filepath.Base(path), offset, len(diffs), spread)
+		if offset != 0 {
+			fmt.Fprintf(&config, "  - match: %q\n    time_offset: %s\n", path, offset)
+		}
+	}
+	if config.Len() > 0 {
+		b.WriteString("\nTo correct the timestamps, add to the sources in the config (on top of\nany time_offset already set there):\n\nsources:\n")
+		b.WriteString(config.String())
+	}
+	m.showConsoleOutput(b.String())
+	return nil, nil
+}
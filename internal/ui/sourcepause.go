@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Source pause modes: hide the entries of the source while still reading
+// them, or stop reading the source until it is resumed.
+const (
+	SourcePauseDisplay = "display"
+	SourcePauseRead    = "read"
+)
+
+// pauseSourceCommand pauses the sources matching a path, file name or glob:
+// ":pause-source api.log [display|read]". The mode defaults to the
+// configured one.
+func (m *Model) pauseSourceCommand(line string) (tea.Cmd, error) {
+	args := strings.Fields(line)
+	if len(args) == 0 || len(args) > 2 {
+		return nil, fmt.Errorf("usage: pause-source <source> [display|read]")
+	}
+	mode := m.sourcePauseMode
+	if len(args) == 2 {
+		mode = args[1]
+	}
+	switch mode {
+	case SourcePauseDisplay:
+	case SourcePauseRead:
+		if m.setSourcePaused == nil {
+			return nil, fmt.Errorf("sources are not read in this session; use display")
+		}
+	default:
+		return nil, fmt.Errorf("unknown pause mode %q (supported: display, read)", mode)
+	}
+	paths := m.matchSources(args[0])
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no source matches %q", args[0])
+	}
+	key := m.selectionKey()
+	for _, path := range paths {
+		if mode == SourcePauseRead {
+			m.setSourcePaused(path, true)
+			m.readPaused[path] = true
+		} else {
+			m.hiddenSources[path] = true
+		}
+	}
+	m.rebuildList()
+	m.selectEntryKey(key)
+	m.statusMessage = fmt.Sprintf("paused %s (%s)", strings.Join(baseNames(paths), ", "), mode)
+	return nil, nil
+}
+
+// resumeSourceCommand resumes the sources matching the argument, or all
+// paused sources without one.
+func (m *Model) resumeSourceCommand(arg string) (tea.Cmd, error) {
+	var paths []string
+	if arg == "" {
+		paths = m.pausedSources()
+	} else {
+		paths = m.matchSources(arg)
+	}
+	resumed := 0
+	for _, path := range paths {
+		if m.readPaused[path] {
+			m.setSourcePaused(path, false)
+			delete(m.readPaused, path)
+			resumed++
+		} else if m.hiddenSources[path] {
+			delete(m.hiddenSources, path)
+			resumed++
+		}
+	}
+	if resumed == 0 {
+		return nil, fmt.Errorf("no paused source matches %q", arg)
+	}
+	key := m.selectionKey()
+	m.rebuildList()
+	m.selectEntryKey(key)
+	m.statusMessage = fmt.Sprintf("resumed %d source(s)", resumed)
+	return nil, nil
+}
+
+// matchSources returns the known sources whose path or file name equals
+// pattern or matches it as a glob.
+func (m Model) matchSources(pattern string) []string {
+	var out []string
+	for _, path := range m.sourcePaths() {
+		base := filepath.Base(path)
+		if pattern == path || pattern == base {
+			out = append(out, path)
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, path); ok {
+			out = append(out, path)
+		} else if ok, _ := filepath.Match(pattern, base); ok {
+			out = append(out, path)
+		}
+	}
+	return out
+}
+
+func (m Model) pausedSources() []string {
+	var out []string
+	for path := range m.readPaused {
+		out = append(out, path)
+	}
+	for path := range m.hiddenSources {
+		if !m.readPaused[path] {
+			out = append(out, path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// sourcePauseBadge lists the paused sources for the status line.
+func (m Model) sourcePauseBadge() string {
+	paths := m.pausedSources()
+	if len(paths) == 0 {
+		return ""
+	}
+	return "paused: " + strings.Join(baseNames(paths), ", ")
+}
+
+func baseNames(paths []string) []string {
+	out := make([]string, len(paths))
+	for i, path := range paths {
+		out[i] = filepath.Base(path)
+	}
+	return out
+}
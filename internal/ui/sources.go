@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// sourceLiveWindow is how recent the last entry of a source must be for the
+// source to be shown as live.
+const sourceLiveWindow = 10 * time.Second
+
+// toggleSourcesPanel shows or hides the sources panel in place of the
+// detail pane.
+func (m *Model) toggleSourcesPanel() tea.Cmd {
+	m.showSources = !m.showSources
+	m.needViewportSync = true
+	if !m.showSources {
+		return nil
+	}
+	m.debug = false
+	m.showWatches = false
+	return debugTick()
+}
+
+// sourcePaths returns every known source: those the tailer reports and
+// those that produced entries, sorted.
+func (m Model) sourcePaths() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	if m.stats != nil {
+		for _, s := range m.stats() {
+			add(s.Path)
+		}
+	}
+	for path := range m.lastSeen {
+		add(path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// sourcesView renders a heartbeat line per source: whether its file is
+// present, how long ago it produced its last entry and how many it
+// delivered, so that a dead input stands out.
+func (m Model) sourcesView() string {
+	var b strings.Builder
+	b.WriteString("Sources (S to close)\n\n")
+	paths := m.sourcePaths()
+	if len(paths) == 0 {
+		b.WriteString("No sources.\n")
+		return b.String()
+	}
+	delivered := make(map[string]int64)
+	tailed := make(map[string]bool)
+	if m.stats != nil {
+		for _, s := range m.stats() {
+			delivered[s.Path] = s.Delivered
+			tailed[s.Path] = true
+		}
+	}
+	now := time.Now()
+	for _, path := range paths {
+		state, mark := "idle", "○"
+		last, ok := m.lastSeen[path]
+		switch _, silent := m.silent[path]; {
+		case m.readPaused[path]:
+			state, mark = "paused, not reading", "‖"
+		case m.hiddenSources[path]:
+			state, mark = "paused, hidden", "‖"
+		case tailed[path] && path != logs.StdinPath && !fileExists(path):
+			state, mark = "missing", "✕"
+		case silent:
+			state, mark = "silent", "!"
+		case ok && now.Sub(last) < sourceLiveWindow:
+			state, mark = "live", "●"
+		}
+		age := "no entries yet"
+		if ok {
+			age = "last entry " + now.Sub(last).Truncate(time.Second).String() + " ago"
+		}
+		fmt.Fprintf(&b, "%s %s\n    %s, %s", mark, path, state, age)
+		if tailed[path] {
+			fmt.Fprintf(&b, ", %d delivered", delivered[path])
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "\n● live: an entry within %s  ○ idle  ! silent  ✕ file missing  ‖ paused\n", sourceLiveWindow)
+	return b.String()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
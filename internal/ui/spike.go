@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+const (
+	// spikeAlpha weights the latest second in the rolling baseline, about
+	// a one-minute average.
+	spikeAlpha = 1.0 / 60
+	// spikeWarmup is the number of seconds observed before spikes are
+	// flagged, so that the first burst of a session is not one.
+	spikeWarmup = 30
+	// spikeMinCount is the least entries per second that can be a spike.
+	spikeMinCount = 5
+	// spikeMaxSeconds bounds how many spike seconds are remembered.
+	spikeMaxSeconds = 4096
+)
+
+// rateBaseline is a rolling mean and variance of entries per second of
+// entry time.
+type rateBaseline struct {
+	second   int64 // current second, Unix time
+	count    int   // entries in the current second
+	mean     float64
+	variance float64
+	seen     int // completed seconds
+}
+
+// add counts an entry at second sec, closing the seconds before it with
+// their counts. Entries older than the current second are counted in it,
+// as merged sources are not strictly ordered.
+func (b *rateBaseline) add(sec int64) {
+	if b.seen == 0 && b.count == 0 {
+		b.second = sec
+	}
+	if sec > b.second {
+		b.close(b.count)
+		// Seconds without entries count as zero; after a few minutes the
+		// old rate has decayed anyway.
+		for gap := min(sec-b.second-1, 600); gap > 0; gap-- {
+			b.close(0)
+		}
+		b.second, b.count = sec, 0
+	}
+	b.count++
+}
+
+func (b *rateBaseline) close(n int) {
+	if b.seen == 0 {
+		b.mean = float64(n)
+	}
+	d := float64(n) - b.mean
+	b.mean += spikeAlpha * d
+	b.variance = (1 - spikeAlpha) * (b.variance + spikeAlpha*d*d)
+	b.seen++
+}
+
+// spiking reports whether the current second deviates from the baseline
+// by more than sigma standard deviations.
+func (b *rateBaseline) spiking(sigma float64) bool {
+	if b.seen < spikeWarmup || b.count < spikeMinCount {
+		return false
+	}
+	// The floor keeps a perfectly steady stream from flagging its first
+	// extra entry.
+	limit := b.mean + sigma*max(math.Sqrt(b.variance), 1)
+	return float64(b.count) > limit
+}
+
+// rateSpikes flags the seconds in which entries arrive much faster than
+// usual, overall or for one level.
+type rateSpikes struct {
+	sigma   float64
+	overall rateBaseline
+	levels  map[string]*rateBaseline
+	// spikes describes each flagged second; order keeps them oldest first
+	// to forget the oldest.
+	spikes map[int64]string
+	order  []int64
+}
+
+func newRateSpikes(sigma float64) *rateSpikes {
+	if sigma <= 0 {
+		return nil
+	}
+	return &rateSpikes{
+		sigma:  sigma,
+		levels: make(map[string]*rateBaseline),
+		spikes: make(map[int64]string),
+	}
+}
+
+// observe counts entry and reports whether this made its second a spike.
+func (r *rateSpikes) observe(entry logs.LogEntry) bool {
+	if entry.Timestamp.IsZero() {
+		return false
+	}
+	sec := entry.Timestamp.Unix()
+	r.overall.add(sec)
+	reason := ""
+	if r.overall.spiking(r.sigma) {
+		reason = describeSpike("all", &r.overall)
+	}
+	if level := entry.Level(); level != "" {
+		b, ok := r.levels[level]
+		if !ok {
+			b = &rateBaseline{}
+			r.levels[level] = b
+		}
+		b.add(sec)
+		if reason == "" && b.spiking(r.sigma) {
+			reason = describeSpike(level, b)
+		}
+	}
+	if reason == "" {
+		return false
+	}
+	_, known := r.spikes[sec]
+	r.spikes[sec] = reason
+	if known {
+		return false
+	}
+	r.order = append(r.order, sec)
+	if len(r.order) > spikeMaxSeconds {
+		delete(r.spikes, r.order[0])
+		r.order = r.order[1:]
+	}
+	return true
+}
+
+func describeSpike(what string, b *rateBaseline) string {
+	return fmt.Sprintf("%s %d/s, usually %.1f/s", what, b.count, b.mean)
+}
+
+// spike returns the description of the spike entry falls in, if any.
+func (r *rateSpikes) spike(entry logs.LogEntry) (string, bool) {
+	if r == nil || entry.Timestamp.IsZero() {
+		return "", false
+	}
+	reason, ok := r.spikes[entry.Timestamp.Unix()]
+	return reason, ok
+}
@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// spillMatches holds the spilled entries the filters show, newest first,
+// so that rebuilding the list only searches what was spilled since.
+type spillMatches struct {
+	// key identifies the filters the entries were matched with.
+	key     string
+	next    uint64
+	seqs    []uint64
+	entries []logs.LogEntry
+}
+
+// filterKey identifies everything entryVisible depends on.
+func (m Model) filterKey() string {
+	return fmt.Sprintf("%q %q %v %d %s %q", m.searchQuery, m.listFilterExpr, m.hiddenSources, m.minSeverity, m.displayLocation(), m.tab)
+}
+
+// spilledMatches returns the spilled entries the list shows, newest
+// first. The spill's word index narrows what is read to entries with the
+// searched words; entries matched before with the same filters are reused.
+func (m *Model) spilledMatches() []logs.LogEntry {
+	c := &m.spillMatches
+	if key := m.filterKey(); c.key != key {
+		*c = spillMatches{key: key}
+	}
+	words := append(m.searchFilter.Words(), m.listFilter.Words()...)
+	var (
+		seqs    []uint64
+		entries []logs.LogEntry
+	)
+	next, err := m.spill.Search(words, c.next, func(seq uint64, entry logs.LogEntry) bool {
+		if m.entryVisible(entry) {
+			seqs = append(seqs, seq)
+			entries = append(entries, entry)
+		}
+		return true
+	})
+	if err != nil {
+		m.errorMessage = err.Error()
+		return c.entries
+	}
+	c.next = next
+	c.seqs = append(seqs, c.seqs...)
+	c.entries = append(entries, c.entries...)
+	// Forget what the spill dropped beyond its limit.
+	n := len(c.seqs)
+	for n > 0 && c.seqs[n-1] < m.spill.Oldest() {
+		n--
+	}
+	c.seqs, c.entries = c.seqs[:n], c.entries[:n]
+	return c.entries
+}
@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// sqlCellWidth caps the width of a column of the :sql table.
+const sqlCellWidth = 48
+
+// sqlCommand runs a SELECT-style query over the listed entries, oldest
+// first, and shows the result as a table in the detail pane:
+// ":sql SELECT path, count(*) WHERE level=error GROUP BY path ORDER BY 2 DESC".
+func (m *Model) sqlCommand(line string) (tea.Cmd, error) {
+	if strings.TrimSpace(line) == "" {
+		return nil, fmt.Errorf("usage: sql SELECT <columns> [WHERE <filter>] [GROUP BY ...] [ORDER BY ...] [LIMIT n]")
+	}
+	query, err := logs.ParseSQL(line)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]logs.LogEntry, len(m.displayEntries))
+	for i, entry := range m.displayEntries {
+		entries[len(entries)-1-i] = entry
+	}
+	res := query.Run(entries)
+	m.showConsoleOutput(formatSQLResult(res))
+	m.statusMessage = fmt.Sprintf("sql: %d rows", len(res.Rows))
+	return nil, nil
+}
+
+// formatSQLResult lays out the result as a text table with a header.
+func formatSQLResult(res logs.SQLResult) string {
+	rows := res.Rows
+	more := 0
+	if len(rows) > consoleMaxLines {
+		rows, more = rows[:consoleMaxLines], len(rows)-consoleMaxLines
+	}
+	cell := func(s string) string {
+		s = sanitizeText(s, false)
+		if lipgloss.Width(s) > sqlCellWidth {
+			s = truncateWidth(s, sqlCellWidth-1) + "…"
+		}
+		return s
+	}
+	widths := make([]int, len(res.Columns))
+	header := make([]string, len(res.Columns))
+	for i, c := range res.Columns {
+		header[i] = cell(c)
+		widths[i] = lipgloss.Width(header[i])
+	}
+	cells := make([][]string, len(rows))
+	for r, row := range rows {
+		cells[r] = make([]string, len(row))
+		for i, v := range row {
+			cells[r][i] = cell(v)
+			widths[i] = max(widths[i], lipgloss.Width(cells[r][i]))
+		}
+	}
+	var b strings.Builder
+	writeRow := func(row []string) {
+		for i, v := range row {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			b.WriteString(v)
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-lipgloss.Width(v)))
+			}
+		}
+		b.WriteByte('\n')
+	}
+	writeRow(header)
+	rule := make([]string, len(widths))
+	for i, w := range widths {
+		rule[i] = strings.Repeat("─", w)
+	}
+	writeRow(rule)
+	for _, row := range cells {
+		writeRow(row)
+	}
+	if more > 0 {
+		fmt.Fprintf(&b, "... %d more rows\n", more)
+	}
+	fmt.Fprintf(&b, "\n%d rows from %d entries\n", len(res.Rows), res.Scanned)
+	return b.String()
+}
+
+// truncateWidth cuts s to at most width terminal cells.
+func truncateWidth(s string, width int) string {
+	w := 0
+	for i, r := range s {
+		w += lipgloss.Width(string(r))
+		if w > width {
+			return s[:i]
+		}
+	}
+	return s
+}
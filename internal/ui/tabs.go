@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// pushSourceEntry adds entry to the buffer of its source, which a tab
+// lists from, so that a busy file does not push a quiet one out of its
+// tab. An evicted entry is returned as by entryRing.Push.
+func (m *Model) pushSourceEntry(entry logs.LogEntry) (logs.LogEntry, bool) {
+	ring, ok := m.sourceEntries[entry.Path]
+	if !ok {
+		ring = newEntryRing(m.perSourceItems)
+		m.sourceEntries[entry.Path] = ring
+	}
+	return ring.Push(entry)
+}
+
+// selectTab shows only the entries of the source at path, or of all
+// sources if path is empty, keeping the selected entry if it is listed.
+func (m *Model) selectTab(path string) {
+	key := m.selectionKey()
+	m.tab = path
+	m.rebuildList()
+	if !m.selectEntryKey(key) && len(m.displayEntries) > 0 {
+		m.list.Select(0)
+	}
+	m.list.Title = m.tabTitle()
+	if path == "" {
+		m.statusMessage = "tab: all sources"
+	} else {
+		m.statusMessage = "tab: " + m.list.Title
+	}
+}
+
+// tabKey selects the tab of a number key: 0 for all sources, 1 to 9 for
+// the sources in the order of the sources panel.
+func (m *Model) tabKey(n int) bool {
+	paths := m.sourcePaths()
+	if len(paths) < 2 || n > len(paths) {
+		return false
+	}
+	if n == 0 {
+		m.selectTab("")
+	} else {
+		m.selectTab(paths[n-1])
+	}
+	return true
+}
+
+// stepTab selects the next (delta 1) or previous (-1) tab, all sources
+// coming before the first one.
+func (m *Model) stepTab(delta int) {
+	paths := m.sourcePaths()
+	if len(paths) < 2 {
+		m.statusMessage = "tabs need more than one source"
+		return
+	}
+	tabs := append([]string{""}, paths...)
+	i := max(slices.Index(tabs, m.tab), 0)
+	i = ((i+delta)%len(tabs) + len(tabs)) % len(tabs)
+	m.selectTab(tabs[i])
+}
+
+// tabTitle names the active tab for the list title.
+func (m Model) tabTitle() string {
+	if m.tab == "" {
+		return "Logs"
+	}
+	paths := m.sourcePaths()
+	return fmt.Sprintf("%s (%d/%d)", filepath.Base(m.tab), slices.Index(paths, m.tab)+1, len(paths))
+}
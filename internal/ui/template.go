@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"encoding/json"
+	"strings"
+	"text/template"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// entryTemplateData is what command templates can refer to, e.g.
+// {{.Path}} or {{.Fields.request_id}}. Extras holds the extra and
+// computed fields.
+type entryTemplateData struct {
+	Path      string
+	Timestamp string
+	Message   string
+	Level     string
+	Raw       string
+	Fields    map[string]string
+	Extras    map[string]string
+}
+
+var templateFuncs = template.FuncMap{
+	"quote": shellQuote,
+	"json":  jsonString,
+}
+
+// renderEntryTemplate expands a command or URL template for entry. Referring
+// to a field the entry lacks is an error. The quote function wraps a value
+// in single quotes for safe use in sh -c.
+func renderEntryTemplate(text string, entry logs.LogEntry) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("command").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	err = tmpl.Execute(&b, newEntryTemplateData(entry))
+	return b.String(), err
+}
+
+func newEntryTemplateData(entry logs.LogEntry) entryTemplateData {
+	entry = entry.Unpacked()
+	fields := make(map[string]string, len(entry.Fields))
+	for k, v := range entry.Fields {
+		fields[k] = logs.FormatValue(v)
+	}
+	return entryTemplateData{
+		Path:      entry.Path,
+		Timestamp: entry.DisplayTimestamp(),
+		Message:   entry.Message,
+		Level:     entry.Level(),
+		Raw:       entry.Raw,
+		Fields:    fields,
+		Extras:    entry.Extras,
+	}
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// jsonString encodes s as a JSON string literal, for building request
+// bodies.
+func jsonString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
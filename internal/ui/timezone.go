@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// displayTime returns the timestamp of entry in the zone chosen for the
+// list.
+func (m Model) displayTime(entry logs.LogEntry) string {
+	return entry.DisplayTimestampIn(m.displayLocation())
+}
+
+func (m Model) displayLocation() *time.Location {
+	if m.location == nil {
+		return time.Local
+	}
+	return m.location
+}
+
+// cycleTimezone switches the zone timestamps are shown in between local
+// time, UTC and the configured zone, if that is another one.
+func (m *Model) cycleTimezone() {
+	zones := []*time.Location{time.Local, time.UTC}
+	if c := m.configLocation; c != nil && c != time.Local && c != time.UTC {
+		zones = append(zones, c)
+	}
+	next := zones[0]
+	for i, zone := range zones {
+		if zone == m.displayLocation() {
+			next = zones[(i+1)%len(zones)]
+		}
+	}
+	m.location = next
+	m.rebuildSearchIndex()
+	key := m.selectionKey()
+	m.rebuildList()
+	m.selectEntryKey(key)
+	m.statusMessage = "timezone: " + next.String()
+}
@@ -0,0 +1,53 @@
+package ui
+
+import "github.com/marcuzy/logsviewer/internal/logs"
+
+// ViewState is what other instances may mirror of a viewer's view: the /
+// search, the :filter expression and the key of the selected entry.
+type ViewState struct {
+	Search   string
+	Filter   string
+	Selected string
+}
+
+// ViewMsg makes a Model show the given view, as an instance attached to a
+// shared session does to follow its host.
+type ViewMsg struct {
+	View ViewState
+}
+
+func (m Model) viewState() ViewState {
+	return ViewState{Search: m.searchQuery, Filter: m.listFilterExpr, Selected: m.selectionKey()}
+}
+
+// reportView passes the view to Options.OnViewChange when it changed.
+func (m *Model) reportView() {
+	if m.onViewChange == nil {
+		return
+	}
+	if v := m.viewState(); v != m.reportedView {
+		m.reportedView = v
+		m.onViewChange(v)
+	}
+}
+
+// applyView shows view. An invalid filter is left as it was, and a
+// selected entry that has not arrived yet is not selected.
+func (m *Model) applyView(view ViewState) {
+	if view.Filter != m.listFilterExpr {
+		if filter, err := logs.ParseFilter(view.Filter); err == nil {
+			m.listFilter, m.listFilterExpr, m.listFilterName = filter, view.Filter, ""
+			if view.Search == m.searchQuery {
+				m.rebuildList()
+			}
+		}
+	}
+	if view.Search != m.searchQuery {
+		m.applySearch(view.Search)
+	}
+	if view.Selected != "" && view.Selected != m.selectionKey() {
+		m.selectEntryKey(view.Selected)
+		// The host's selection is mirrored, whether or not it follows.
+		m.follow = m.list.Index() == 0
+	}
+}
@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// toggleVisual starts a range selection anchored at the selected entry, or
+// ends the current one. The anchor is tracked by entry key because the list
+// indexes shift as new entries arrive.
+func (m *Model) toggleVisual() {
+	if m.visualAnchor != "" {
+		m.visualAnchor = ""
+		m.statusMessage = "selection cleared"
+		return
+	}
+	m.visualAnchor = m.selectionKey()
+	if m.visualAnchor != "" {
+		m.statusMessage = "visual: move to extend, :export to save the range"
+	}
+}
+
+// visualRange returns the displayed indexes covered by the selection,
+// lo <= hi. ok is false when no selection is active or its anchor is no
+// longer displayed.
+func (m Model) visualRange() (lo, hi int, ok bool) {
+	if m.visualAnchor == "" {
+		return 0, 0, false
+	}
+	anchor := -1
+	for i, entry := range m.displayEntries {
+		if entryKey(entry) == m.visualAnchor {
+			anchor = i
+			break
+		}
+	}
+	cur := m.list.Index()
+	if anchor < 0 || cur < 0 {
+		return 0, 0, false
+	}
+	if anchor > cur {
+		return cur, anchor, true
+	}
+	return anchor, cur, true
+}
+
+// selectedEntries returns the entries of the active selection, oldest
+// first.
+func (m Model) selectedEntries() []logs.LogEntry {
+	lo, hi, ok := m.visualRange()
+	if !ok {
+		return nil
+	}
+	out := make([]logs.LogEntry, 0, hi-lo+1)
+	for i := hi; i >= lo; i-- {
+		out = append(out, m.displayEntries[i])
+	}
+	return out
+}
+
+func (m Model) visualStatus() string {
+	lo, hi, ok := m.visualRange()
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("visual: %d selected", hi-lo+1)
+}
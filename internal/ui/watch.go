@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// watchBucket is the time covered by one sparkline bar, and watchBars the
+// number of bars shown.
+const (
+	watchBucket = time.Minute
+	watchBars   = 30
+)
+
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// watch is a live query counting matching entries over the session.
+type watch struct {
+	expr   string
+	filter logs.Filter
+	count  int
+	// buckets counts matches per watchBucket of entry time; newest is the
+	// latest bucket seen.
+	buckets map[int64]int
+	newest  int64
+}
+
+func newWatch(expr string) (*watch, error) {
+	filter, err := logs.ParseFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Empty() {
+		return nil, fmt.Errorf("empty watch expression")
+	}
+	return &watch{expr: expr, filter: filter, buckets: make(map[int64]int)}, nil
+}
+
+// observe counts entry if it matches, with text matched against its
+// timestamp as shown in loc.
+func (w *watch) observe(entry logs.LogEntry, loc *time.Location) {
+	if !w.filter.In(loc).Match(entry) {
+		return
+	}
+	w.count++
+	at := entry.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	bucket := at.UnixNano() / int64(watchBucket)
+	w.buckets[bucket]++
+	if bucket > w.newest {
+		w.newest = bucket
+		for b := range w.buckets {
+			if b <= w.newest-watchBars {
+				delete(w.buckets, b)
+			}
+		}
+	}
+}
+
+// sparkline renders the matches per bucket for the latest watchBars
+// buckets, scaled to the busiest one.
+func (w *watch) sparkline() string {
+	peak := 0
+	for _, n := range w.buckets {
+		peak = max(peak, n)
+	}
+	var b strings.Builder
+	for i := w.newest - watchBars + 1; i <= w.newest; i++ {
+		if n := w.buckets[i]; n > 0 {
+			b.WriteRune(sparkLevels[(n*len(sparkLevels)-1)/peak])
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return b.String()
+}
+
+// addWatch registers a watch and counts the buffered entries into it.
+func (m *Model) addWatch(expr string) error {
+	w, err := newWatch(expr)
+	if err != nil {
+		return err
+	}
+	for _, entry := range m.entries.Oldest(0) {
+		w.observe(entry, m.displayLocation())
+	}
+	m.watches = append(m.watches, w)
+	return nil
+}
+
+// watchCommand adds a watch expression and shows the watch panel:
+// ":watch status>=500". Without an expression it toggles the panel.
+func (m *Model) watchCommand(expr string) (tea.Cmd, error) {
+	if expr == "" {
+		m.toggleWatchPanel()
+		return nil, nil
+	}
+	if err := m.addWatch(expr); err != nil {
+		return nil, err
+	}
+	m.showWatches = true
+	m.debug = false
+	m.showSources = false
+	m.needViewportSync = true
+	m.statusMessage = "watching " + expr
+	return nil, nil
+}
+
+// unwatchCommand removes a watch by its number in the panel: ":unwatch 2".
+func (m *Model) unwatchCommand(arg string) (tea.Cmd, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > len(m.watches) {
+		return nil, fmt.Errorf("usage: unwatch <1-%d>", len(m.watches))
+	}
+	m.watches = append(m.watches[:n-1], m.watches[n:]...)
+	m.statusMessage = "watch removed"
+	return nil, nil
+}
+
+func (m *Model) toggleWatchPanel() {
+	m.showWatches = !m.showWatches
+	if m.showWatches {
+		m.debug = false
+		m.showSources = false
+	}
+	m.needViewportSync = true
+}
+
+// watchView renders each watch with its running count and a sparkline of
+// matches per minute.
+func (m Model) watchView() string {
+	var b strings.Builder
+	b.WriteString("Watches (W to close)\n\n")
+	if len(m.watches) == 0 {
+		b.WriteString("No watches. Add one with :watch <filter>, e.g. :watch level=error\n")
+		return b.String()
+	}
+	for i, w := range m.watches {
+		fmt.Fprintf(&b, "%d. %s\n   %d  %s\n\n", i+1, w.expr, w.count, w.sparkline())
+	}
+	fmt.Fprintf(&b, "One bar per %s, newest on the right. :unwatch <n> removes a watch.\n", watchBucket)
+	return b.String()
+}
@@ -0,0 +1,255 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// wizardSampleSize is the number of initial entries inspected before
+// deciding whether the configured field mapping looks wrong.
+const wizardSampleSize = 20
+
+type wizardStep int
+
+const (
+	wizardPickTimestamp wizardStep = iota
+	wizardPickMessage
+	wizardConfirmSave
+)
+
+// fieldWizard lets the user pick timestamp/message fields from the keys
+// detected in the sampled entries.
+type fieldWizard struct {
+	step     wizardStep
+	keys     []string
+	previews map[string]string
+	cursor   int
+
+	timestampField string
+	messageField   string
+}
+
+func newFieldWizard(sample []logs.LogEntry, timestampField, messageField string) *fieldWizard {
+	previews := make(map[string]string)
+	for _, entry := range sample {
+		for key := range entry.FieldMap() {
+			if _, ok := previews[key]; ok {
+				continue
+			}
+			previews[key] = entry.FieldPreview(key, 40)
+		}
+	}
+	keys := make([]string, 0, len(previews))
+	for key := range previews {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w := &fieldWizard{
+		keys:           keys,
+		previews:       previews,
+		timestampField: timestampField,
+		messageField:   messageField,
+	}
+	w.cursor = w.indexOf(timestampField)
+	return w
+}
+
+func (w *fieldWizard) indexOf(key string) int {
+	for i, k := range w.keys {
+		if k == key {
+			return i
+		}
+	}
+	return 0
+}
+
+// choose records the highlighted key for the current step and advances.
+func (w *fieldWizard) choose() {
+	if len(w.keys) == 0 {
+		w.step = wizardConfirmSave
+		return
+	}
+	key := w.keys[w.cursor]
+	switch w.step {
+	case wizardPickTimestamp:
+		w.timestampField = key
+		w.step = wizardPickMessage
+		w.cursor = w.indexOf(w.messageField)
+	case wizardPickMessage:
+		w.messageField = key
+		w.step = wizardConfirmSave
+	}
+}
+
+// skip keeps the current mapping for the active step.
+func (w *fieldWizard) skip() {
+	switch w.step {
+	case wizardPickTimestamp:
+		w.step = wizardPickMessage
+		w.cursor = w.indexOf(w.messageField)
+	case wizardPickMessage:
+		w.step = wizardConfirmSave
+	}
+}
+
+func (w *fieldWizard) move(delta int) {
+	if len(w.keys) == 0 {
+		return
+	}
+	w.cursor = (w.cursor + delta + len(w.keys)) % len(w.keys)
+}
+
+func (w *fieldWizard) view(height int) string {
+	var b strings.Builder
+	switch w.step {
+	case wizardPickTimestamp:
+		b.WriteString("Timestamp field not found. Pick the field holding the time:\n")
+	case wizardPickMessage:
+		b.WriteString("Message field not found. Pick the field holding the message:\n")
+	case wizardConfirmSave:
+		fmt.Fprintf(&b, "Mapping: timestamp=%s message=%s\n\n", w.timestampField, w.messageField)
+		b.WriteString("Save mapping to config? (y)es / (n)o")
+		return b.String()
+	}
+	b.WriteString("↑/↓ move  enter select  s skip  esc cancel\n\n")
+
+	visible := height - 3
+	if visible < 1 {
+		visible = len(w.keys)
+	}
+	start := 0
+	if w.cursor >= visible {
+		start = w.cursor - visible + 1
+	}
+	for i := start; i < len(w.keys) && i < start+visible; i++ {
+		marker := "  "
+		if i == w.cursor {
+			marker = "> "
+		}
+		key := w.keys[i]
+		fmt.Fprintf(&b, "%s%s  %s\n", marker, key, w.previews[key])
+	}
+	if len(w.keys) == 0 {
+		b.WriteString("(no fields detected)\n")
+	}
+	return b.String()
+}
+
+// observeForWizard collects the first entries and opens the wizard when
+// none of them yields a timestamp or a message.
+func (m *Model) observeForWizard(entry logs.LogEntry) {
+	if m.wizardChecked {
+		return
+	}
+	m.wizardSample = append(m.wizardSample, entry)
+	if len(m.wizardSample) < wizardSampleSize {
+		return
+	}
+	m.checkFieldMapping()
+}
+
+func (m *Model) checkFieldMapping() {
+	if m.wizardChecked {
+		return
+	}
+	m.wizardChecked = true
+	sample := m.wizardSample
+	m.wizardSample = nil
+	if len(sample) == 0 {
+		return
+	}
+
+	hasTimestamp, hasMessage := false, false
+	for _, entry := range sample {
+		if entry.TimestampText != "" {
+			hasTimestamp = true
+		}
+		if entry.Message != "" {
+			hasMessage = true
+		}
+	}
+	if hasTimestamp && hasMessage {
+		return
+	}
+	m.openWizard(sample)
+}
+
+func (m *Model) openWizard(sample []logs.LogEntry) {
+	w := newFieldWizard(sample, m.timestampField, m.messageField)
+	if len(w.keys) == 0 {
+		return
+	}
+	m.wizard = w
+	m.statusMessage = "field mapping"
+}
+
+func (m *Model) handleWizardKey(key string) {
+	w := m.wizard
+	if w.step == wizardConfirmSave {
+		switch key {
+		case "y", "Y":
+			m.finishWizard(true)
+		case "n", "N", "esc", "enter":
+			m.finishWizard(false)
+		}
+		return
+	}
+	switch key {
+	case "up", "k":
+		w.move(-1)
+	case "down", "j":
+		w.move(1)
+	case "pgup":
+		w.move(-10)
+	case "pgdown":
+		w.move(10)
+	case "enter":
+		w.choose()
+	case "s":
+		w.skip()
+	case "esc":
+		m.wizard = nil
+		m.statusMessage = "field mapping unchanged"
+		m.needViewportSync = true
+	}
+}
+
+func (m *Model) finishWizard(save bool) {
+	w := m.wizard
+	m.wizard = nil
+	m.needViewportSync = true
+
+	m.timestampField = w.timestampField
+	m.messageField = w.messageField
+	m.remapFields = true
+	remap := func(ring *entryRing) {
+		for i := 0; i < ring.Len(); i++ {
+			ring.Set(i, ring.At(i).WithMapping(m.timestampField, m.messageField))
+		}
+	}
+	remap(m.entries)
+	for _, ring := range m.sourceEntries {
+		remap(ring)
+	}
+	// Messages changed; index them again.
+	m.rebuildSearchIndex()
+	m.rebuildList()
+	m.statusMessage = fmt.Sprintf("mapping: timestamp=%s message=%s", m.timestampField, m.messageField)
+
+	if !save {
+		return
+	}
+	if m.saveMapping == nil {
+		m.errorMessage = "saving mapping is not supported"
+		return
+	}
+	if err := m.saveMapping(m.timestampField, m.messageField); err != nil {
+		m.errorMessage = err.Error()
+		return
+	}
+	m.statusMessage += " (saved)"
+}
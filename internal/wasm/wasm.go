@@ -0,0 +1,219 @@
+// Package wasm runs parser plugins compiled to WebAssembly in the wazero
+// runtime. A plugin is sandboxed: it sees only the entries it is given and
+// has no access to files, the network or the environment.
+//
+// A plugin exports its memory and two functions:
+//
+//	alloc(size i32) i32          returns a buffer of size bytes for the input
+//	parse(ptr i32, len i32) i64  handles the input written at ptr
+//
+// The input is the entry as JSON in the export.Record schema, the one
+// processors and scripts see, with the raw line added as "raw". parse
+// returns the address of its output in the high 32 bits and the length in
+// the low ones. The output is a record that replaces the entry's
+// timestamp, message, extras and fields, or null to drop the entry; an
+// empty output keeps the entry as it is. Modules built for WASI, such as
+// TinyGo's or Go's wasip1 reactors, are started through _initialize, and
+// what they write to stderr is reported.
+package wasm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/marcuzy/logsviewer/internal/export"
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// callTimeout bounds one call of a plugin, so that a plugin stuck in a
+// loop fails instead of stalling the tailer.
+const callTimeout = time.Second
+
+// input is what parse receives.
+type input struct {
+	export.Record
+	Raw string `json:"raw"`
+}
+
+// Plugin is a loaded parser plugin. Calls are serialized, as a module
+// instance runs one at a time.
+type Plugin struct {
+	path string
+	ctx  context.Context
+	// extraFields are refreshed from the changed fields unless the plugin
+	// sets them itself.
+	extraFields []string
+	errs        logs.ErrorSink
+	// stderr receives what the instances write to stderr.
+	stderr io.Writer
+
+	mu       sync.Mutex
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	// mod is the running instance, nil after a failed call until the next
+	// one instantiates the module again.
+	mod         api.Module
+	alloc, call api.Function
+	closed      bool
+}
+
+// Load compiles the module at path and checks its exports. The plugin is
+// closed when ctx is done. extraFields are the configured extra fields of
+// the entries.
+func Load(ctx context.Context, path string, extraFields []string) (*Plugin, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", path, err)
+	}
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("plugin %s: %w", path, err)
+	}
+	compiled, err := runtime.CompileModule(ctx, code)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("plugin %s: %w", path, err)
+	}
+	p := &Plugin{
+		path:        path,
+		ctx:         ctx,
+		extraFields: extraFields,
+		errs:        logs.NewErrorSink(),
+		runtime:     runtime,
+		compiled:    compiled,
+	}
+	p.stderr = p.stderrReporter()
+	if err := p.instantiate(); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("plugin %s: %w", path, err)
+	}
+	go func() {
+		<-ctx.Done()
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.closed = true
+		p.runtime.Close(context.Background())
+	}()
+	return p, nil
+}
+
+// instantiate starts a fresh instance of the module.
+func (p *Plugin) instantiate() error {
+	cfg := wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize").
+		WithStderr(p.stderr)
+	mod, err := p.runtime.InstantiateModule(p.ctx, p.compiled, cfg)
+	if err != nil {
+		return err
+	}
+	alloc, call := mod.ExportedFunction("alloc"), mod.ExportedFunction("parse")
+	if alloc == nil || call == nil || mod.Memory() == nil {
+		mod.Close(p.ctx)
+		return fmt.Errorf("module exports no memory, alloc(size) and parse(ptr, len)")
+	}
+	p.mod, p.alloc, p.call = mod, alloc, call
+	return nil
+}
+
+// stderrReporter returns a writer that reports every line written to it.
+func (p *Plugin) stderrReporter() io.Writer {
+	r, w := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			p.errs.Report(fmt.Errorf("plugin %s: %s", p.path, scanner.Text()))
+		}
+	}()
+	return w
+}
+
+// Transform returns the hook that runs the plugin for every entry. An
+// entry the plugin fails on passes unchanged and the error is reported.
+func (p *Plugin) Transform() logs.TransformFunc {
+	return func(entry logs.LogEntry) (logs.LogEntry, bool) {
+		out, keep, err := p.run(entry)
+		if err != nil {
+			p.errs.Report(fmt.Errorf("plugin %s: %w", p.path, err))
+			return entry, true
+		}
+		return out, keep
+	}
+}
+
+// Errors reports plugin failures. It is never closed.
+func (p *Plugin) Errors() <-chan error {
+	return p.errs
+}
+
+func (p *Plugin) run(entry logs.LogEntry) (logs.LogEntry, bool, error) {
+	data, err := json.Marshal(input{Record: export.NewRecord(entry), Raw: entry.RawLine()})
+	if err != nil {
+		return entry, true, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return entry, true, nil
+	}
+	if p.mod == nil {
+		if err := p.instantiate(); err != nil {
+			return entry, true, err
+		}
+	}
+	reply, err := p.exchange(data)
+	if err != nil {
+		// The instance may be left in any state; start afresh next time.
+		p.mod.Close(p.ctx)
+		p.mod = nil
+		return entry, true, err
+	}
+	reply = strings.TrimSpace(reply)
+	switch reply {
+	case "":
+		return entry, true, nil
+	case "null":
+		return entry, false, nil
+	}
+	var rec export.Record
+	if err := json.Unmarshal([]byte(reply), &rec); err != nil {
+		return entry, true, fmt.Errorf("invalid output: %w", err)
+	}
+	return rec.Apply(entry, p.extraFields), true, nil
+}
+
+// exchange passes data to parse and returns its output.
+func (p *Plugin) exchange(data []byte) (string, error) {
+	ctx, cancel := context.WithTimeout(p.ctx, callTimeout)
+	defer cancel()
+	res, err := p.alloc.Call(ctx, uint64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("alloc: %w", err)
+	}
+	ptr := uint32(res[0])
+	if !p.mod.Memory().Write(ptr, data) {
+		return "", fmt.Errorf("alloc returned %d, outside memory", ptr)
+	}
+	res, err = p.call.Call(ctx, uint64(ptr), uint64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("parse: %w", err)
+	}
+	outPtr, outLen := uint32(res[0]>>32), uint32(res[0])
+	out, ok := p.mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return "", fmt.Errorf("parse returned %d bytes at %d, outside memory", outLen, outPtr)
+	}
+	return string(out), nil
+}
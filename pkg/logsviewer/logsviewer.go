@@ -0,0 +1,152 @@
+// Package logsviewer is the public API for embedding the log viewer in
+// other Go programs: the tailer that follows and parses log files, the
+// filter language, and the Bubble Tea model of the viewer itself.
+//
+// The options, the Tailer and the Model are declared in this package and
+// mapped onto the implementation, so they stay as they are when the
+// logsviewer command's own settings change. Entry, Filter, ComputedField,
+// SourceStats, SourceEvent and SourceEventKind are the implementation's
+// values; they may gain fields and methods but keep the ones they have.
+// Only what is declared in this package is covered by compatibility
+// promises.
+//
+// A minimal embedding follows files and shows them in the viewer:
+//
+//	ctx, cancel := context.WithCancel(context.Background())
+//	tailer := logsviewer.NewTailer([]string{"app.log"}, logsviewer.TailerOptions{
+//		Parser:    logsviewer.DefaultParserConfig(),
+//		TailLines: 200,
+//	})
+//	entries, errs := tailer.Start(ctx)
+//	model := logsviewer.NewModel(logsviewer.ViewerOptions{
+//		Entries: entries,
+//		Errors:  errs,
+//		Stats:   tailer.Stats,
+//		Cancel:  cancel,
+//	})
+//	_, err := tea.NewProgram(model, tea.WithAltScreen()).Run()
+//
+// The model is an ordinary tea.Model and can be nested in another program
+// by forwarding messages to its Update and rendering its View.
+package logsviewer
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// Entry is a parsed log line.
+type Entry = logs.LogEntry
+
+// ParserConfig selects the line format and the timestamp and message
+// fields.
+type ParserConfig struct {
+	// Format is a line format: json, logfmt, nginx, regex, docker or plain.
+	Format         string
+	TimestampField string
+	MessageField   string
+	// ExtraFields are shown next to the message in the list.
+	ExtraFields []string
+	// StripANSI removes terminal escape sequences from lines before they
+	// are parsed.
+	StripANSI bool
+	// Pattern is the expression of the regex format, whose named groups
+	// become fields.
+	Pattern *regexp.Regexp
+	// LevelMap maps lower-cased raw level values, "30" or "warning", to
+	// the level entries report instead.
+	LevelMap map[string]string
+}
+
+func (c ParserConfig) internal() logs.ParserConfig {
+	return logs.ParserConfig{
+		Format:         c.Format,
+		TimestampField: c.TimestampField,
+		MessageField:   c.MessageField,
+		ExtraFields:    c.ExtraFields,
+		StripANSI:      c.StripANSI,
+		Pattern:        c.Pattern,
+		LevelMap:       c.LevelMap,
+	}
+}
+
+// DefaultParserConfig returns the parser settings of the logsviewer command
+// without a config file: JSON lines with "timestamp" and "message" fields,
+// showing "level" as an extra field.
+func DefaultParserConfig() ParserConfig {
+	return ParserConfig{
+		Format:         logs.DefaultFormat,
+		TimestampField: "timestamp",
+		MessageField:   "message",
+		ExtraFields:    []string{"level"},
+	}
+}
+
+// ParserProfile applies a format to files matching a glob pattern; empty
+// fields are taken from the ParserConfig.
+type ParserProfile struct {
+	Match          string
+	Format         string
+	TimestampField string
+	MessageField   string
+	// Pattern is the expression of the regex format.
+	Pattern *regexp.Regexp
+}
+
+func internalProfiles(profiles []ParserProfile) []logs.ParserProfile {
+	out := make([]logs.ParserProfile, len(profiles))
+	for i, p := range profiles {
+		out[i] = logs.ParserProfile{
+			Match:          p.Match,
+			Format:         p.Format,
+			TimestampField: p.TimestampField,
+			MessageField:   p.MessageField,
+			Pattern:        p.Pattern,
+		}
+	}
+	return out
+}
+
+// TransformFunc rewrites or drops entries after parsing; see
+// TailerOptions.Transform.
+type TransformFunc = logs.TransformFunc
+
+// Filter selects entries by field conditions and text.
+type Filter = logs.Filter
+
+// ComputedField derives a field from others by an expression.
+type ComputedField = logs.ComputedField
+
+// ParseLine parses a single line as if it was read from path.
+func ParseLine(path, line string, base ParserConfig, profiles []ParserProfile) (Entry, error) {
+	return logs.ParseLine(path, line, base.internal(), internalProfiles(profiles))
+}
+
+// ParseFilter parses a filter expression such as "level=error status>=500".
+func ParseFilter(expr string) (Filter, error) {
+	return logs.ParseFilter(expr)
+}
+
+// ParseComputedField parses a "name = expression" definition.
+func ParseComputedField(def string) (ComputedField, error) {
+	return logs.ParseComputedField(def)
+}
+
+// ComputeTransform returns a transform that sets fields on every entry.
+func ComputeTransform(fields []ComputedField) TransformFunc {
+	return logs.ComputeTransform(fields)
+}
+
+// ChainTransforms applies transforms in order.
+func ChainTransforms(transforms ...TransformFunc) TransformFunc {
+	return logs.ChainTransforms(transforms...)
+}
+
+// MergeByTime reorders entries of several files by timestamp, holding each
+// back for at most window.
+func MergeByTime(ctx context.Context, in <-chan Entry, window time.Duration) <-chan Entry {
+	return logs.MergeByTime(ctx, in, window)
+}
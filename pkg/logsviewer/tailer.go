@@ -0,0 +1,151 @@
+package logsviewer
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/marcuzy/logsviewer/internal/logs"
+)
+
+// BackpressurePolicy decides what happens when entries are not consumed
+// fast enough.
+type BackpressurePolicy string
+
+// Backpressure policies.
+const (
+	// BackpressureBlock waits until the consumer catches up.
+	BackpressureBlock BackpressurePolicy = "block"
+	// BackpressureDropOldest discards the oldest queued entry.
+	BackpressureDropOldest BackpressurePolicy = "drop-oldest"
+	// BackpressureDropNewest discards the entry being delivered.
+	BackpressureDropNewest BackpressurePolicy = "drop-newest"
+)
+
+// SourceOptions overrides tailer settings for files matching a glob
+// pattern.
+type SourceOptions struct {
+	Match        string
+	Backpressure BackpressurePolicy
+	// ReadChunkSize is the read buffer size used for the file.
+	ReadChunkSize int
+	// Join appends lines matching it to the line before, e.g. the lines of
+	// a stack trace. JoinJSON instead joins every line that does not start
+	// a JSON object.
+	Join     *regexp.Regexp
+	JoinJSON bool
+	// TimeOffset is added to the timestamps of the file's entries, to
+	// correct the clock of the machine that wrote it.
+	TimeOffset time.Duration
+}
+
+// SourceStats are the delivery counters of one file.
+type SourceStats = logs.SourceStats
+
+// SourceEvent reports a file that started or stopped being tailed after
+// startup.
+type SourceEvent = logs.SourceEvent
+
+// SourceEventKind classifies a SourceEvent.
+type SourceEventKind = logs.SourceEventKind
+
+// Kinds of SourceEvent.
+const (
+	SourceAdded   = logs.SourceAdded
+	SourceRemoved = logs.SourceRemoved
+)
+
+// TailerOptions configures a Tailer.
+type TailerOptions struct {
+	Parser   ParserConfig
+	Profiles []ParserProfile
+	// TailLines is how many lines to read from the end of the files
+	// present at startup, 0 for all of them.
+	TailLines int
+	// Backpressure is the default policy applied when the entries channel
+	// is full; Sources may override it per file.
+	Backpressure BackpressurePolicy
+	Sources      []SourceOptions
+	// EntryBuffer and ErrorBuffer size the channels returned by Start.
+	EntryBuffer int
+	ErrorBuffer int
+	// ReadChunkSize is the default read buffer size per file.
+	ReadChunkSize int
+	// MaxEntrySize caps the raw bytes kept per entry. Longer lines are
+	// truncated in memory and re-read from the file on demand.
+	MaxEntrySize int
+	// Once reads the current contents of the files and stops instead of
+	// following them.
+	Once bool
+	// Transform, if set, runs on every parsed entry before it is
+	// delivered and may rewrite or drop it.
+	Transform TransformFunc
+	// Stdin is read for the file "-"; it defaults to os.Stdin.
+	Stdin io.Reader
+	// SkipExisting starts at the end of the files present at startup, so
+	// only what is appended to them is delivered. TailLines is ignored.
+	SkipExisting bool
+}
+
+func (o TailerOptions) internal() logs.Options {
+	sources := make([]logs.SourceOptions, len(o.Sources))
+	for i, s := range o.Sources {
+		sources[i] = logs.SourceOptions{
+			Match:         s.Match,
+			Backpressure:  logs.BackpressurePolicy(s.Backpressure),
+			ReadChunkSize: s.ReadChunkSize,
+			Join:          s.Join,
+			JoinJSON:      s.JoinJSON,
+			TimeOffset:    s.TimeOffset,
+		}
+	}
+	return logs.Options{
+		Parser:        o.Parser.internal(),
+		Profiles:      internalProfiles(o.Profiles),
+		TailLines:     o.TailLines,
+		Backpressure:  logs.BackpressurePolicy(o.Backpressure),
+		Sources:       sources,
+		EntryBuffer:   o.EntryBuffer,
+		ErrorBuffer:   o.ErrorBuffer,
+		ReadChunkSize: o.ReadChunkSize,
+		MaxEntrySize:  o.MaxEntrySize,
+		Once:          o.Once,
+		Transform:     o.Transform,
+		Stdin:         o.Stdin,
+		SkipExisting:  o.SkipExisting,
+	}
+}
+
+// Tailer follows a set of files, globs included, and streams their
+// entries.
+type Tailer struct {
+	t *logs.Tailer
+}
+
+// NewTailer returns a Tailer for files. Call Start to begin reading.
+func NewTailer(files []string, opts TailerOptions) *Tailer {
+	return &Tailer{t: logs.NewTailer(files, opts.internal())}
+}
+
+// Start begins streaming entries until ctx is canceled, or until the
+// files are read when Once is set.
+func (t *Tailer) Start(ctx context.Context) (<-chan Entry, <-chan error) {
+	return t.t.Start(ctx)
+}
+
+// Stats returns the delivery counters of the files read so far.
+func (t *Tailer) Stats() []SourceStats {
+	return t.t.Stats()
+}
+
+// Events reports files that started or stopped being tailed after
+// startup, e.g. new files matching a glob pattern.
+func (t *Tailer) Events() <-chan SourceEvent {
+	return t.t.Events()
+}
+
+// SetPaused stops or resumes reading the file at path.
+func (t *Tailer) SetPaused(path string, paused bool) {
+	t.t.SetPaused(path, paused)
+}
@@ -0,0 +1,208 @@
+package logsviewer
+
+import (
+	"context"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcuzy/logsviewer/internal/ui"
+)
+
+// Layouts of the viewer.
+const (
+	// LayoutVertical places the list beside the detail pane.
+	LayoutVertical = ui.LayoutVertical
+	// LayoutHorizontal places the list above the detail pane.
+	LayoutHorizontal = ui.LayoutHorizontal
+	// LayoutAuto picks by the width of the terminal.
+	LayoutAuto = ui.LayoutAuto
+)
+
+// AlertRule raises an alert when Threshold entries match Filter within
+// Window.
+type AlertRule struct {
+	Name      string
+	Filter    string
+	Threshold int
+	Window    time.Duration
+	// Quiet suppresses the terminal bell; the status line badge is still
+	// shown.
+	Quiet bool
+	// Notify also shows a desktop notification.
+	Notify bool
+	// Command is run through sh with the matching entry on stdin. It is a
+	// template like ViewerOptions.PipeCommand.
+	Command string
+	// Pause stops following when the rule fires.
+	Pause bool
+}
+
+// SendTarget is a webhook for :send.
+type SendTarget struct {
+	Name string
+	URL  string
+	// Template renders the request body; the default suits Slack incoming
+	// webhooks.
+	Template string
+	Headers  map[string]string
+}
+
+// ViewerOptions configures a Model. Only Entries is required.
+type ViewerOptions struct {
+	Entries <-chan Entry
+	Errors  <-chan error
+	// Sources reports files added or removed at runtime, e.g.
+	// Tailer.Events.
+	Sources <-chan SourceEvent
+	// Cancel is called when the viewer quits.
+	Cancel context.CancelFunc
+	// ExtraFields are shown next to the message in the list.
+	ExtraFields []string
+	// MaxItems caps the entries kept in memory; PerSourceItems sizes the
+	// buffer each source keeps for its tab, MaxItems if zero.
+	MaxItems       int
+	PerSourceItems int
+	// RefreshRate caps how many times per second incoming entries are
+	// applied to the list; zero applies every entry immediately.
+	RefreshRate int
+	// Stats reports per-source delivery counters, e.g. Tailer.Stats.
+	Stats func() []SourceStats
+
+	TimestampField string
+	MessageField   string
+
+	// PipeCommand is the command template the "|" key pipes entries into.
+	PipeCommand string
+	// TraceURL is the URL template the "T" key opens for the selected
+	// entry, e.g. https://jaeger.example.com/trace/{{.Fields.trace_id}}.
+	TraceURL string
+	// EditorCommand opens a source location; {{.File}} and {{.Line}} are
+	// replaced. Defaults to $VISUAL or $EDITOR.
+	EditorCommand string
+	// Layout is LayoutVertical (the default), LayoutHorizontal or
+	// LayoutAuto; Split is the share of the screen the list takes, 0.5 if
+	// zero.
+	Layout string
+	Split  float64
+	// SearchIndex indexes the words of buffered entries so that searching
+	// a large buffer does not scan all of it.
+	SearchIndex bool
+	// Location is the zone timestamps are shown in, local time if nil.
+	Location *time.Location
+	// SendTargets are the webhooks :send can post entries to.
+	SendTargets []SendTarget
+	// Alerts are checked against every arriving entry.
+	Alerts []AlertRule
+	// Watches are filter expressions counted in the watch panel.
+	Watches []string
+	// FilterPresets are named filter expressions applied with F or
+	// :preset.
+	FilterPresets map[string]string
+	// LevelField is the field whose value colors list rows; empty means
+	// the usual level fields. LevelColors maps its values to colors.
+	LevelField  string
+	LevelColors map[string]string
+}
+
+func (o ViewerOptions) internal() ui.Options {
+	alerts := make([]ui.AlertRule, len(o.Alerts))
+	for i, a := range o.Alerts {
+		alerts[i] = ui.AlertRule{
+			Name:      a.Name,
+			Filter:    a.Filter,
+			Threshold: a.Threshold,
+			Window:    a.Window,
+			Quiet:     a.Quiet,
+			Notify:    a.Notify,
+			Command:   a.Command,
+			Pause:     a.Pause,
+		}
+	}
+	targets := make([]ui.SendTarget, len(o.SendTargets))
+	for i, t := range o.SendTargets {
+		targets[i] = ui.SendTarget{Name: t.Name, URL: t.URL, Template: t.Template, Headers: t.Headers}
+	}
+	return ui.Options{
+		Entries:        o.Entries,
+		Errors:         o.Errors,
+		Sources:        o.Sources,
+		Cancel:         o.Cancel,
+		Extra:          o.ExtraFields,
+		MaxItems:       o.MaxItems,
+		PerSourceItems: o.PerSourceItems,
+		RefreshRate:    o.RefreshRate,
+		Stats:          o.Stats,
+		TimestampField: o.TimestampField,
+		MessageField:   o.MessageField,
+		PipeCommand:    o.PipeCommand,
+		TraceURL:       o.TraceURL,
+		EditorCommand:  o.EditorCommand,
+		Layout:         o.Layout,
+		Split:          o.Split,
+		SearchIndex:    o.SearchIndex,
+		Location:       o.Location,
+		SendTargets:    targets,
+		Alerts:         alerts,
+		Watches:        o.Watches,
+		FilterPresets:  o.FilterPresets,
+		LevelField:     o.LevelField,
+		LevelColors:    o.LevelColors,
+	}
+}
+
+// ControlMsg runs a viewer command line, as typed after ":", when sent to
+// the program. The outcome is sent on Reply, which must have room for one
+// value.
+type ControlMsg struct {
+	Command string
+	Reply   chan<- ControlReply
+}
+
+// ControlReply is the outcome of a ControlMsg: the status line after the
+// command, or the error it failed with.
+type ControlReply struct {
+	Status string
+	Err    error
+}
+
+// Model is the Bubble Tea model of the viewer.
+type Model struct {
+	m ui.Model
+}
+
+// NewModel returns the viewer model reading from opts.Entries.
+func NewModel(opts ViewerOptions) Model {
+	return Model{m: ui.NewModel(opts.internal())}
+}
+
+// Init implements tea.Model.
+func (m Model) Init() tea.Cmd {
+	return m.m.Init()
+}
+
+// Update implements tea.Model.
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if c, ok := msg.(ControlMsg); ok {
+		// The viewer replies while it handles the message, if it does.
+		reply := make(chan ui.ControlReply, 1)
+		msg = ui.ControlMsg{Command: c.Command, Reply: reply}
+		defer func() {
+			select {
+			case r := <-reply:
+				c.Reply <- ControlReply{Status: r.Status, Err: r.Err}
+			default:
+			}
+		}()
+	}
+	next, cmd := m.m.Update(msg)
+	if um, ok := next.(ui.Model); ok {
+		m.m = um
+	}
+	return m, cmd
+}
+
+// View implements tea.Model.
+func (m Model) View() string {
+	return m.m.View()
+}